@@ -3,16 +3,58 @@ package handler
 import (
 	"encoding/json"
 	"net/http"
+	"os"
+	"slices"
+	"strings"
 
 	"github.com/DhanushNehru/urlhawkscanner/scanner"
 )
 
+// policyErr is reported on every request if the SSRF policy could not be loaded,
+// rather than silently scanning without protection.
+var policyErr error
+
+// corsOrigins restricts browser access to the comma-separated origins in
+// URLHAWK_CORS_ORIGINS; unset allows any origin.
+var corsOrigins []string
+
+func init() {
+	// Parsed first so a policy error is not answered with a wildcard origin
+	for _, origin := range strings.Split(os.Getenv("URLHAWK_CORS_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			corsOrigins = append(corsOrigins, origin)
+		}
+	}
+
+	// Serverless deployments are internet facing, so internal ranges are denied
+	// unless URLHAWK_ALLOW_INTERNAL=true is set on the deployment.
+	policy, err := scanner.AddressPolicyFromEnv()
+	if err != nil {
+		policyErr = err
+		return
+	}
+	scanner.SetAddressPolicy(policy)
+}
+
 // Handler is the entrypoint for Vercel Serverless Functions
 func Handler(w http.ResponseWriter, r *http.Request) {
 	// CORS for external clients (if any)
-	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if len(corsOrigins) == 0 {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	} else {
+		w.Header().Add("Vary", "Origin")
+		if origin := r.Header.Get("Origin"); origin != "" && slices.Contains(corsOrigins, origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+	}
 	w.Header().Set("Content-Type", "application/json")
 
+	if policyErr != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": policyErr.Error()})
+		return
+	}
+
 	urlParam := r.URL.Query().Get("url")
 	if urlParam == "" {
 		w.WriteHeader(http.StatusBadRequest)
@@ -20,6 +62,12 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := scanner.CheckTargetAddress(r.Context(), urlParam); err != nil {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
 	// In a serverless environment, we execute the scan synchronously
 	// Vercel free tier limits execution to 10 seconds, which should be fine for URL Hawk Scan
 	result := scanner.API_ScanURL(urlParam)
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/DhanushNehru/urlhawkscanner/scanner"
+	"github.com/fatih/color"
+)
+
+// certEntry is one row of the certs report
+type certEntry struct {
+	Target   string            `json:"target"`
+	Subject  string            `json:"subject,omitempty"`
+	Issuer   string            `json:"issuer,omitempty"`
+	Expires  string            `json:"expires,omitempty"`
+	Days     *int              `json:"days_until_expiry,omitempty"`
+	Status   string            `json:"status"`
+	Error    string            `json:"error,omitempty"`
+	Findings []scanner.Finding `json:"findings,omitempty"`
+}
+
+// runCerts implements `urlhawkscanner certs`: check the certificate of every target
+// and print them sorted by expiry, soonest first. It exits with status 1 when any
+// certificate is expired or within the critical threshold, so it can gate cron jobs.
+func runCerts(args []string) {
+	fs := flag.NewFlagSet("certs", flag.ExitOnError)
+	urlFlag := fs.String("u", "", "Single host to check")
+	listFlag := fs.String("l", "", "File containing list of hosts to check")
+	threadsFlag := fs.Int("t", 20, "Number of concurrent checks")
+	warnFlag := fs.Int("warn", 30, "Warn when a certificate expires within this many days")
+	critFlag := fs.Int("crit", 7, "Critical when a certificate expires within this many days")
+	jsonFlag := fs.Bool("json", false, "Print the report as JSON")
+	caBundleFlag := fs.String("ca-bundle", "", "PEM bundle of extra CA certificates trusted when validating chains")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: urlhawkscanner certs (-u host | -l hosts.txt) [-warn 30] [-crit 7] [-json]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	var targets []string
+	switch {
+	case *urlFlag != "":
+		targets = []string{*urlFlag}
+	case *listFlag != "":
+		var err error
+		targets, err = loadURLList(*listFlag)
+		if err != nil {
+			color.Red("[-] Error opening file: %v", err)
+			os.Exit(1)
+		}
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	scanner.SetCertExpiryThresholds(*warnFlag, *critFlag)
+	loadCABundle(*caBundleFlag)
+
+	entries := make([]certEntry, len(targets))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < max(*threadsFlag, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				entries[idx] = checkCertificate(targets[idx], *warnFlag, *critFlag)
+			}
+		}()
+	}
+	for i := range targets {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	// Soonest expiry first; hosts that could not be checked go last
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i].Days, entries[j].Days
+		if a == nil || b == nil {
+			return b == nil && a != nil
+		}
+		return *a < *b
+	})
+
+	if *jsonFlag {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(entries)
+	} else {
+		printCertReport(entries)
+	}
+
+	for _, e := range entries {
+		if e.Status == "EXPIRED" || e.Status == "CRITICAL" {
+			os.Exit(1)
+		}
+	}
+}
+
+func checkCertificate(target string, warn, crit int) certEntry {
+	entry := certEntry{Target: target, Status: "ERROR"}
+
+	res, ok := scanner.RunCheck("ssl_certificate", target)
+	if !ok {
+		entry.Error = "ssl_certificate check is not available (excluded by scope?)"
+		return entry
+	}
+	ssl, ok := res.(map[string]interface{})
+	if !ok {
+		if failed, isErr := res.(map[string]string); isErr {
+			entry.Error = failed["error"]
+		}
+		return entry
+	}
+
+	entry.Subject, _ = ssl["Subject"].(string)
+	entry.Issuer, _ = ssl["Issuer"].(string)
+	entry.Expires, _ = ssl["Expires"].(string)
+	entry.Findings, _ = ssl["findings"].([]scanner.Finding)
+	if days, ok := ssl["Days Until Expiry"].(int); ok {
+		entry.Days = &days
+		switch {
+		case days < 0:
+			entry.Status = "EXPIRED"
+		case days <= crit:
+			entry.Status = "CRITICAL"
+		case days <= warn:
+			entry.Status = "WARNING"
+		default:
+			entry.Status = "OK"
+		}
+	}
+	return entry
+}
+
+func printCertReport(entries []certEntry) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STATUS\tDAYS\tEXPIRES\tTARGET\tSUBJECT\tISSUER")
+	for _, e := range entries {
+		days := "-"
+		if e.Days != nil {
+			days = strconv.Itoa(*e.Days)
+		}
+		status := e.Status
+		switch e.Status {
+		case "EXPIRED", "CRITICAL", "ERROR":
+			status = color.RedString(e.Status)
+		case "WARNING":
+			status = color.YellowString(e.Status)
+		default:
+			status = color.GreenString(e.Status)
+		}
+		detail := e.Subject
+		if e.Error != "" {
+			detail = e.Error
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", status, days, e.Expires, e.Target, detail, e.Issuer)
+	}
+	w.Flush()
+}
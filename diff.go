@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/DhanushNehru/urlhawkscanner/history"
+	"github.com/DhanushNehru/urlhawkscanner/scanner"
+	"github.com/fatih/color"
+)
+
+// targetDiff is the JSON output of the diff subcommand for one target
+type targetDiff struct {
+	Target  string           `json:"target"`
+	From    history.Summary  `json:"from"`
+	To      history.Summary  `json:"to"`
+	Changes []scanner.Change `json:"changes"`
+}
+
+// runDiff implements `urlhawkscanner diff`. Each side is either a JSON export
+// (from -o, /api/scan or /api/scans/{id}) or a scan ID from the history database.
+func runDiff(args []string) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	jsonFlag := fs.Bool("json", false, "Print changes as JSON")
+	dbFlag := fs.String("db", "", "History database used to resolve scan IDs and -target")
+	targetFlag := fs.String("target", "", "Compare the two most recent stored scans of this target")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: urlhawkscanner diff [-json] [-db file] <old.json|scan-id> <new.json|scan-id>")
+		fmt.Fprintln(os.Stderr, "       urlhawkscanner diff [-json] [-db file] -target example.com")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	var store *history.Store
+	openStore := func() *history.Store {
+		if store == nil {
+			if *dbFlag == "" {
+				color.Red("[-] Scan IDs and -target are looked up in the history database; pass -db")
+				os.Exit(2)
+			}
+			var err error
+			store, err = history.Open(*dbFlag, history.Retention{})
+			if err != nil {
+				color.Red("[-] Error opening scan history: %v", err)
+				os.Exit(1)
+			}
+		}
+		return store
+	}
+
+	var before, after []history.Record
+	switch {
+	case *targetFlag != "" && fs.NArg() == 0:
+		scans, err := openStore().List(*targetFlag, 2)
+		if err != nil || len(scans) < 2 {
+			color.Red("[-] Need at least two stored scans of %s to diff", *targetFlag)
+			os.Exit(1)
+		}
+		before = []history.Record{loadStored(openStore(), scans[1].ID)}
+		after = []history.Record{loadStored(openStore(), scans[0].ID)}
+	case fs.NArg() == 2:
+		before = loadScanRef(fs.Arg(0), openStore)
+		after = loadScanRef(fs.Arg(1), openStore)
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+	if store != nil {
+		defer store.Close()
+	}
+
+	diffs := diffRecords(before, after)
+	if len(diffs) == 0 {
+		color.Red("[-] The two scans have no target in common")
+		os.Exit(1)
+	}
+
+	if *jsonFlag {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(diffs)
+		return
+	}
+
+	for _, d := range diffs {
+		color.Blue("[~] %s: %s -> %s", d.Target, describeScan(d.From), describeScan(d.To))
+		fmt.Print(scanner.FormatChanges(d.Changes))
+		fmt.Println()
+	}
+}
+
+// diffRecords pairs the scans on both sides by target and diffs each pair
+func diffRecords(before, after []history.Record) []targetDiff {
+	// A single scan on each side is compared even if the URLs differ slightly
+	if len(before) == 1 && len(after) == 1 {
+		return []targetDiff{diffPair(before[0], after[0])}
+	}
+
+	prev, next := history.ByTarget(before), history.ByTarget(after)
+	var keys []string
+	for key := range next {
+		if _, ok := prev[key]; ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var diffs []targetDiff
+	for _, key := range keys {
+		diffs = append(diffs, diffPair(prev[key], next[key]))
+	}
+	return diffs
+}
+
+func diffPair(before, after history.Record) targetDiff {
+	changes := scanner.DiffResults(before.Results, after.Results)
+	if changes == nil {
+		changes = []scanner.Change{}
+	}
+	return targetDiff{
+		Target:  history.TargetKey(after.Target),
+		From:    history.Summary{ID: before.ID, Target: before.Target, Time: before.Time},
+		To:      history.Summary{ID: after.ID, Target: after.Target, Time: after.Time},
+		Changes: changes,
+	}
+}
+
+// loadScanRef loads an exported file, falling back to a stored scan ID
+func loadScanRef(ref string, openStore func() *history.Store) []history.Record {
+	if _, err := os.Stat(ref); err == nil {
+		records, err := history.LoadFile(ref)
+		if err != nil {
+			color.Red("[-] %v", err)
+			os.Exit(1)
+		}
+		return records
+	}
+	return []history.Record{loadStored(openStore(), ref)}
+}
+
+func loadStored(store *history.Store, id string) history.Record {
+	record, err := store.Get(id)
+	if err != nil {
+		color.Red("[-] Error loading scan %s: %v", id, err)
+		os.Exit(1)
+	}
+	return *record
+}
+
+func describeScan(s history.Summary) string {
+	label := s.Time.Format("2006-01-02 15:04 MST")
+	if s.ID != "" {
+		label += " (" + s.ID + ")"
+	}
+	return label
+}
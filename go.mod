@@ -6,13 +6,15 @@ require (
 	github.com/fatih/color v1.18.0
 	github.com/likexian/whois v1.15.7
 	github.com/likexian/whois-parser v1.24.21
+	go.etcd.io/bbolt v1.4.3
+	golang.org/x/crypto v0.48.0
+	golang.org/x/net v0.50.0
 )
 
 require (
 	github.com/likexian/gokit v0.25.16 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
-	golang.org/x/net v0.50.0 // indirect
 	golang.org/x/sys v0.41.0 // indirect
 	golang.org/x/text v0.34.0 // indirect
 )
@@ -0,0 +1,65 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// WriteFile exports records as an indented JSON array, the format read back by
+// LoadFile, the diff subcommand and -baseline.
+func WriteFile(path string, records []Record) error {
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// LoadFile reads exported scans. It accepts an array of records as written by
+// WriteFile, a single record (e.g. saved from GET /api/scans/{id}), or a bare results
+// object as returned by GET /api/scan.
+func LoadFile(path string) ([]Record, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	if err := json.Unmarshal(data, &records); err == nil {
+		return records, nil
+	}
+
+	var record Record
+	if err := json.Unmarshal(data, &record); err == nil && record.Results != nil {
+		return []Record{record}, nil
+	}
+
+	var results map[string]interface{}
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, fmt.Errorf("%s: not a scan export: %w", path, err)
+	}
+	target, _ := results["url"].(string)
+	if target == "" {
+		return nil, fmt.Errorf("%s: not a scan export (no records or url field)", path)
+	}
+	info, _ := os.Stat(path)
+	var modTime time.Time
+	if info != nil {
+		modTime = info.ModTime().UTC()
+	}
+	return []Record{{Target: target, Time: modTime, Results: results}}, nil
+}
+
+// ByTarget indexes records by TargetKey, keeping the newest record per target
+func ByTarget(records []Record) map[string]Record {
+	index := make(map[string]Record)
+	for _, record := range records {
+		key := TargetKey(record.Target)
+		if existing, ok := index[key]; !ok || record.Time.After(existing.Time) {
+			index[key] = record
+		}
+	}
+	return index
+}
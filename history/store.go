@@ -0,0 +1,303 @@
+// Package history persists scan results in a local embedded bbolt database so past
+// scans can be looked up by target and compared over time.
+package history
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// ErrNotFound is returned when a scan ID does not exist in the store
+	ErrNotFound = errors.New("scan not found")
+
+	scansBucket   = []byte("scans")
+	targetsBucket = []byte("targets")
+)
+
+// Record is a single stored scan
+type Record struct {
+	ID      string                 `json:"id,omitempty"`
+	Target  string                 `json:"target"`
+	Time    time.Time              `json:"time"`
+	Results map[string]interface{} `json:"results"`
+}
+
+// Summary is the lightweight listing form of a Record
+type Summary struct {
+	ID     string    `json:"id,omitempty"`
+	Target string    `json:"target"`
+	Time   time.Time `json:"time"`
+}
+
+// Retention limits how much history is kept. Zero values disable the limit.
+type Retention struct {
+	MaxAge       time.Duration
+	MaxPerTarget int
+}
+
+// Store is a file-backed scan history. It is safe for concurrent use.
+type Store struct {
+	db        *bolt.DB
+	retention Retention
+}
+
+// Open opens (or creates) the history database at path and applies the retention
+// policy once up front.
+func Open(path string, retention Retention) (*Store, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 2 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("opening history database %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(scansBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(targetsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	store := &Store{db: db, retention: retention}
+	if _, err := store.Prune(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// Close releases the database file
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// TargetKey reduces a scanned URL to the host the history is indexed by, so
+// "example.com", "http://example.com" and "https://EXAMPLE.com/" share one timeline.
+func TargetKey(target string) string {
+	target = strings.TrimSpace(target)
+	if !strings.Contains(target, "://") {
+		target = "http://" + target
+	}
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		return strings.ToLower(target)
+	}
+	return strings.ToLower(u.Host)
+}
+
+// Save stores the results of a scan of target and returns the new record
+func (s *Store) Save(target string, results map[string]interface{}) (*Record, error) {
+	return s.saveAt(target, results, time.Now().UTC())
+}
+
+// saveAt stores a scan made at now, applying the retention policy as of that time
+func (s *Store) saveAt(target string, results map[string]interface{}, now time.Time) (*Record, error) {
+	record := &Record{
+		ID:      newID(now),
+		Target:  target,
+		Time:    now,
+		Results: results,
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(scansBucket).Put([]byte(record.ID), data); err != nil {
+			return err
+		}
+		index, err := tx.Bucket(targetsBucket).CreateBucketIfNotExists([]byte(TargetKey(target)))
+		if err != nil {
+			return err
+		}
+		if err := index.Put(indexKey(now, record.ID), []byte(record.ID)); err != nil {
+			return err
+		}
+		_, err = s.pruneTarget(tx, index, now)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// Get loads a single scan by ID
+func (s *Store) Get(id string) (*Record, error) {
+	var record Record
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(scansBucket).Get([]byte(id))
+		if data == nil {
+			return ErrNotFound
+		}
+		return json.Unmarshal(data, &record)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// List returns up to limit scans of target, newest first. An empty target lists the
+// most recent scans across all targets.
+func (s *Store) List(target string, limit int) ([]Summary, error) {
+	summaries := []Summary{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if target == "" {
+			return s.listAll(tx, limit, &summaries)
+		}
+
+		index := tx.Bucket(targetsBucket).Bucket([]byte(TargetKey(target)))
+		if index == nil {
+			return nil
+		}
+		c := index.Cursor()
+		for k, v := c.Last(); k != nil && (limit <= 0 || len(summaries) < limit); k, v = c.Prev() {
+			summary, err := summaryOf(tx, string(v))
+			if err != nil {
+				return err
+			}
+			summaries = append(summaries, summary)
+		}
+		return nil
+	})
+	return summaries, err
+}
+
+// Latest returns the most recent scan of target, or ErrNotFound
+func (s *Store) Latest(target string) (*Record, error) {
+	summaries, err := s.List(target, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(summaries) == 0 {
+		return nil, ErrNotFound
+	}
+	return s.Get(summaries[0].ID)
+}
+
+// Prune applies the retention policy to every target and returns how many scans
+// were deleted.
+func (s *Store) Prune() (int, error) {
+	deleted := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		targets := tx.Bucket(targetsBucket)
+		var names [][]byte
+		targets.ForEach(func(k, v []byte) error {
+			if v == nil {
+				names = append(names, append([]byte(nil), k...))
+			}
+			return nil
+		})
+
+		now := time.Now().UTC()
+		for _, name := range names {
+			n, err := s.pruneTarget(tx, targets.Bucket(name), now)
+			if err != nil {
+				return err
+			}
+			deleted += n
+		}
+		return nil
+	})
+	return deleted, err
+}
+
+// pruneTarget removes scans of one target that fall outside the retention policy.
+// Index keys sort oldest first, so the cursor walks from the oldest scan forwards.
+func (s *Store) pruneTarget(tx *bolt.Tx, index *bolt.Bucket, now time.Time) (int, error) {
+	if s.retention.MaxAge <= 0 && s.retention.MaxPerTarget <= 0 {
+		return 0, nil
+	}
+
+	excess := 0
+	if s.retention.MaxPerTarget > 0 {
+		count := 0
+		index.ForEach(func(k, v []byte) error {
+			count++
+			return nil
+		})
+		excess = count - s.retention.MaxPerTarget
+	}
+	cutoff := now.Add(-s.retention.MaxAge)
+
+	var doomed [][]byte
+	c := index.Cursor()
+	for k, _ := c.First(); k != nil; k, _ = c.Next() {
+		tooOld := s.retention.MaxAge > 0 && indexTime(k).Before(cutoff)
+		if !tooOld && excess <= 0 {
+			break
+		}
+		doomed = append(doomed, append([]byte(nil), k...))
+		excess--
+	}
+
+	scans := tx.Bucket(scansBucket)
+	for _, k := range doomed {
+		if err := scans.Delete(index.Get(k)); err != nil {
+			return 0, err
+		}
+		if err := index.Delete(k); err != nil {
+			return 0, err
+		}
+	}
+	return len(doomed), nil
+}
+
+func (s *Store) listAll(tx *bolt.Tx, limit int, summaries *[]Summary) error {
+	// Scan IDs start with their timestamp, so the scans bucket is already in time order
+	c := tx.Bucket(scansBucket).Cursor()
+	for k, _ := c.Last(); k != nil && (limit <= 0 || len(*summaries) < limit); k, _ = c.Prev() {
+		summary, err := summaryOf(tx, string(k))
+		if err != nil {
+			return err
+		}
+		*summaries = append(*summaries, summary)
+	}
+	return nil
+}
+
+func summaryOf(tx *bolt.Tx, id string) (Summary, error) {
+	var summary Summary
+	data := tx.Bucket(scansBucket).Get([]byte(id))
+	if data == nil {
+		return summary, ErrNotFound
+	}
+	err := json.Unmarshal(data, &summary)
+	return summary, err
+}
+
+// newID builds a sortable, URL-safe scan ID such as 20261019T101500.123Z-3fa2c1
+func newID(t time.Time) string {
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+	return t.Format("20060102T150405.000Z") + "-" + hex.EncodeToString(suffix)
+}
+
+func indexKey(t time.Time, id string) []byte {
+	key := make([]byte, 8, 8+len(id))
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano()))
+	return append(key, id...)
+}
+
+func indexTime(key []byte) time.Time {
+	if len(key) < 8 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(binary.BigEndian.Uint64(key[:8])))
+}
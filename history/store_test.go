@@ -0,0 +1,161 @@
+package history
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTestStore(t *testing.T, retention Retention) *Store {
+	t.Helper()
+	store, err := Open(filepath.Join(t.TempDir(), "history.db"), retention)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestTargetKey(t *testing.T) {
+	tests := []struct {
+		target, key string
+	}{
+		{"example.com", "example.com"},
+		{"http://example.com", "example.com"},
+		{" https://EXAMPLE.com/path?q=1 ", "example.com"},
+		{"https://example.com:8443/", "example.com:8443"},
+	}
+	for _, tt := range tests {
+		if got := TargetKey(tt.target); got != tt.key {
+			t.Errorf("TargetKey(%q) = %q, want %q", tt.target, got, tt.key)
+		}
+	}
+}
+
+func TestSaveListGet(t *testing.T) {
+	store := openTestStore(t, Retention{})
+
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	var ids []string
+	for i, target := range []string{"https://example.com", "example.org", "http://EXAMPLE.com/"} {
+		record, err := store.saveAt(target, map[string]interface{}{"open_ports": []string{"443"}, "run": i}, start.Add(time.Duration(i)*time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, record.ID)
+	}
+
+	// Both spellings of example.com share one timeline, newest first
+	scans, err := store.List("example.com", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scans) != 2 || scans[0].ID != ids[2] || scans[1].ID != ids[0] {
+		t.Errorf("List(example.com) = %+v", scans)
+	}
+	if scans, _ := store.List("example.com", 1); len(scans) != 1 || scans[0].ID != ids[2] {
+		t.Errorf("List(example.com, 1) = %+v", scans)
+	}
+	if scans, _ := store.List("", 0); len(scans) != 3 || scans[0].ID != ids[2] || scans[2].ID != ids[0] {
+		t.Errorf("List of every target = %+v", scans)
+	}
+	if scans, err := store.List("unknown.example", 0); err != nil || len(scans) != 0 {
+		t.Errorf("List of an unknown target = %+v, %v", scans, err)
+	}
+
+	record, err := store.Get(ids[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Target != "example.org" || !record.Time.Equal(start.Add(time.Hour)) || record.Results["run"] != float64(1) {
+		t.Errorf("Get = %+v", record)
+	}
+	if _, err := store.Get("20260101T000000.000Z-000000"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of a missing ID = %v, want ErrNotFound", err)
+	}
+
+	latest, err := store.Latest("https://example.com")
+	if err != nil || latest.ID != ids[2] {
+		t.Errorf("Latest = %+v, %v", latest, err)
+	}
+	if _, err := store.Latest("unknown.example"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Latest of an unknown target = %v, want ErrNotFound", err)
+	}
+}
+
+func TestRetentionMaxPerTarget(t *testing.T) {
+	store := openTestStore(t, Retention{MaxPerTarget: 2})
+
+	start := time.Now().UTC().Add(-time.Hour)
+	var ids []string
+	for i := 0; i < 4; i++ {
+		record, err := store.saveAt("example.com", nil, start.Add(time.Duration(i)*time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, record.ID)
+	}
+	if _, err := store.saveAt("example.org", nil, start); err != nil {
+		t.Fatal(err)
+	}
+
+	scans, _ := store.List("example.com", 0)
+	if len(scans) != 2 || scans[0].ID != ids[3] || scans[1].ID != ids[2] {
+		t.Errorf("kept %+v, want the two newest scans", scans)
+	}
+	// Pruned scans are deleted, not only unindexed
+	if _, err := store.Get(ids[0]); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of a pruned scan = %v, want ErrNotFound", err)
+	}
+	if scans, _ := store.List("example.org", 0); len(scans) != 1 {
+		t.Errorf("another target lost scans: %+v", scans)
+	}
+}
+
+func TestRetentionMaxAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	store, err := Open(path, Retention{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	old, _ := store.saveAt("example.com", nil, now.Add(-48*time.Hour))
+	recent, _ := store.saveAt("example.com", nil, now.Add(-time.Hour))
+	stale, _ := store.saveAt("example.org", nil, now.Add(-72*time.Hour))
+	store.Close()
+
+	// Opening with a retention policy prunes every target up front
+	store, err = Open(path, Retention{MaxAge: 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	scans, _ := store.List("", 0)
+	if len(scans) != 1 || scans[0].ID != recent.ID {
+		t.Errorf("kept %+v, want only %s", scans, recent.ID)
+	}
+	for _, id := range []string{old.ID, stale.ID} {
+		if _, err := store.Get(id); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Get(%s) = %v, want ErrNotFound", id, err)
+		}
+	}
+	if deleted, err := store.Prune(); err != nil || deleted != 0 {
+		t.Errorf("second Prune deleted %d, %v", deleted, err)
+	}
+}
+
+func TestOpenLockedDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	store, err := Open(path, Retention{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	// bbolt holds an exclusive lock, so a second process or store gives up after the timeout
+	if _, err := Open(path, Retention{}); err == nil {
+		t.Error("a locked database was opened twice")
+	}
+}
@@ -5,8 +5,14 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/DhanushNehru/urlhawkscanner/history"
+	"github.com/DhanushNehru/urlhawkscanner/monitor"
+	"github.com/DhanushNehru/urlhawkscanner/notify"
 	"github.com/DhanushNehru/urlhawkscanner/scanner"
 	"github.com/DhanushNehru/urlhawkscanner/web"
 	"github.com/fatih/color"
@@ -27,16 +33,138 @@ func printBanner() {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		runDiff(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "monitor" {
+		runMonitor(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "certs" {
+		runCerts(os.Args[2:])
+		return
+	}
+
 	urlFlag := flag.String("u", "", "Single URL to scan")
 	listFlag := flag.String("l", "", "File containing list of URLs to scan")
 	threadsFlag := flag.Int("t", 10, "Number of concurrent threads")
 	webFlag := flag.Bool("web", false, "Start the URLHawk web interface")
 	portFlag := flag.Int("p", 8080, "Port for the web server (default 8080)")
+	recurseFlag := flag.Bool("recurse", false, "Scan in-scope hosts discovered during the scan (subdomains, SANs, MX/NS, redirects)")
+	depthFlag := flag.Int("depth", 1, "Maximum recursion depth when -recurse is enabled")
+	allowFlag := flag.String("recurse-allow", "", "Comma-separated extra domains recursion may expand into")
+	allowInternalFlag := flag.Bool("allow-internal", false, "Allow the web server to scan loopback, private and link-local addresses")
+	apiKeysFlag := flag.String("api-keys", "", "JSON file of API keys, rate limits and daily quotas for the web API")
+	dbFlag := flag.String("db", "", "Scan history database file; history is only kept when this is set")
+	maxAgeFlag := flag.Duration("history-max-age", 90*24*time.Hour, "Delete stored scans older than this (0 keeps everything)")
+	maxScansFlag := flag.Int("history-max-scans", 100, "Scans kept per target in the history database (0 keeps everything)")
+	outputFlag := flag.String("o", "", "Export scan results as JSON to this file")
+	baselineFlag := flag.String("baseline", "", "Previous JSON export; only report findings that are new compared to it")
+	scopeFlag := flag.String("scope", "", "Scope file (domains, wildcards, CIDRs, exclude-path, exclude-check) enforced on every request")
+	monitorListFlag := flag.String("monitor-list", "", "With -web, file of targets to rescan on a schedule in the background")
+	monitorEveryFlag := flag.Duration("monitor-every", 6*time.Hour, "Rescan interval for -monitor-list")
+	monitorStateFlag := flag.String("monitor-state", "urlhawk-monitor.json", "Monitor schedule state file for -monitor-list")
+	certWarnFlag := flag.Int("cert-warn", 30, "Raise a warning finding when the TLS certificate expires within this many days")
+	certCritFlag := flag.Int("cert-crit", 7, "Raise a critical finding when the TLS certificate expires within this many days")
+	caBundleFlag := flag.String("ca-bundle", "", "PEM bundle of extra CA certificates trusted when validating certificate chains")
+	ocspURLFlag := flag.String("ocsp-url", "", "Send OCSP requests to this responder instead of the one named in the certificate")
+	crlURLFlag := flag.String("crl-url", "", "Download the CRL from this URL instead of the certificate's distribution point")
+	sensitivePathsFlag := flag.String("sensitive-paths", "", "File of \"path signature\" lines replacing the built-in exposed_files list")
+	gitDumpFlag := flag.String("git-dump", "", "Save files recovered from exposed .git directories under this directory, one folder per host")
+	intrusiveFlag := flag.Bool("intrusive", false, "Also run intrusive checks that send large numbers of requests (content_discovery)")
+	timeoutFlag := flag.Duration("timeout", 8*time.Second, "Time limit for all checks against one target")
+	rateFlag := flag.Float64("rate", 0, "Maximum HTTP requests per second to each target host (0 for no limit)")
+	wordlistFlag := flag.String("wordlist", "", "Wordlist for content_discovery instead of the built-in one")
+	extensionsFlag := flag.String("extensions", "", "Comma-separated extensions content_discovery appends to each word, e.g. php,bak")
+	discoveryDepthFlag := flag.Int("discovery-depth", 1, "Directory levels content_discovery brute forces (1 is the site root only)")
+	matchStatusFlag := flag.String("match-status", "", "Comma-separated status codes content_discovery reports (default 200,204,301,302,307,308,401,403,405,500)")
+	filterSizeFlag := flag.String("filter-size", "", "Comma-separated response sizes content_discovery ignores")
+	corsOriginsFlag := flag.String("cors-origins", "", "With -web, comma-separated origins allowed to call the API from a browser (default any)")
+	notifyFlag := flag.String("notify", "", "JSON file of webhook, Slack and Teams targets notified on scan completion and changes")
 
 	flag.Parse()
 
+	scanner.SetCertExpiryThresholds(*certWarnFlag, *certCritFlag)
+	loadCABundle(*caBundleFlag)
+	scanner.SetRevocationEndpoints(*ocspURLFlag, *crlURLFlag)
+	if *webFlag && *gitDumpFlag != "" {
+		color.Red("[-] -git-dump cannot be combined with -web: API callers could fill this server's disk")
+		os.Exit(1)
+	}
+	scanner.SetGitDumpDir(*gitDumpFlag)
+	if *webFlag && *intrusiveFlag {
+		color.Red("[-] -intrusive cannot be combined with -web: API callers could brute force any host from this server")
+		os.Exit(1)
+	}
+	scanner.SetIntrusiveChecks(*intrusiveFlag)
+	scanner.SetScanTimeout(*timeoutFlag)
+	scanner.SetRateLimit(*rateFlag)
+	configureDiscovery(*wordlistFlag, *extensionsFlag, *discoveryDepthFlag, *matchStatusFlag, *filterSizeFlag)
+	if err := scanner.SetSensitivePaths(*sensitivePathsFlag); err != nil {
+		color.Red("[-] Error loading sensitive paths: %v", err)
+		os.Exit(1)
+	}
+
+	if *scopeFlag != "" {
+		scope, err := scanner.LoadScope(*scopeFlag)
+		if err != nil {
+			color.Red("[-] Error loading scope: %v", err)
+			os.Exit(1)
+		}
+		scanner.SetScope(scope)
+		color.Green("[+] Scope loaded from %s", *scopeFlag)
+	}
+
+	var store *history.Store
+	if *dbFlag != "" {
+		var err error
+		store, err = history.Open(*dbFlag, history.Retention{MaxAge: *maxAgeFlag, MaxPerTarget: *maxScansFlag})
+		if err != nil {
+			// Another scan or server holding the database must not stop this one
+			color.Yellow("[!] Scan history disabled: %v", err)
+		} else {
+			defer store.Close()
+		}
+	}
+
+	var notifier *notify.Dispatcher
+	if *notifyFlag != "" {
+		notifier = loadNotifier(*notifyFlag)
+		defer notifier.Wait()
+	}
+
 	if *webFlag {
-		web.StartServer(*portFlag)
+		opts := web.Options{
+			AllowInternal: *allowInternalFlag,
+			APIKeysFile:   *apiKeysFlag,
+			History:       store,
+			Notifier:      notifier,
+		}
+		for _, origin := range strings.Split(*corsOriginsFlag, ",") {
+			if origin = strings.TrimSpace(origin); origin != "" {
+				opts.CORSOrigins = append(opts.CORSOrigins, origin)
+			}
+		}
+		if *monitorListFlag != "" {
+			targets, err := loadURLList(*monitorListFlag)
+			if err != nil {
+				color.Red("[-] Error opening file: %v", err)
+				os.Exit(1)
+			}
+			opts.Monitor, err = monitor.New(monitor.Options{
+				Targets:   targets,
+				Every:     *monitorEveryFlag,
+				StateFile: *monitorStateFlag,
+				History:   store,
+				OnAlert:   alertHandler(printAlert, notifier),
+			})
+			if err != nil {
+				color.Red("[-] Error starting monitor: %v", err)
+				os.Exit(1)
+			}
+		}
+		web.StartServer(*portFlag, opts)
 		return
 	}
 
@@ -47,34 +175,157 @@ func main() {
 	if *urlFlag != "" {
 		urls = append(urls, *urlFlag)
 	} else if *listFlag != "" {
-		file, err := os.Open(*listFlag)
+		var err error
+		urls, err = loadURLList(*listFlag)
 		if err != nil {
 			color.Red("[-] Error opening file: %v", err)
 			os.Exit(1)
 		}
-		defer file.Close()
-		fileScanner := bufio.NewScanner(file)
-		for fileScanner.Scan() {
-			url := strings.TrimSpace(fileScanner.Text())
-			if url != "" {
-				// Ensure simple URLs have http:// if missing, basic normalization
-				if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
-					url = "http://" + url
-				}
-				urls = append(urls, url)
-			}
-		}
 	} else {
 		// Provide a default simple example
 		color.Yellow("[-] No URLs provided. Provide either -u, -l, or -web")
 		fmt.Println("Example CLI: ./urlhawkscanner -u example.com")
 		fmt.Println("Example CLI: ./urlhawkscanner -l urls.txt -t 50")
+		fmt.Println("Example CLI: ./urlhawkscanner -u example.com -recurse -depth 2")
 		fmt.Println("Example Web: ./urlhawkscanner -web -p 8080")
+		fmt.Println("Example Diff: ./urlhawkscanner diff old.json new.json")
+		fmt.Println("Example Monitor: ./urlhawkscanner monitor -l urls.txt -every 6h")
+		fmt.Println("Example Certs: ./urlhawkscanner certs -l hosts.txt -warn 30 -crit 7")
 		os.Exit(1)
 	}
 
 	color.Green("[+] Loaded %d URLs to scan", len(urls))
 	color.Green("[+] Starting scan with %d threads...\n\n", *threadsFlag)
 
-	scanner.RunScan(urls, *threadsFlag)
+	opts := scanner.ScanOptions{
+		Threads:  *threadsFlag,
+		Recurse:  *recurseFlag,
+		MaxDepth: *depthFlag,
+	}
+	if *allowFlag != "" {
+		opts.RecurseAllow = strings.Split(*allowFlag, ",")
+	}
+	if *baselineFlag != "" {
+		records, err := history.LoadFile(*baselineFlag)
+		if err != nil {
+			color.Red("[-] Error loading baseline: %v", err)
+			os.Exit(1)
+		}
+		baselines := history.ByTarget(records)
+		opts.BaselineFor = func(url string) map[string]interface{} {
+			return baselines[history.TargetKey(url)].Results
+		}
+		color.Green("[+] Baseline loaded from %s (%d targets), reporting new findings only", *baselineFlag, len(baselines))
+	}
+
+	var exportMu sync.Mutex
+	var exported []history.Record
+	opts.OnResult = func(url string, results map[string]interface{}) {
+		if store != nil {
+			if _, err := store.Save(url, results); err != nil {
+				color.Red("[-] Failed to save %s to history: %v", url, err)
+			}
+		}
+		if notifier != nil {
+			notifier.Notify(notify.ScanEvent(url, results))
+			if opts.BaselineFor != nil {
+				if baseline := opts.BaselineFor(url); baseline != nil {
+					if changes := scanner.DiffResults(baseline, results); len(changes) > 0 {
+						notifier.Notify(notify.ChangesEvent(url, changes))
+					}
+				}
+			}
+		}
+		if *outputFlag != "" {
+			exportMu.Lock()
+			exported = append(exported, history.Record{Target: url, Time: time.Now().UTC(), Results: results})
+			exportMu.Unlock()
+		}
+	}
+	if opts.Recurse {
+		color.Green("[+] Recursive mode enabled (max depth %d)", opts.MaxDepth)
+	}
+
+	scanner.RunScan(urls, opts)
+
+	if *outputFlag != "" {
+		if err := history.WriteFile(*outputFlag, exported); err != nil {
+			color.Red("[-] Failed to write %s: %v", *outputFlag, err)
+			os.Exit(1)
+		}
+		color.Green("[+] Results exported to %s", *outputFlag)
+	}
+}
+
+// loadURLList reads one target per line, adding http:// where the scheme is missing
+func loadURLList(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var urls []string
+	fileScanner := bufio.NewScanner(file)
+	for fileScanner.Scan() {
+		url := strings.TrimSpace(fileScanner.Text())
+		if url != "" {
+			// Ensure simple URLs have http:// if missing, basic normalization
+			if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+				url = "http://" + url
+			}
+			urls = append(urls, url)
+		}
+	}
+	return urls, fileScanner.Err()
+}
+
+// configureDiscovery applies the content_discovery flags, exiting on invalid values
+func configureDiscovery(wordlist, extensions string, depth int, matchStatus, filterSize string) {
+	opts := scanner.DiscoveryOptions{Depth: depth}
+	if wordlist != "" {
+		words, err := scanner.LoadWordlist(wordlist)
+		if err != nil {
+			color.Red("[-] Error loading wordlist: %v", err)
+			os.Exit(1)
+		}
+		opts.Wordlist = words
+	}
+	for _, ext := range strings.Split(extensions, ",") {
+		if ext = strings.TrimPrefix(strings.TrimSpace(ext), "."); ext != "" {
+			opts.Extensions = append(opts.Extensions, ext)
+		}
+	}
+	for _, code := range splitNumbers("-match-status", matchStatus) {
+		opts.MatchStatus = append(opts.MatchStatus, int(code))
+	}
+	opts.FilterSizes = splitNumbers("-filter-size", filterSize)
+	scanner.SetDiscoveryOptions(opts)
+}
+
+// splitNumbers parses a comma-separated list of non-negative integers for flag
+func splitNumbers(flagName, list string) []int64 {
+	var numbers []int64
+	for _, field := range strings.Split(list, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(field, 10, 64)
+		if err != nil || n < 0 {
+			color.Red("[-] Invalid value %q for %s", field, flagName)
+			os.Exit(1)
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers
+}
+
+func loadCABundle(path string) {
+	if path == "" {
+		return
+	}
+	if err := scanner.SetCABundle(path); err != nil {
+		color.Red("[-] Error loading CA bundle: %v", err)
+		os.Exit(1)
+	}
 }
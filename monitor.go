@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/DhanushNehru/urlhawkscanner/history"
+	"github.com/DhanushNehru/urlhawkscanner/monitor"
+	"github.com/DhanushNehru/urlhawkscanner/notify"
+	"github.com/DhanushNehru/urlhawkscanner/scanner"
+	"github.com/fatih/color"
+)
+
+// runMonitor implements `urlhawkscanner monitor`: rescan targets on a schedule and
+// print an alert only when something changed since the previous run.
+func runMonitor(args []string) {
+	fs := flag.NewFlagSet("monitor", flag.ExitOnError)
+	urlFlag := fs.String("u", "", "Single URL to monitor")
+	listFlag := fs.String("l", "", "File containing list of URLs to monitor")
+	everyFlag := fs.Duration("every", 6*time.Hour, "Rescan interval per target")
+	stateFlag := fs.String("state", "urlhawk-monitor.json", "File the schedule and last results are persisted to")
+	certWarnFlag := fs.Int("cert-warn", 30, "Alert when the TLS certificate expires within this many days")
+	certCritFlag := fs.Int("cert-crit", 7, "Alert again when the TLS certificate expires within this many days")
+	dbFlag := fs.String("db", "", "Scan history database file; history is only kept when this is set")
+	scopeFlag := fs.String("scope", "", "Scope file enforced on every request")
+	jsonFlag := fs.Bool("json", false, "Print alerts as JSON lines")
+	caBundleFlag := fs.String("ca-bundle", "", "PEM bundle of extra CA certificates trusted when validating chains")
+	notifyFlag := fs.String("notify", "", "JSON file of webhook, Slack and Teams targets alerts are sent to")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: urlhawkscanner monitor (-u url | -l targets.txt) [-every 6h] [-state file]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	var targets []string
+	switch {
+	case *urlFlag != "":
+		targets = []string{*urlFlag}
+	case *listFlag != "":
+		var err error
+		targets, err = loadURLList(*listFlag)
+		if err != nil {
+			color.Red("[-] Error opening file: %v", err)
+			os.Exit(1)
+		}
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	if *scopeFlag != "" {
+		scope, err := scanner.LoadScope(*scopeFlag)
+		if err != nil {
+			color.Red("[-] Error loading scope: %v", err)
+			os.Exit(1)
+		}
+		scanner.SetScope(scope)
+	}
+
+	scanner.SetCertExpiryThresholds(*certWarnFlag, *certCritFlag)
+	loadCABundle(*caBundleFlag)
+
+	var store *history.Store
+	if *dbFlag != "" {
+		var err error
+		store, err = history.Open(*dbFlag, history.Retention{})
+		if err != nil {
+			// Another scan or server holding the database must not stop this one
+			color.Yellow("[!] Scan history disabled: %v", err)
+		} else {
+			defer store.Close()
+		}
+	}
+
+	onAlert := printAlert
+	if *jsonFlag {
+		enc := json.NewEncoder(os.Stdout)
+		onAlert = func(alert monitor.Alert) { enc.Encode(alert) }
+	}
+
+	var notifier *notify.Dispatcher
+	if *notifyFlag != "" {
+		notifier = loadNotifier(*notifyFlag)
+		defer notifier.Wait()
+	}
+
+	m, err := monitor.New(monitor.Options{
+		Targets:   targets,
+		Every:     *everyFlag,
+		StateFile: *stateFlag,
+		History:   store,
+		OnAlert:   alertHandler(onAlert, notifier),
+	})
+	if err != nil {
+		color.Red("[-] Error starting monitor: %v", err)
+		os.Exit(1)
+	}
+
+	if !*jsonFlag {
+		printBanner()
+		color.Green("[+] Monitoring %d targets every %s (state in %s)", len(targets), *everyFlag, *stateFlag)
+		color.Yellow("[!] Press Ctrl+C to stop")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	m.Run(ctx)
+}
+
+func printAlert(alert monitor.Alert) {
+	stamp := alert.Time.Format("2006-01-02 15:04")
+	switch alert.Kind {
+	case monitor.AlertChange:
+		color.Yellow("[!] %s %s: %s", stamp, alert.Target, alert.Message)
+		fmt.Print(scanner.FormatChanges(alert.Changes))
+	case monitor.AlertCertExpiry:
+		color.Red("[!] %s %s: %s", stamp, alert.Target, alert.Message)
+	default:
+		color.Red("[-] %s %s: %s", stamp, alert.Target, alert.Message)
+	}
+}
+
+// alertHandler prints every alert and forwards it to the notifier, if any
+func alertHandler(print func(monitor.Alert), notifier *notify.Dispatcher) func(monitor.Alert) {
+	if notifier == nil {
+		return print
+	}
+	return func(alert monitor.Alert) {
+		print(alert)
+		switch alert.Kind {
+		case monitor.AlertChange:
+			event := notify.ChangesEvent(alert.Target, alert.Changes)
+			event.Time = alert.Time
+			notifier.Notify(event)
+		case monitor.AlertCertExpiry:
+			notifier.Notify(notify.Event{
+				Type:     notify.EventCertExpiry,
+				Target:   alert.Target,
+				Time:     alert.Time,
+				Severity: alert.Severity,
+				Title:    alert.Target + ": " + alert.Message,
+			})
+		}
+	}
+}
+
+func loadNotifier(path string) *notify.Dispatcher {
+	notifier, err := notify.Load(path)
+	if err != nil {
+		color.Red("[-] Error loading notification targets: %v", err)
+		os.Exit(1)
+	}
+	notifier.OnError = func(target notify.Target, event notify.Event, err error) {
+		color.Red("[-] Failed to notify %s about %s: %v", target.URL, event.Target, err)
+	}
+	return notifier
+}
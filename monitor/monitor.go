@@ -0,0 +1,379 @@
+// Package monitor rescans a fixed set of targets on a schedule and raises alerts when
+// a scan differs from the previous one or the certificate crosses an expiry threshold.
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/DhanushNehru/urlhawkscanner/history"
+	"github.com/DhanushNehru/urlhawkscanner/scanner"
+)
+
+const (
+	AlertChange     = "change"
+	AlertCertExpiry = "cert_expiry"
+	AlertError      = "error"
+
+	// maxStaggerStep caps the spacing used for the first round (and for targets that
+	// became overdue while the monitor was down), so a baseline is gathered quickly
+	// without firing every scan at once.
+	maxStaggerStep = time.Minute
+
+	maxStoredAlerts = 100
+)
+
+// Options configures a Monitor
+type Options struct {
+	Targets []string
+	Every   time.Duration
+
+	// StateFile persists the schedule, the last results and recent alerts so a
+	// restarted monitor picks up where it left off. Empty keeps state in memory only.
+	StateFile string
+
+	// History, when set, stores every monitoring scan
+	History *history.Store
+
+	// OnAlert is called for every alert, e.g. to print or forward it
+	OnAlert func(Alert)
+
+	// Scan runs one scan; it defaults to scanner.API_ScanURL
+	Scan func(url string) map[string]interface{}
+}
+
+// Alert is raised when a rescan differs from the previous run or the certificate
+// crosses one of the ssl_certificate expiry thresholds (see
+// scanner.SetCertExpiryThresholds)
+type Alert struct {
+	Target   string           `json:"target"`
+	Time     time.Time        `json:"time"`
+	Kind     string           `json:"kind"`
+	Severity string           `json:"severity,omitempty"`
+	Message  string           `json:"message"`
+	Changes  []scanner.Change `json:"changes,omitempty"`
+}
+
+// TargetStatus is the public view of one monitored target
+type TargetStatus struct {
+	Target  string    `json:"target"`
+	LastRun time.Time `json:"last_run,omitempty"`
+	NextRun time.Time `json:"next_run"`
+}
+
+type targetState struct {
+	LastRun time.Time              `json:"last_run,omitempty"`
+	NextRun time.Time              `json:"next_run"`
+	Results map[string]interface{} `json:"results,omitempty"`
+
+	// ExpiryAlerted remembers which expiry finding of which certificate was already
+	// alerted on, so each threshold fires once per certificate rather than on every rescan
+	ExpiryAlerted string `json:"expiry_alerted,omitempty"`
+}
+
+type persistedState struct {
+	Targets map[string]*targetState `json:"targets"`
+	Alerts  []Alert                 `json:"alerts"`
+}
+
+// Monitor schedules rescans of its targets. It is safe for concurrent use.
+type Monitor struct {
+	opts Options
+
+	mu    sync.Mutex
+	state persistedState
+}
+
+// New creates a monitor, restoring the schedule from the state file if it exists
+func New(opts Options) (*Monitor, error) {
+	if len(opts.Targets) == 0 {
+		return nil, errors.New("monitor needs at least one target")
+	}
+	if opts.Every <= 0 {
+		return nil, errors.New("monitor interval must be positive")
+	}
+	if opts.Scan == nil {
+		opts.Scan = scanner.API_ScanURL
+	}
+
+	m := &Monitor{
+		opts:  opts,
+		state: persistedState{Targets: make(map[string]*targetState)},
+	}
+
+	if opts.StateFile != "" {
+		data, err := os.ReadFile(opts.StateFile)
+		if err == nil {
+			if err := json.Unmarshal(data, &m.state); err != nil {
+				return nil, fmt.Errorf("parsing monitor state %s: %w", opts.StateFile, err)
+			}
+			if m.state.Targets == nil {
+				m.state.Targets = make(map[string]*targetState)
+			}
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	m.schedule(time.Now())
+	return m, nil
+}
+
+// schedule assigns a first run to new targets and to targets that became overdue
+// while the monitor was not running. Steady-state runs keep the spacing they got
+// here because every run is followed by exactly one interval.
+func (m *Monitor) schedule(now time.Time) {
+	step := m.opts.Every / time.Duration(len(m.opts.Targets))
+	if step > maxStaggerStep {
+		step = maxStaggerStep
+	}
+
+	slot := 0
+	for _, target := range m.opts.Targets {
+		key := history.TargetKey(target)
+		st, ok := m.state.Targets[key]
+		if !ok {
+			st = &targetState{}
+			m.state.Targets[key] = st
+		}
+		if !ok || st.NextRun.Before(now) {
+			st.NextRun = now.Add(time.Duration(slot) * step)
+			slot++
+		}
+	}
+}
+
+// Run rescans targets as they become due until ctx is cancelled
+func (m *Monitor) Run(ctx context.Context) error {
+	for {
+		target, due := m.nextDue()
+		wait := time.Until(due)
+
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		m.runTarget(target)
+	}
+}
+
+func (m *Monitor) nextDue() (string, time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var next string
+	var due time.Time
+	for _, target := range m.opts.Targets {
+		st := m.state.Targets[history.TargetKey(target)]
+		if next == "" || st.NextRun.Before(due) {
+			next, due = target, st.NextRun
+		}
+	}
+	return next, due
+}
+
+// runTarget scans one target, compares the result with the previous run and
+// persists the new state.
+func (m *Monitor) runTarget(target string) {
+	started := time.Now()
+	results := m.opts.Scan(target)
+
+	var historyErr error
+	if m.opts.History != nil {
+		_, historyErr = m.opts.History.Save(target, results)
+	}
+
+	m.mu.Lock()
+	st := m.state.Targets[history.TargetKey(target)]
+	previous := st.Results
+
+	var alerts []Alert
+	if previous != nil {
+		if changes := withoutExpiryChanges(scanner.DiffResults(previous, results)); len(changes) > 0 {
+			alerts = append(alerts, Alert{
+				Target:  target,
+				Time:    started,
+				Kind:    AlertChange,
+				Message: fmt.Sprintf("%d change(s) since %s", len(changes), st.LastRun.Format(time.RFC822)),
+				Changes: changes,
+			})
+		}
+	}
+	if alert, ok := m.expiryAlert(target, st, results, started); ok {
+		alerts = append(alerts, alert)
+	}
+
+	// Keep the previous results if the whole scan failed, so one bad run does not
+	// produce a flood of "changes" on the next one
+	if !scanFailed(results) {
+		st.Results = results
+	}
+	st.LastRun = started
+	st.NextRun = started.Add(m.opts.Every)
+
+	m.state.Alerts = append(m.state.Alerts, alerts...)
+	if len(m.state.Alerts) > maxStoredAlerts {
+		m.state.Alerts = m.state.Alerts[len(m.state.Alerts)-maxStoredAlerts:]
+	}
+	saveErr := m.saveLocked()
+	m.mu.Unlock()
+
+	if m.opts.OnAlert == nil {
+		return
+	}
+	if historyErr != nil {
+		m.opts.OnAlert(Alert{Target: target, Time: time.Now(), Kind: AlertError, Message: "Failed to save scan history: " + historyErr.Error()})
+	}
+	if saveErr != nil {
+		m.opts.OnAlert(Alert{Target: target, Time: time.Now(), Kind: AlertError, Message: "Failed to persist monitor state: " + saveErr.Error()})
+	}
+	for _, alert := range alerts {
+		m.opts.OnAlert(alert)
+	}
+}
+
+// withoutExpiryChanges drops the expiry findings from a change alert; expiryAlert
+// reports those, so one expiring certificate raises one alert
+func withoutExpiryChanges(changes []scanner.Change) []scanner.Change {
+	kept := changes[:0]
+	for _, c := range changes {
+		if !scanner.IsCertExpiryChange(c) {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+// expiryAlert raises a cert_expiry alert from the ssl_certificate expiry finding the
+// first time the certificate crosses each threshold
+func (m *Monitor) expiryAlert(target string, st *targetState, results map[string]interface{}, now time.Time) (Alert, bool) {
+	finding, ok := scanner.CertExpiryFinding(results)
+	if !ok {
+		return Alert{}, false
+	}
+
+	marker := finding.Title
+	if expires, ok := certificateExpiry(results); ok {
+		marker += " " + expires.UTC().Format(time.RFC3339)
+	}
+	if st.ExpiryAlerted == marker {
+		return Alert{}, false
+	}
+	st.ExpiryAlerted = marker
+
+	message := finding.Title
+	if finding.Detail != "" {
+		message += ": " + finding.Detail
+	}
+	return Alert{Target: target, Time: now, Kind: AlertCertExpiry, Severity: finding.Severity, Message: message}, true
+}
+
+// certificateExpiry reads the leaf certificate expiry from ssl_certificate results
+func certificateExpiry(results map[string]interface{}) (time.Time, bool) {
+	var raw string
+	switch ssl := results["ssl_certificate"].(type) {
+	case map[string]string:
+		raw = ssl["Expires"]
+	case map[string]interface{}:
+		raw, _ = ssl["Expires"].(string)
+	}
+	if raw == "" {
+		return time.Time{}, false
+	}
+	expires, err := time.Parse(time.RFC822, raw)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return expires, true
+}
+
+// scanFailed reports whether every check errored, e.g. because the host was down
+func scanFailed(results map[string]interface{}) bool {
+	checks := 0
+	for key, value := range results {
+		if key == "url" {
+			continue
+		}
+		checks++
+		errored := false
+		switch v := value.(type) {
+		case map[string]string:
+			_, errored = v["error"]
+		case map[string]interface{}:
+			_, errored = v["error"]
+		}
+		if !errored {
+			return false
+		}
+	}
+	return checks > 0
+}
+
+// saveLocked writes the state file atomically. Callers hold m.mu.
+func (m *Monitor) saveLocked() error {
+	if m.opts.StateFile == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(m.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(m.opts.StateFile), ".monitor-state-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), m.opts.StateFile)
+}
+
+// Status lists the monitored targets ordered by their next run
+func (m *Monitor) Status() []TargetStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	statuses := make([]TargetStatus, 0, len(m.opts.Targets))
+	for _, target := range m.opts.Targets {
+		st := m.state.Targets[history.TargetKey(target)]
+		statuses = append(statuses, TargetStatus{Target: target, LastRun: st.LastRun, NextRun: st.NextRun})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].NextRun.Before(statuses[j].NextRun)
+	})
+	return statuses
+}
+
+// Alerts returns the most recent alerts, newest last
+func (m *Monitor) Alerts() []Alert {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Alert{}, m.state.Alerts...)
+}
+
+// Interval is the configured rescan interval
+func (m *Monitor) Interval() time.Duration {
+	return m.opts.Every
+}
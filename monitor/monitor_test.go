@@ -0,0 +1,161 @@
+package monitor
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DhanushNehru/urlhawkscanner/history"
+	"github.com/DhanushNehru/urlhawkscanner/scanner"
+)
+
+// fakeScans serves the next prepared result for every scan of a target
+type fakeScans map[string][]map[string]interface{}
+
+func (f fakeScans) scan(url string) map[string]interface{} {
+	queue := f[url]
+	if len(queue) == 0 {
+		return map[string]interface{}{"open_ports": []string{"443"}}
+	}
+	f[url] = queue[1:]
+	return queue[0]
+}
+
+func certResult(expires time.Time, findings ...scanner.Finding) map[string]interface{} {
+	return map[string]interface{}{
+		"open_ports": []string{"443"},
+		"ssl_certificate": map[string]interface{}{
+			"Subject":  "example.com",
+			"Expires":  expires.Format(time.RFC822),
+			"findings": findings,
+		},
+	}
+}
+
+func newTestMonitor(t *testing.T, opts Options) (*Monitor, *[]Alert) {
+	t.Helper()
+	var alerts []Alert
+	opts.OnAlert = func(alert Alert) { alerts = append(alerts, alert) }
+	m, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m, &alerts
+}
+
+func TestScheduleStaggersTargets(t *testing.T) {
+	tests := []struct {
+		every time.Duration
+		step  time.Duration
+	}{
+		{30 * time.Second, 10 * time.Second},
+		// The first round is capped at one target a minute however long the interval
+		{6 * time.Hour, maxStaggerStep},
+	}
+	for _, tt := range tests {
+		start := time.Now()
+		m, _ := newTestMonitor(t, Options{Targets: []string{"a.example", "b.example", "c.example"}, Every: tt.every})
+
+		statuses := m.Status()
+		if len(statuses) != 3 {
+			t.Fatalf("Status = %+v", statuses)
+		}
+		first := statuses[0].NextRun
+		if first.Before(start) || first.After(time.Now()) {
+			t.Errorf("every %s: first run at %v, want immediately", tt.every, first)
+		}
+		for i, st := range statuses {
+			if gap := st.NextRun.Sub(first); gap != time.Duration(i)*tt.step {
+				t.Errorf("every %s: %s runs %v after the first, want %v", tt.every, st.Target, gap, time.Duration(i)*tt.step)
+			}
+		}
+	}
+}
+
+func TestStateSurvivesRestart(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "monitor.json")
+	scans := fakeScans{"example.com": {
+		{"open_ports": []string{"443"}},
+		{"open_ports": []string{"443", "8080"}},
+	}}
+	opts := Options{Targets: []string{"example.com", "example.org"}, Every: time.Hour, StateFile: stateFile, Scan: scans.scan}
+
+	m, _ := newTestMonitor(t, opts)
+	m.runTarget("example.com")
+	ran := m.Status()
+
+	restarted, alerts := newTestMonitor(t, opts)
+	for i, st := range restarted.Status() {
+		if st.Target != ran[i].Target || !st.LastRun.Equal(ran[i].LastRun) || !st.NextRun.Equal(ran[i].NextRun) {
+			t.Errorf("restored %+v, want %+v", st, ran[i])
+		}
+	}
+	// The results restored from the state file are the baseline for the next scan
+	restarted.runTarget("example.com")
+	if len(*alerts) != 1 || (*alerts)[0].Kind != AlertChange || len((*alerts)[0].Changes) != 1 || (*alerts)[0].Changes[0].Item != "8080" {
+		t.Fatalf("alerts after restart = %+v", *alerts)
+	}
+	if stored := restarted.Alerts(); len(stored) != 1 {
+		t.Errorf("stored alerts = %+v", stored)
+	}
+	if reloaded, _ := newTestMonitor(t, opts); len(reloaded.Alerts()) != 1 {
+		t.Errorf("alerts were not persisted: %+v", reloaded.Alerts())
+	}
+}
+
+func TestCertExpiryAlertsOncePerThreshold(t *testing.T) {
+	expires := time.Now().Add(20 * 24 * time.Hour).Truncate(time.Minute)
+	renewed := expires.Add(90 * 24 * time.Hour)
+	warning := scanner.Finding{Severity: scanner.SeverityMedium, Title: "Certificate expires within warning threshold", Detail: "in 20 days"}
+	critical := scanner.Finding{Severity: scanner.SeverityHigh, Title: "Certificate expires within critical threshold", Detail: "in 6 days"}
+
+	scans := fakeScans{"example.com": {
+		certResult(expires, warning),
+		certResult(expires, warning),
+		certResult(expires, critical),
+		certResult(expires, critical),
+		certResult(renewed),
+	}}
+	m, alerts := newTestMonitor(t, Options{Targets: []string{"example.com"}, Every: time.Hour, Scan: scans.scan})
+
+	var kinds [][]string
+	for i := 0; i < 5; i++ {
+		before := len(*alerts)
+		m.runTarget("example.com")
+		var run []string
+		for _, alert := range (*alerts)[before:] {
+			run = append(run, alert.Kind+" "+alert.Severity)
+			for _, c := range alert.Changes {
+				if scanner.IsCertExpiryChange(c) {
+					t.Errorf("run %d: change alert repeats the expiry finding: %s", i+1, c)
+				}
+			}
+		}
+		kinds = append(kinds, run)
+	}
+
+	want := []string{"cert_expiry medium", "", "cert_expiry high", "", "change "}
+	for i := range want {
+		if got := strings.Join(kinds[i], ", "); got != want[i] {
+			t.Errorf("run %d alerts = %q, want %q", i+1, got, want[i])
+		}
+	}
+	if msg := (*alerts)[0].Message; !strings.Contains(msg, "warning threshold") || !strings.Contains(msg, "in 20 days") {
+		t.Errorf("expiry message = %q", msg)
+	}
+}
+
+func TestHistoryErrorsAreReported(t *testing.T) {
+	store, err := history.Open(filepath.Join(t.TempDir(), "history.db"), history.Retention{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	m, alerts := newTestMonitor(t, Options{Targets: []string{"example.com"}, Every: time.Hour, History: store, Scan: fakeScans{}.scan})
+	m.runTarget("example.com")
+	if len(*alerts) != 1 || (*alerts)[0].Kind != AlertError || !strings.Contains((*alerts)[0].Message, "scan history") {
+		t.Errorf("alerts = %+v", *alerts)
+	}
+}
@@ -0,0 +1,99 @@
+package notify
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/DhanushNehru/urlhawkscanner/scanner"
+)
+
+// maxChangeLines keeps chat messages readable when a scan changed a lot
+const maxChangeLines = 20
+
+var formatters = map[string]func(Event) interface{}{
+	"webhook": func(e Event) interface{} { return e },
+	"slack":   slackPayload,
+	"teams":   teamsPayload,
+}
+
+var severityColors = map[string]string{
+	SeverityInfo:     "#439FE0",
+	SeverityLow:      "#2EB886",
+	SeverityMedium:   "#DAA038",
+	SeverityHigh:     "#E8590C",
+	SeverityCritical: "#D00000",
+}
+
+// slackPayload uses the incoming-webhook attachment format, which Mattermost and
+// Rocket.Chat accept as well.
+func slackPayload(e Event) interface{} {
+	return map[string]interface{}{
+		"text": fmt.Sprintf("[%s] %s", strings.ToUpper(e.Severity), e.Title),
+		"attachments": []map[string]interface{}{{
+			"color":  severityColors[e.Severity],
+			"title":  e.Target,
+			"text":   strings.Join(eventLines(e), "\n"),
+			"footer": "URLHawkScanner",
+			"ts":     e.Time.Unix(),
+		}},
+	}
+}
+
+// teamsPayload builds a legacy MessageCard, which Teams incoming webhooks and
+// workflow connectors both accept.
+func teamsPayload(e Event) interface{} {
+	return map[string]interface{}{
+		"@type":      "MessageCard",
+		"@context":   "https://schema.org/extensions",
+		"summary":    e.Title,
+		"themeColor": strings.TrimPrefix(severityColors[e.Severity], "#"),
+		"title":      fmt.Sprintf("[%s] %s", strings.ToUpper(e.Severity), e.Title),
+		"sections": []map[string]interface{}{{
+			"activityTitle": e.Target,
+			"facts": []map[string]string{
+				{"name": "Event", "value": e.Type},
+				{"name": "Severity", "value": e.Severity},
+				{"name": "Time", "value": e.Time.Format("2006-01-02 15:04 MST")},
+			},
+			"text": strings.Join(eventLines(e), "<br>"),
+		}},
+	}
+}
+
+// eventLines summarises an event for chat: one line per change, or the names of the
+// checks that ran for a completed scan.
+func eventLines(e Event) []string {
+	var lines []string
+	for i, c := range e.Changes {
+		if i == maxChangeLines {
+			lines = append(lines, fmt.Sprintf("... and %d more", len(e.Changes)-maxChangeLines))
+			break
+		}
+		lines = append(lines, "• "+c.String())
+	}
+
+	if e.Results != nil {
+		findings := scanner.CollectFindings(e.Results)
+		var withFindings []string
+		for check := range findings {
+			withFindings = append(withFindings, check)
+		}
+		sort.Strings(withFindings)
+		for _, check := range withFindings {
+			for _, f := range findings[check] {
+				lines = append(lines, fmt.Sprintf("• [%s] %s: %s", strings.ToUpper(f.Severity), check, f.Title))
+			}
+		}
+
+		var checks []string
+		for check := range e.Results {
+			if check != "url" {
+				checks = append(checks, check)
+			}
+		}
+		sort.Strings(checks)
+		lines = append(lines, fmt.Sprintf("%d checks ran: %s", len(checks), strings.Join(checks, ", ")))
+	}
+	return lines
+}
@@ -0,0 +1,337 @@
+// Package notify pushes scan results and detected changes to webhooks and chat tools
+// (generic JSON, Slack-compatible and Microsoft Teams-compatible payloads).
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DhanushNehru/urlhawkscanner/scanner"
+)
+
+// Event types
+const (
+	EventScanCompleted = "scan_completed"
+	EventChanges       = "changes"
+	EventCertExpiry    = "cert_expiry"
+)
+
+// Severities, lowest first; the same levels scanner findings use
+const (
+	SeverityInfo     = scanner.SeverityInfo
+	SeverityLow      = scanner.SeverityLow
+	SeverityMedium   = scanner.SeverityMedium
+	SeverityHigh     = scanner.SeverityHigh
+	SeverityCritical = scanner.SeverityCritical
+)
+
+const (
+	defaultMaxAttempts = 4
+	maxBackoff         = 30 * time.Second
+)
+
+// initialBackoff is the wait before the first retry, doubled on every further one
+var initialBackoff = time.Second
+
+// SignatureHeader carries "sha256=<hex HMAC of the body>" when a target has a secret
+const SignatureHeader = "X-Urlhawk-Signature"
+
+// Event is one notification. Changes is set for change and expiry events, Results for
+// completed scans.
+type Event struct {
+	Type     string                 `json:"type"`
+	Target   string                 `json:"target"`
+	Time     time.Time              `json:"time"`
+	Severity string                 `json:"severity"`
+	Title    string                 `json:"title"`
+	Changes  []scanner.Change       `json:"changes,omitempty"`
+	Results  map[string]interface{} `json:"results,omitempty"`
+}
+
+// Target is one configured destination, as read from the notifications file
+type Target struct {
+	// Format is "webhook" (default), "slack" or "teams"
+	Format string `json:"format"`
+	URL    string `json:"url"`
+
+	// Secret signs every body with HMAC-SHA256 in the X-Urlhawk-Signature header
+	Secret string `json:"secret,omitempty"`
+
+	// Events limits which event types are sent. Empty sends all of them.
+	Events []string `json:"events,omitempty"`
+
+	// MinSeverity drops events below this severity
+	MinSeverity string `json:"min_severity,omitempty"`
+
+	// Checks limits change events to changes of these checks, e.g. ["open_ports"]
+	Checks []string `json:"checks,omitempty"`
+
+	// MaxAttempts caps delivery attempts, including the first one
+	MaxAttempts int `json:"max_attempts,omitempty"`
+}
+
+// Dispatcher delivers events to every matching target in the background
+type Dispatcher struct {
+	targets []Target
+	client  *http.Client
+	wg      sync.WaitGroup
+
+	// OnError is called when an event could not be delivered after all retries
+	OnError func(target Target, event Event, err error)
+}
+
+// Load reads a JSON list of targets from path
+func Load(path string) (*Dispatcher, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var targets []Target
+	if err := json.Unmarshal(data, &targets); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return New(targets)
+}
+
+// New validates the targets and returns a dispatcher for them
+func New(targets []Target) (*Dispatcher, error) {
+	for i := range targets {
+		t := &targets[i]
+		if t.Format == "" {
+			t.Format = "webhook"
+		}
+		if _, ok := formatters[t.Format]; !ok {
+			return nil, fmt.Errorf("notification target #%d: unknown format %q", i+1, t.Format)
+		}
+		if !strings.HasPrefix(t.URL, "http://") && !strings.HasPrefix(t.URL, "https://") {
+			return nil, fmt.Errorf("notification target #%d: invalid url %q", i+1, t.URL)
+		}
+		if t.MinSeverity != "" && !scanner.ValidSeverity(t.MinSeverity) {
+			return nil, fmt.Errorf("notification target #%d: unknown severity %q", i+1, t.MinSeverity)
+		}
+		if t.MaxAttempts <= 0 {
+			t.MaxAttempts = defaultMaxAttempts
+		}
+	}
+	return &Dispatcher{
+		targets: targets,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Notify queues event for every target whose filters it passes and returns
+// immediately. Use Wait before exiting to let deliveries finish.
+func (d *Dispatcher) Notify(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+	if event.Severity == "" {
+		event.Severity = SeverityInfo
+	}
+
+	for _, target := range d.targets {
+		filtered, ok := target.accepts(event)
+		if !ok {
+			continue
+		}
+		d.wg.Add(1)
+		go func(target Target, event Event) {
+			defer d.wg.Done()
+			if err := d.deliver(context.Background(), target, event); err != nil && d.OnError != nil {
+				d.OnError(target, event, err)
+			}
+		}(target, filtered)
+	}
+}
+
+// Wait blocks until every queued delivery succeeded or gave up
+func (d *Dispatcher) Wait() {
+	d.wg.Wait()
+}
+
+// accepts applies the target's filters and returns the event as it should be sent,
+// with change events trimmed to the checks the target is interested in.
+func (t Target) accepts(event Event) (Event, bool) {
+	if len(t.Events) > 0 && !contains(t.Events, event.Type) {
+		return event, false
+	}
+
+	if len(t.Checks) > 0 && event.Changes != nil {
+		var kept []scanner.Change
+		for _, c := range event.Changes {
+			if contains(t.Checks, c.Check) {
+				kept = append(kept, c)
+			}
+		}
+		if len(kept) == 0 {
+			return event, false
+		}
+		if len(kept) != len(event.Changes) && event.Type == EventChanges {
+			event.Severity = ChangesSeverity(kept)
+			event.Title = fmt.Sprintf("%d change(s) on %s", len(kept), event.Target)
+		}
+		event.Changes = kept
+	}
+
+	if t.MinSeverity != "" && scanner.SeverityRank(event.Severity) < scanner.SeverityRank(t.MinSeverity) {
+		return event, false
+	}
+	return event, true
+}
+
+// deliver posts the event, retrying network errors, 429 and 5xx responses with
+// exponential backoff (or the server's Retry-After).
+func (d *Dispatcher) deliver(ctx context.Context, target Target, event Event) error {
+	body, err := json.Marshal(formatters[target.Format](event))
+	if err != nil {
+		return err
+	}
+
+	backoff := initialBackoff
+	var lastErr error
+	for attempt := 1; attempt <= target.MaxAttempts; attempt++ {
+		var retryAfter time.Duration
+		retryAfter, lastErr = d.post(ctx, target, body)
+		if lastErr == nil {
+			return nil
+		}
+		if retryAfter < 0 || attempt == target.MaxAttempts {
+			break
+		}
+
+		wait := backoff
+		if retryAfter > 0 {
+			wait = retryAfter
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+	return lastErr
+}
+
+// post sends one attempt. A negative retryAfter means the failure is permanent.
+func (d *Dispatcher) post(ctx context.Context, target Target, body []byte) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.URL, bytes.NewReader(body))
+	if err != nil {
+		return -1, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "URLHawkScanner-Scanner/1.0")
+	if target.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(target.Secret, body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode < 300:
+		return 0, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		retry := 0
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+			retry = secs
+		}
+		return min(time.Duration(retry)*time.Second, maxBackoff), fmt.Errorf("%s returned %s", target.URL, resp.Status)
+	}
+	return -1, fmt.Errorf("%s returned %s", target.URL, resp.Status)
+}
+
+// Sign returns the X-Urlhawk-Signature value for body, so receivers can verify it
+// with the shared secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// ChangeSeverity rates a single change between two scans
+func ChangeSeverity(c scanner.Change) string {
+	switch {
+	case c.Kind != scanner.ChangeAdded && c.Check != "ssl_certificate":
+		// Something went away or changed value; a header becoming present is good news
+		if c.Kind == scanner.ChangeRemoved {
+			return SeverityInfo
+		}
+		return SeverityLow
+	case c.Check == "open_ports" || c.Check == "exposed_files":
+		return SeverityHigh
+	case c.Check == "missing_headers":
+		return SeverityMedium
+	}
+	return SeverityLow
+}
+
+// ChangesSeverity is the highest severity among changes
+func ChangesSeverity(changes []scanner.Change) string {
+	severity := SeverityInfo
+	for _, c := range changes {
+		if s := ChangeSeverity(c); scanner.SeverityRank(s) > scanner.SeverityRank(severity) {
+			severity = s
+		}
+	}
+	return severity
+}
+
+// ChangesEvent builds the event sent when a rescan differs from an earlier scan
+func ChangesEvent(target string, changes []scanner.Change) Event {
+	return Event{
+		Type:     EventChanges,
+		Target:   target,
+		Severity: ChangesSeverity(changes),
+		Title:    fmt.Sprintf("%d change(s) on %s", len(changes), target),
+		Changes:  changes,
+	}
+}
+
+// ScanEvent builds the event sent when a scan completes. Its severity is that of the
+// most severe finding in the results.
+func ScanEvent(target string, results map[string]interface{}) Event {
+	findings := scanner.CollectFindings(results)
+	title := "Scan completed for " + target
+	if count := countFindings(findings); count > 0 {
+		title = fmt.Sprintf("%s (%d findings)", title, count)
+	}
+	return Event{
+		Type:     EventScanCompleted,
+		Target:   target,
+		Severity: scanner.MaxSeverity(findings),
+		Title:    title,
+		Results:  results,
+	}
+}
+
+func countFindings(findings map[string][]scanner.Finding) int {
+	count := 0
+	for _, list := range findings {
+		count += len(list)
+	}
+	return count
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
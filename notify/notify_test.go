@@ -0,0 +1,257 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DhanushNehru/urlhawkscanner/scanner"
+)
+
+// receiver records what a webhook endpoint was sent and answers with the statuses
+// in replies, one per request, then 200
+type receiver struct {
+	mu      sync.Mutex
+	bodies  [][]byte
+	headers []http.Header
+	times   []time.Time
+}
+
+func newReceiver(t *testing.T, replies ...func(w http.ResponseWriter)) (*receiver, *httptest.Server) {
+	t.Helper()
+	rec := &receiver{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rec.mu.Lock()
+		n := len(rec.bodies)
+		rec.bodies = append(rec.bodies, body)
+		rec.headers = append(rec.headers, r.Header.Clone())
+		rec.times = append(rec.times, time.Now())
+		rec.mu.Unlock()
+		if n < len(replies) {
+			replies[n](w)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return rec, srv
+}
+
+func (r *receiver) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.bodies)
+}
+
+func status(code int) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) { w.WriteHeader(code) }
+}
+
+func retryAfter(code int, seconds string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		w.Header().Set("Retry-After", seconds)
+		w.WriteHeader(code)
+	}
+}
+
+func fastBackoff(t *testing.T) {
+	t.Helper()
+	saved := initialBackoff
+	initialBackoff = 10 * time.Millisecond
+	t.Cleanup(func() { initialBackoff = saved })
+}
+
+func newDispatcher(t *testing.T, targets ...Target) *Dispatcher {
+	t.Helper()
+	d, err := New(targets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestSign(t *testing.T) {
+	// RFC 4231 test case 2
+	got := Sign("Jefe", []byte("what do ya want for nothing?"))
+	want := "sha256=5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"
+	if got != want {
+		t.Errorf("Sign = %s, want %s", got, want)
+	}
+}
+
+func TestDeliverSignsBody(t *testing.T) {
+	rec, srv := newReceiver(t)
+	d := newDispatcher(t, Target{URL: srv.URL, Secret: "s3cret"}, Target{URL: srv.URL})
+
+	d.Notify(Event{Type: EventScanCompleted, Target: "example.com", Title: "Scan completed for example.com"})
+	d.Wait()
+
+	if rec.count() != 2 {
+		t.Fatalf("%d deliveries, want 2", rec.count())
+	}
+	signed := 0
+	for i, body := range rec.bodies {
+		signature := rec.headers[i].Get(SignatureHeader)
+		if signature == "" {
+			continue
+		}
+		signed++
+		if !hmac.Equal([]byte(signature), []byte(Sign("s3cret", body))) {
+			t.Errorf("signature %s does not match the body", signature)
+		}
+		var event Event
+		if err := json.Unmarshal(body, &event); err != nil || event.Target != "example.com" || event.Severity != SeverityInfo || event.Time.IsZero() {
+			t.Errorf("webhook body = %s (%v)", body, err)
+		}
+	}
+	if signed != 1 {
+		t.Errorf("%d signed deliveries, want only the target with a secret", signed)
+	}
+}
+
+func TestDeliverRetries(t *testing.T) {
+	fastBackoff(t)
+	event := Event{Type: EventChanges, Target: "example.com", Severity: SeverityHigh}
+
+	tests := []struct {
+		name     string
+		replies  []func(w http.ResponseWriter)
+		attempts int
+		ok       bool
+	}{
+		{"success", nil, 1, true},
+		{"5xx then success", []func(w http.ResponseWriter){status(502), status(503)}, 3, true},
+		{"429 then success", []func(w http.ResponseWriter){status(429)}, 2, true},
+		{"gives up", []func(w http.ResponseWriter){status(500), status(500), status(500), status(500), status(500)}, 3, false},
+		{"permanent 4xx", []func(w http.ResponseWriter){status(400)}, 1, false},
+		{"gone", []func(w http.ResponseWriter){status(410)}, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, srv := newReceiver(t, tt.replies...)
+			d := newDispatcher(t, Target{URL: srv.URL, MaxAttempts: 3})
+			err := d.deliver(context.Background(), d.targets[0], event)
+			if (err == nil) != tt.ok {
+				t.Errorf("deliver = %v, want success %t", err, tt.ok)
+			}
+			if rec.count() != tt.attempts {
+				t.Errorf("%d attempts, want %d", rec.count(), tt.attempts)
+			}
+		})
+	}
+}
+
+func TestDeliverHonoursRetryAfter(t *testing.T) {
+	fastBackoff(t)
+	rec, srv := newReceiver(t, retryAfter(http.StatusTooManyRequests, "1"), retryAfter(http.StatusServiceUnavailable, "soon"))
+	d := newDispatcher(t, Target{URL: srv.URL})
+
+	if err := d.deliver(context.Background(), d.targets[0], Event{Type: EventChanges}); err != nil {
+		t.Fatal(err)
+	}
+	if rec.count() != 3 {
+		t.Fatalf("%d attempts, want 3", rec.count())
+	}
+	if waited := rec.times[1].Sub(rec.times[0]); waited < time.Second {
+		t.Errorf("retried after %v despite Retry-After: 1", waited)
+	}
+	// An unparseable Retry-After falls back to the backoff
+	if waited := rec.times[2].Sub(rec.times[1]); waited >= time.Second {
+		t.Errorf("retried after %v for Retry-After: soon", waited)
+	}
+}
+
+func TestDeliverStopsWithContext(t *testing.T) {
+	_, srv := newReceiver(t, retryAfter(http.StatusServiceUnavailable, "30"))
+	d := newDispatcher(t, Target{URL: srv.URL})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := d.deliver(ctx, d.targets[0], Event{}); err == nil {
+		t.Error("deliver succeeded after the context ended")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("deliver kept waiting %v after the context ended", elapsed)
+	}
+}
+
+func TestNotifyReportsFailures(t *testing.T) {
+	_, srv := newReceiver(t, status(http.StatusForbidden))
+	d := newDispatcher(t, Target{URL: srv.URL})
+
+	var failed []error
+	d.OnError = func(target Target, event Event, err error) { failed = append(failed, err) }
+	d.Notify(Event{Type: EventScanCompleted})
+	d.Wait()
+	if len(failed) != 1 || !strings.Contains(failed[0].Error(), "403") {
+		t.Errorf("OnError got %v", failed)
+	}
+}
+
+func TestTargetAccepts(t *testing.T) {
+	changes := []scanner.Change{
+		{Check: "open_ports", Kind: scanner.ChangeAdded, Item: "8080"},
+		{Check: "server_info", Kind: scanner.ChangeChanged, Item: "Server", Before: "nginx", After: "Apache"},
+	}
+	changed := ChangesEvent("example.com", changes)
+	scan := Event{Type: EventScanCompleted, Target: "example.com", Severity: SeverityMedium}
+	expiry := Event{Type: EventCertExpiry, Target: "example.com", Severity: SeverityCritical, Changes: []scanner.Change{}}
+
+	tests := []struct {
+		name     string
+		target   Target
+		event    Event
+		ok       bool
+		severity string
+		changes  int
+	}{
+		{"no filters", Target{}, changed, true, SeverityHigh, 2},
+		{"event type", Target{Events: []string{EventCertExpiry}}, changed, false, "", 0},
+		{"event type matches", Target{Events: []string{EventScanCompleted, EventChanges}}, scan, true, SeverityMedium, 0},
+		{"below min severity", Target{MinSeverity: SeverityHigh}, scan, false, "", 0},
+		{"at min severity", Target{MinSeverity: SeverityMedium}, scan, true, SeverityMedium, 0},
+		// Trimming the changes also lowers the event's severity
+		{"checks trim changes", Target{Checks: []string{"server_info"}}, changed, true, SeverityLow, 1},
+		{"checks then min severity", Target{Checks: []string{"server_info"}, MinSeverity: SeverityMedium}, changed, false, "", 0},
+		{"no matching checks", Target{Checks: []string{"dns_records"}}, changed, false, "", 0},
+		{"checks ignore scans", Target{Checks: []string{"dns_records"}}, scan, true, SeverityMedium, 0},
+		{"checks on an expiry", Target{Checks: []string{"ssl_certificate"}}, expiry, false, "", 0},
+	}
+	for _, tt := range tests {
+		event, ok := tt.target.accepts(tt.event)
+		if ok != tt.ok {
+			t.Errorf("%s: accepts = %t, want %t", tt.name, ok, tt.ok)
+			continue
+		}
+		if ok && (event.Severity != tt.severity || len(event.Changes) != tt.changes) {
+			t.Errorf("%s: sent %s with %d changes, want %s with %d", tt.name, event.Severity, len(event.Changes), tt.severity, tt.changes)
+		}
+	}
+	if len(changed.Changes) != 2 {
+		t.Error("accepts modified the caller's event")
+	}
+}
+
+func TestNewValidatesTargets(t *testing.T) {
+	invalid := []Target{
+		{URL: "ftp://example.com/hook"},
+		{URL: "https://example.com/hook", Format: "discord"},
+		{URL: "https://example.com/hook", MinSeverity: "urgent"},
+	}
+	for _, target := range invalid {
+		if _, err := New([]Target{target}); err == nil {
+			t.Errorf("New accepted %+v", target)
+		}
+	}
+	d := newDispatcher(t, Target{URL: "https://example.com/hook"})
+	if d.targets[0].Format != "webhook" || d.targets[0].MaxAttempts != defaultMaxAttempts {
+		t.Errorf("defaults = %+v", d.targets[0])
+	}
+}
@@ -0,0 +1,218 @@
+package scanner
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	caBundleMu sync.RWMutex
+	caBundle   *x509.CertPool
+)
+
+// CertificateInfo describes one certificate of the chain a server presented
+type CertificateInfo struct {
+	Subject           string   `json:"subject"`
+	Issuer            string   `json:"issuer"`
+	SANs              []string `json:"sans,omitempty"`
+	Serial            string   `json:"serial"`
+	KeyType           string   `json:"key_type"`
+	KeyBits           int      `json:"key_bits,omitempty"`
+	SignatureAlgo     string   `json:"signature_algorithm"`
+	NotBefore         string   `json:"not_before"`
+	NotAfter          string   `json:"not_after"`
+	IsCA              bool     `json:"is_ca"`
+	SelfSigned        bool     `json:"self_signed"`
+	FingerprintSHA256 string   `json:"fingerprint_sha256"`
+}
+
+// SetCABundle trusts the PEM certificates in path in addition to the system roots
+// when the ssl_certificate check validates chains, e.g. for an internal CA. An empty
+// path goes back to the system roots only.
+func SetCABundle(path string) error {
+	var pool *x509.CertPool
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		pool, err = x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return fmt.Errorf("%s contains no PEM certificates", path)
+		}
+	}
+
+	caBundleMu.Lock()
+	defer caBundleMu.Unlock()
+	caBundle = pool
+	return nil
+}
+
+// trustedRoots returns the custom pool, or nil to make x509 use the system roots
+func trustedRoots() *x509.CertPool {
+	caBundleMu.RLock()
+	defer caBundleMu.RUnlock()
+	return caBundle
+}
+
+func describeCertificate(cert *x509.Certificate) CertificateInfo {
+	keyType, keyBits := publicKeyInfo(cert)
+	sum := sha256.Sum256(cert.Raw)
+	return CertificateInfo{
+		Subject:           cert.Subject.String(),
+		Issuer:            cert.Issuer.String(),
+		SANs:              certificateNames(cert),
+		Serial:            formatSerial(cert),
+		KeyType:           keyType,
+		KeyBits:           keyBits,
+		SignatureAlgo:     cert.SignatureAlgorithm.String(),
+		NotBefore:         cert.NotBefore.UTC().Format(time.RFC3339),
+		NotAfter:          cert.NotAfter.UTC().Format(time.RFC3339),
+		IsCA:              cert.IsCA,
+		SelfSigned:        isSelfSigned(cert),
+		FingerprintSHA256: colonHex(sum[:]),
+	}
+}
+
+// verifyChain validates the presented chain against the trusted roots and the
+// hostname, returning findings for every problem found.
+func verifyChain(chain []*x509.Certificate, host string, now time.Time) (trusted, hostnameOK bool, findings []Finding) {
+	leaf := chain[0]
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         trustedRoots(),
+		Intermediates: intermediates,
+		CurrentTime:   now,
+	})
+	trusted = err == nil
+
+	var unknownAuthority x509.UnknownAuthorityError
+	var invalid x509.CertificateInvalidError
+	switch {
+	case err == nil:
+	case len(chain) == 1 && isSelfSigned(leaf):
+		findings = append(findings, Finding{
+			Severity:       SeverityHigh,
+			Title:          "Self-signed certificate",
+			Detail:         "The server presents a self-signed certificate that browsers will not trust.",
+			Recommendation: "Use a certificate issued by a public CA, or distribute the internal CA to clients.",
+		})
+	case errors.As(err, &unknownAuthority):
+		findings = append(findings, Finding{
+			Severity:       SeverityHigh,
+			Title:          "Untrusted or incomplete certificate chain",
+			Detail:         fmt.Sprintf("The chain does not lead to a trusted root: %v", err),
+			Recommendation: "Serve the full intermediate chain and make sure the root CA is publicly trusted.",
+		})
+	case errors.As(err, &invalid) && invalid.Reason == x509.Expired:
+		// The leaf's expiry is reported by the expiry findings; only flag expired CA certs here
+		for _, cert := range chain[1:] {
+			if now.After(cert.NotAfter) {
+				findings = append(findings, Finding{
+					Severity:       SeverityCritical,
+					Title:          "Expired certificate in chain",
+					Detail:         fmt.Sprintf("%s expired on %s.", cert.Subject.CommonName, cert.NotAfter.Format("2006-01-02")),
+					Recommendation: "Replace the expired intermediate with the CA's current one.",
+				})
+			}
+		}
+	default:
+		findings = append(findings, Finding{
+			Severity:       SeverityHigh,
+			Title:          "Certificate chain failed validation",
+			Detail:         err.Error(),
+			Recommendation: "Reissue the certificate or fix the served chain.",
+		})
+	}
+
+	hostnameOK = leaf.VerifyHostname(host) == nil
+	if !hostnameOK {
+		findings = append(findings, Finding{
+			Severity:       SeverityHigh,
+			Title:          "Certificate does not match hostname",
+			Detail:         fmt.Sprintf("%s is not covered by the certificate names (%s).", host, namesOrNone(leaf)),
+			Recommendation: "Issue a certificate that includes this hostname in its SANs.",
+		})
+	}
+
+	for _, cert := range chain {
+		if rsaKey, ok := cert.PublicKey.(*rsa.PublicKey); ok && rsaKey.N.BitLen() < 2048 {
+			findings = append(findings, Finding{
+				Severity:       SeverityMedium,
+				Title:          "Weak RSA key",
+				Detail:         fmt.Sprintf("%s uses a %d-bit RSA key.", cert.Subject.CommonName, rsaKey.N.BitLen()),
+				Recommendation: "Use RSA keys of at least 2048 bits, or ECDSA P-256.",
+			})
+		}
+	}
+	return trusted, hostnameOK, findings
+}
+
+func publicKeyInfo(cert *x509.Certificate) (string, int) {
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return "RSA", key.N.BitLen()
+	case *ecdsa.PublicKey:
+		return "ECDSA " + key.Curve.Params().Name, key.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		return "Ed25519", 256
+	}
+	return cert.PublicKeyAlgorithm.String(), 0
+}
+
+// certificateNames lists the DNS and IP SANs of cert
+func certificateNames(cert *x509.Certificate) []string {
+	names := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		names = append(names, ip.String())
+	}
+	return names
+}
+
+func namesOrNone(cert *x509.Certificate) string {
+	if names := certificateNames(cert); len(names) > 0 {
+		return strings.Join(names, ", ")
+	}
+	return "no SAN entries"
+}
+
+// isSelfSigned checks the signature directly: CheckSignatureFrom would refuse the many
+// self-signed server certificates that are not marked as a CA
+func isSelfSigned(cert *x509.Certificate) bool {
+	return cert.Subject.String() == cert.Issuer.String() &&
+		cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil
+}
+
+func formatSerial(cert *x509.Certificate) string {
+	if cert.SerialNumber == nil {
+		return ""
+	}
+	return colonHex(cert.SerialNumber.Bytes())
+}
+
+// colonHex formats bytes as upper-case hex pairs separated by colons, the way
+// browsers and openssl display fingerprints and serials
+func colonHex(b []byte) string {
+	pairs := make([]string, len(b))
+	for i, v := range b {
+		pairs[i] = strings.ToUpper(hex.EncodeToString([]byte{v}))
+	}
+	return strings.Join(pairs, ":")
+}
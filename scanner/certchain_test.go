@@ -0,0 +1,141 @@
+package scanner
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// issue signs template with parentKey (self-signed when parent is nil) for a new
+// key, RSA of rsaBits or else ECDSA P-256
+func issue(t *testing.T, template, parent *x509.Certificate, parentKey crypto.Signer, rsaBits int) (*x509.Certificate, crypto.Signer) {
+	t.Helper()
+	var key crypto.Signer
+	var err error
+	if rsaBits > 0 {
+		key, err = rsa.GenerateKey(rand.Reader, rsaBits)
+	} else {
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func caTemplate(name string, notBefore, notAfter time.Time) *x509.Certificate {
+	return &x509.Certificate{
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+}
+
+func leafTemplate(name string, notBefore, notAfter time.Time) *x509.Certificate {
+	return &x509.Certificate{
+		Subject:     pkix.Name{CommonName: name},
+		DNSNames:    []string{name},
+		NotBefore:   notBefore,
+		NotAfter:    notAfter,
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+}
+
+// trustRoot makes root the only extra trusted CA for the rest of the test
+func trustRoot(t *testing.T, root *x509.Certificate) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: root.Raw}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetCABundle(path); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SetCABundle("") })
+}
+
+func TestVerifyChain(t *testing.T) {
+	now := time.Now()
+	year := 365 * 24 * time.Hour
+
+	root, rootKey := issue(t, caTemplate("URLHawk Test Root", now.Add(-year), now.Add(10*year)), nil, nil, 0)
+	inter, interKey := issue(t, caTemplate("URLHawk Test Intermediate", now.Add(-year), now.Add(5*year)), root, rootKey, 0)
+	leaf, _ := issue(t, leafTemplate("www.example.test", now.Add(-time.Hour), now.Add(90*24*time.Hour)), inter, interKey, 0)
+	trustRoot(t, root)
+
+	// An intermediate that expired while the leaf it signed is still valid
+	oldInter, oldInterKey := issue(t, caTemplate("URLHawk Old Intermediate", now.Add(-2*year), now.Add(-time.Hour)), root, rootKey, 0)
+	orphan, _ := issue(t, leafTemplate("www.example.test", now.Add(-2*year+time.Hour), now.Add(30*24*time.Hour)), oldInter, oldInterKey, 0)
+
+	selfSigned, _ := issue(t, leafTemplate("www.example.test", now.Add(-time.Hour), now.Add(year)), nil, nil, 0)
+	selfSignedCA := leafTemplate("www.example.test", now.Add(-time.Hour), now.Add(year))
+	selfSignedCA.IsCA, selfSignedCA.BasicConstraintsValid = true, true
+	selfSignedCA.KeyUsage |= x509.KeyUsageCertSign
+	selfSignedRoot, _ := issue(t, selfSignedCA, nil, nil, 0)
+	weak, _ := issue(t, leafTemplate("www.example.test", now.Add(-time.Hour), now.Add(year)), inter, interKey, 1024)
+
+	tests := []struct {
+		name       string
+		chain      []*x509.Certificate
+		host       string
+		trusted    bool
+		hostnameOK bool
+		findings   []string
+	}{
+		{"full chain", []*x509.Certificate{leaf, inter}, "www.example.test", true, true, nil},
+		{"root served too", []*x509.Certificate{leaf, inter, root}, "www.example.test", true, true, nil},
+		{"missing intermediate", []*x509.Certificate{leaf}, "www.example.test", false, true, []string{"Untrusted or incomplete certificate chain"}},
+		{"self-signed", []*x509.Certificate{selfSigned}, "www.example.test", false, true, []string{"Self-signed certificate"}},
+		{"self-signed CA", []*x509.Certificate{selfSignedRoot}, "www.example.test", false, true, []string{"Self-signed certificate"}},
+		{"expired intermediate", []*x509.Certificate{orphan, oldInter}, "www.example.test", false, true, []string{"Expired certificate in chain"}},
+		{"wrong host", []*x509.Certificate{leaf, inter}, "shop.example.test", true, false, []string{"Certificate does not match hostname"}},
+		{"weak key", []*x509.Certificate{weak, inter}, "www.example.test", true, true, []string{"Weak RSA key"}},
+	}
+	for _, tt := range tests {
+		trusted, hostnameOK, findings := verifyChain(tt.chain, tt.host, now)
+		titles := findingTitles(findings)
+		if trusted != tt.trusted || hostnameOK != tt.hostnameOK || !slices.Equal(titles, tt.findings) {
+			t.Errorf("%s: verifyChain = %t, %t, %q, want %t, %t, %q", tt.name, trusted, hostnameOK, titles, tt.trusted, tt.hostnameOK, tt.findings)
+		}
+	}
+
+	// The leaf's own expiry is left to the expiry findings
+	_, _, findings := verifyChain([]*x509.Certificate{leaf, inter}, "www.example.test", now.Add(91*24*time.Hour))
+	if len(findings) != 0 {
+		t.Errorf("an expired leaf raised chain findings %q", findingTitles(findings))
+	}
+
+	// Without the custom bundle the test root is not trusted
+	SetCABundle("")
+	if trusted, _, findings := verifyChain([]*x509.Certificate{leaf, inter}, "www.example.test", now); trusted || len(findings) != 1 || !strings.Contains(findings[0].Title, "Untrusted") {
+		t.Errorf("system roots trusted the test chain: %t, %q", trusted, findingTitles(findings))
+	}
+}
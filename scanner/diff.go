@@ -0,0 +1,455 @@
+package scanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Change is a single difference between two scans of the same target
+type Change struct {
+	Check  string `json:"check"`
+	Kind   string `json:"kind"` // added, removed or changed
+	Item   string `json:"item"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// certIdentityFields change together when a certificate is replaced
+var certIdentityFields = []string{"Subject", "Issuer", "Expires", "Algorithm", "Fingerprint SHA-256"}
+
+// derivedFields are recomputed on every scan (countdowns, timings) or are bulk
+// inventories (sitemap URLs) and would otherwise show up as changes each time
+var derivedFields = map[string]bool{
+	"Days Until Expiry": true,
+	"CRL Next Update":   true,
+	"Timing":            true,
+	"Size":              true,
+	"URLs":              true,
+}
+
+// DiffResults compares two result maps as returned by RunAllChecks (or loaded back
+// from JSON) and reports what changed per check. Checks that errored in either scan
+// are skipped, so a timeout does not show up as everything disappearing.
+func DiffResults(before, after map[string]interface{}) []Change {
+	// Live results hold typed values (map[string]int, structs, ...) that stored ones
+	// lost in JSON; compare both in their JSON form
+	before, after = jsonShape(before), jsonShape(after)
+
+	keys := make(map[string]bool)
+	for k := range before {
+		keys[k] = true
+	}
+	for k := range after {
+		keys[k] = true
+	}
+
+	var names []string
+	for k := range keys {
+		if k != "url" {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+
+	var changes []Change
+	for _, check := range names {
+		prev, next := before[check], after[check]
+		if isErrorResult(prev) || isErrorResult(next) {
+			continue
+		}
+
+		changes = append(changes, diffSets(check, "finding", findingKeys(prev), findingKeys(next))...)
+		prev, next = withoutFindings(prev), withoutFindings(next)
+
+		switch check {
+		case "ssl_certificate":
+			changes = append(changes, diffCertificate(prev, next)...)
+		case "tech_stack":
+			changes = append(changes, diffKeyValues(check, splitPairs(toStringList(prev)), splitPairs(toStringList(next)))...)
+		default:
+			changes = append(changes, diffGeneric(check, prev, next)...)
+		}
+	}
+	return changes
+}
+
+// NewFindings keeps only the changes that introduce something that was not there
+// before, which is what -baseline mode reports.
+func NewFindings(changes []Change) []Change {
+	var added []Change
+	for _, c := range changes {
+		if c.Kind == ChangeAdded || (c.Check == "ssl_certificate" && c.Kind == ChangeChanged) {
+			added = append(added, c)
+		}
+	}
+	return added
+}
+
+// String renders the change as a single human readable line
+func (c Change) String() string {
+	switch c.Check {
+	case "open_ports":
+		if c.Kind == ChangeAdded {
+			return fmt.Sprintf("New open port %s", c.Item)
+		}
+		return fmt.Sprintf("Port %s is no longer open", c.Item)
+	case "missing_headers":
+		if c.Kind == ChangeAdded {
+			return fmt.Sprintf("Header now missing: %s", c.Item)
+		}
+		return fmt.Sprintf("Header now present: %s", c.Item)
+	case "exposed_files":
+		if c.Kind == ChangeAdded {
+			return fmt.Sprintf("Newly exposed file: %s", c.Item)
+		}
+		return fmt.Sprintf("File no longer exposed: %s", c.Item)
+	case "ssl_certificate":
+		if c.Item == "certificate" {
+			return fmt.Sprintf("Certificate rotated: %s -> %s", c.Before, c.After)
+		}
+	case "dns_records":
+		if c.Kind == ChangeAdded {
+			return fmt.Sprintf("New DNS record %s", c.Item)
+		}
+		if c.Kind == ChangeRemoved {
+			return fmt.Sprintf("DNS record removed %s", c.Item)
+		}
+	}
+
+	switch c.Kind {
+	case ChangeAdded:
+		return fmt.Sprintf("%s: added %s", c.Check, c.Item)
+	case ChangeRemoved:
+		return fmt.Sprintf("%s: removed %s", c.Check, c.Item)
+	}
+	return fmt.Sprintf("%s: %s changed from %q to %q", c.Check, c.Item, c.Before, c.After)
+}
+
+// FormatChanges renders changes grouped by check for terminal output
+func FormatChanges(changes []Change) string {
+	if len(changes) == 0 {
+		return "No changes detected.\n"
+	}
+
+	var b strings.Builder
+	lastCheck := ""
+	for _, c := range changes {
+		if c.Check != lastCheck {
+			fmt.Fprintf(&b, "[%s]\n", c.Check)
+			lastCheck = c.Check
+		}
+		marker := "~"
+		if c.Kind == ChangeAdded {
+			marker = "+"
+		} else if c.Kind == ChangeRemoved {
+			marker = "-"
+		}
+		fmt.Fprintf(&b, "  %s %s\n", marker, c)
+	}
+	return b.String()
+}
+
+func diffCertificate(prev, next interface{}) []Change {
+	before, after := toStringMap(prev), toStringMap(next)
+	if len(before) == 0 || len(after) == 0 {
+		return diffGeneric("ssl_certificate", prev, next)
+	}
+
+	rotated := false
+	for _, field := range certIdentityFields {
+		// Fields added in newer versions are missing from older scans; only compare both sides
+		prevValue, inBefore := before[field]
+		nextValue, inAfter := after[field]
+		if inBefore && inAfter && prevValue != nextValue {
+			rotated = true
+		}
+	}
+
+	var changes []Change
+	if rotated {
+		changes = append(changes, Change{
+			Check:  "ssl_certificate",
+			Kind:   ChangeChanged,
+			Item:   "certificate",
+			Before: fmt.Sprintf("%s (issuer %s, expires %s)", before["Subject"], before["Issuer"], before["Expires"]),
+			After:  fmt.Sprintf("%s (issuer %s, expires %s)", after["Subject"], after["Issuer"], after["Expires"]),
+		})
+	}
+
+	// Anything else (validity, SANs, ...) is reported field by field. The chain is
+	// covered by the rotation check above.
+	skip := map[string]bool{"Chain": true}
+	for field := range derivedFields {
+		skip[field] = true
+	}
+	for _, field := range certIdentityFields {
+		skip[field] = true
+	}
+	for _, c := range diffKeyValues("ssl_certificate", before, after) {
+		if !skip[c.Item] {
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+// diffGeneric handles lists (set difference), maps of lists (set difference per key,
+// e.g. DNS record types) and maps of scalars (value changes per key).
+func diffGeneric(check string, prev, next interface{}) []Change {
+	if isList(prev) || isList(next) {
+		return diffSets(check, "", toStringList(prev), toStringList(next))
+	}
+
+	before, after := toAnyMap(prev), toAnyMap(next)
+	if before == nil && after == nil {
+		o, n := stringify(prev), stringify(next)
+		if o == n {
+			return nil
+		}
+		return []Change{{Check: check, Kind: ChangeChanged, Item: "value", Before: o, After: n}}
+	}
+
+	var changes []Change
+	for _, key := range unionKeys(before, after) {
+		if derivedFields[key] {
+			continue
+		}
+		o, n := before[key], after[key]
+		if isList(o) || isList(n) {
+			changes = append(changes, diffSets(check, key, toStringList(o), toStringList(n))...)
+			continue
+		}
+		changes = append(changes, diffKeyValues(check, map[string]string{key: stringify(o)}, map[string]string{key: stringify(n)})...)
+	}
+	return changes
+}
+
+func diffSets(check, prefix string, before, after []string) []Change {
+	oldSet, newSet := make(map[string]bool), make(map[string]bool)
+	for _, v := range before {
+		oldSet[v] = true
+	}
+	for _, v := range after {
+		newSet[v] = true
+	}
+
+	label := func(v string) string {
+		if prefix == "" {
+			return v
+		}
+		return prefix + " " + v
+	}
+
+	var changes []Change
+	for _, v := range sortedKeys(newSet) {
+		if !oldSet[v] {
+			changes = append(changes, Change{Check: check, Kind: ChangeAdded, Item: label(v)})
+		}
+	}
+	for _, v := range sortedKeys(oldSet) {
+		if !newSet[v] {
+			changes = append(changes, Change{Check: check, Kind: ChangeRemoved, Item: label(v)})
+		}
+	}
+	return changes
+}
+
+func diffKeyValues(check string, before, after map[string]string) []Change {
+	var changes []Change
+	for _, key := range unionStringKeys(before, after) {
+		o, hadOld := before[key]
+		n, hasNew := after[key]
+		switch {
+		case hadOld && o != "" && (!hasNew || n == ""):
+			changes = append(changes, Change{Check: check, Kind: ChangeRemoved, Item: fmt.Sprintf("%s: %s", key, o), Before: o})
+		case hasNew && n != "" && (!hadOld || o == ""):
+			changes = append(changes, Change{Check: check, Kind: ChangeAdded, Item: fmt.Sprintf("%s: %s", key, n), After: n})
+		case o != n:
+			changes = append(changes, Change{Check: check, Kind: ChangeChanged, Item: key, Before: o, After: n})
+		}
+	}
+	return changes
+}
+
+// splitPairs turns ["Server: nginx", ...] entries as produced by tech_stack into a map
+func splitPairs(items []string) map[string]string {
+	pairs := make(map[string]string)
+	for _, item := range items {
+		if k, v, ok := strings.Cut(item, ": "); ok {
+			pairs[k] = v
+		} else {
+			pairs[item] = item
+		}
+	}
+	return pairs
+}
+
+// jsonShape round-trips each check's result through JSON so only the types JSON
+// decodes to remain. Values that cannot be encoded are kept as they are.
+func jsonShape(results map[string]interface{}) map[string]interface{} {
+	shaped := make(map[string]interface{}, len(results))
+	for check, v := range results {
+		shaped[check] = v
+		data, err := json.Marshal(v)
+		if err != nil {
+			continue
+		}
+		var decoded interface{}
+		if json.Unmarshal(data, &decoded) == nil {
+			shaped[check] = decoded
+		}
+	}
+	return shaped
+}
+
+// findingKeys identifies a check's findings by severity and title. Detail is left
+// out: it carries counts and dates that change on every scan.
+func findingKeys(v interface{}) []string {
+	list, _ := toAnyMap(v)["findings"].([]interface{})
+	keys := make([]string, 0, len(list))
+	for _, item := range list {
+		if f := toAnyMap(item); f != nil {
+			keys = append(keys, fmt.Sprintf("[%s] %s", stringify(f["severity"]), stringify(f["title"])))
+		}
+	}
+	return keys
+}
+
+// withoutFindings drops the findings from a result so the rest is diffed field by field
+func withoutFindings(v interface{}) interface{} {
+	m := toAnyMap(v)
+	if _, ok := m["findings"]; !ok {
+		return v
+	}
+	rest := make(map[string]interface{}, len(m))
+	for k, val := range m {
+		if k != "findings" {
+			rest[k] = val
+		}
+	}
+	return rest
+}
+
+func isErrorResult(v interface{}) bool {
+	m := toAnyMap(v)
+	if m == nil {
+		return false
+	}
+	_, hasErr := m["error"]
+	return hasErr && len(m) == 1
+}
+
+func isList(v interface{}) bool {
+	switch v.(type) {
+	case []string, []interface{}:
+		return true
+	}
+	return false
+}
+
+func toStringList(v interface{}) []string {
+	switch t := v.(type) {
+	case []string:
+		return t
+	case []interface{}:
+		out := make([]string, 0, len(t))
+		for _, item := range t {
+			out = append(out, stringify(item))
+		}
+		return out
+	}
+	return nil
+}
+
+func toAnyMap(v interface{}) map[string]interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		return t
+	case map[string]string:
+		out := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			out[k] = val
+		}
+		return out
+	}
+	return nil
+}
+
+func toStringMap(v interface{}) map[string]string {
+	m := toAnyMap(v)
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]string, len(m))
+	for k, val := range m {
+		out[k] = stringify(val)
+	}
+	return out
+}
+
+func stringify(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case []string, []interface{}:
+		return strings.Join(toStringList(t), ", ")
+	case map[string]interface{}, map[string]string:
+		// Nested records, e.g. one per port: compare them as sorted key=value pairs,
+		// leaving out fields that change on every scan
+		m := toAnyMap(t)
+		pairs := make([]string, 0, len(m))
+		for _, k := range unionKeys(m, nil) {
+			if !derivedFields[k] {
+				pairs = append(pairs, k+"="+stringify(m[k]))
+			}
+		}
+		return strings.Join(pairs, ", ")
+	case float64:
+		// Numbers come back from JSON as float64; print whole numbers without decimals
+		if t == float64(int64(t)) {
+			return fmt.Sprintf("%d", int64(t))
+		}
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func unionKeys(a, b map[string]interface{}) []string {
+	set := make(map[string]bool)
+	for k := range a {
+		set[k] = true
+	}
+	for k := range b {
+		set[k] = true
+	}
+	return sortedKeys(set)
+}
+
+func unionStringKeys(a, b map[string]string) []string {
+	set := make(map[string]bool)
+	for k := range a {
+		set[k] = true
+	}
+	for k := range b {
+		set[k] = true
+	}
+	return sortedKeys(set)
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,143 @@
+package scanner
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// roundTrip returns results as they come back from a JSON export or the history store
+func roundTrip(t *testing.T, results map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	data, err := json.Marshal(results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stored map[string]interface{}
+	if err := json.Unmarshal(data, &stored); err != nil {
+		t.Fatal(err)
+	}
+	return stored
+}
+
+func TestDiffResultsLiveAgainstStored(t *testing.T) {
+	live := map[string]interface{}{
+		"url": "https://example.com",
+		"robots_txt": map[string]interface{}{
+			"URLs by Section": map[string]int{"/blog": 3, "/docs": 1},
+			"URL Count":       4,
+			"Disallowed":      []string{"/admin/"},
+			"Groups":          map[string]interface{}{"*": map[string]interface{}{"Disallow": []string{"/admin/"}}},
+		},
+		"open_ports":   []string{"80", "443"},
+		"server_info":  map[string]string{"Server": "nginx"},
+		"hsts_preload": map[string]interface{}{"Requirements": map[string]string{"preload directive": "pass"}},
+	}
+	stored := roundTrip(t, live)
+
+	if changes := DiffResults(stored, live); len(changes) != 0 {
+		t.Errorf("stored -> live reported %v for identical data", changes)
+	}
+	if changes := DiffResults(live, stored); len(changes) != 0 {
+		t.Errorf("live -> stored reported %v for identical data", changes)
+	}
+}
+
+func TestDiffResults(t *testing.T) {
+	before := map[string]interface{}{
+		"open_ports":  []string{"80", "443"},
+		"server_info": map[string]interface{}{"Server": "nginx", "Timing": "120ms"},
+		"dns_records": map[string]interface{}{"A": []string{"192.0.2.1"}},
+		"whois_info":  map[string]string{"error": "timeout"},
+	}
+	after := map[string]interface{}{
+		"open_ports":  []string{"443", "8080"},
+		"server_info": map[string]interface{}{"Server": "Apache", "Timing": "80ms"},
+		"dns_records": map[string]interface{}{"A": []string{"192.0.2.1"}, "AAAA": []string{"2001:db8::1"}},
+		"whois_info":  map[string]string{"Registrar": "Example"},
+	}
+
+	// whois_info errored before, so it is skipped rather than reported as all new
+	want := map[string]bool{
+		"New open port 8080":                                   true,
+		"Port 80 is no longer open":                            true,
+		`server_info: Server changed from "nginx" to "Apache"`: true,
+		"New DNS record AAAA 2001:db8::1":                      true,
+	}
+	changes := DiffResults(before, roundTrip(t, after))
+	for _, c := range changes {
+		if !want[c.String()] {
+			t.Errorf("unexpected change %q", c.String())
+		}
+		delete(want, c.String())
+	}
+	for missing := range want {
+		t.Errorf("missing change %q", missing)
+	}
+}
+
+func TestDiffResultsFindings(t *testing.T) {
+	scan := func(findings ...Finding) map[string]interface{} {
+		return map[string]interface{}{
+			"ssl_certificate": map[string]interface{}{"Subject": "example.com", "findings": findings},
+			"cookie_security": map[string]interface{}{"Cookies": 2, "findings": findings},
+		}
+	}
+	// Real expiry findings for a certificate checked on consecutive days
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	expiring := func(days int) Finding {
+		findings := certExpiryFindings(now.AddDate(-1, 0, 0), now.AddDate(0, 0, days), now)
+		if len(findings) != 1 {
+			t.Fatalf("certExpiryFindings for %d days = %+v", days, findings)
+		}
+		return findings[0]
+	}
+	leak := Finding{Severity: SeverityCritical, Title: "Credentials in remote URL", Detail: "https://REDACTED@git.example.com"}
+
+	tests := []struct {
+		name          string
+		before, after map[string]interface{}
+		want          []string
+	}{
+		{"detail only", scan(expiring(20)), scan(expiring(19)), nil},
+		{"new finding", scan(expiring(20)), scan(expiring(19), leak), []string{
+			"cookie_security: added finding [critical] Credentials in remote URL",
+			"ssl_certificate: added finding [critical] Credentials in remote URL",
+		}},
+		{"crosses a threshold", scan(expiring(8)), scan(expiring(7)), []string{
+			"cookie_security: added finding [high] Certificate expires within critical threshold",
+			"cookie_security: removed finding [medium] Certificate expires within warning threshold",
+			"ssl_certificate: added finding [high] Certificate expires within critical threshold",
+			"ssl_certificate: removed finding [medium] Certificate expires within warning threshold",
+		}},
+		{"resolved finding", scan(expiring(20), leak), scan(), []string{
+			"cookie_security: removed finding [critical] Credentials in remote URL",
+			"cookie_security: removed finding [medium] Certificate expires within warning threshold",
+			"ssl_certificate: removed finding [critical] Credentials in remote URL",
+			"ssl_certificate: removed finding [medium] Certificate expires within warning threshold",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := DiffResults(roundTrip(t, tt.before), tt.after)
+			var got []string
+			for _, c := range changes {
+				got = append(got, c.String())
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("changes = %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("change %d = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+
+	// -baseline reports only what is new
+	added := NewFindings(DiffResults(scan(), scan(leak)))
+	if len(added) != 2 {
+		t.Errorf("NewFindings = %v, want the critical finding for both checks", added)
+	}
+}
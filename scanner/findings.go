@@ -0,0 +1,92 @@
+package scanner
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// Finding severities, lowest first
+const (
+	SeverityInfo     = "info"
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+var severityRank = map[string]int{
+	SeverityInfo:     0,
+	SeverityLow:      1,
+	SeverityMedium:   2,
+	SeverityHigh:     3,
+	SeverityCritical: 4,
+}
+
+// Finding is an actionable issue raised by a check. Checks that produce findings
+// return them under the "findings" key of their result map.
+type Finding struct {
+	Severity       string `json:"severity"`
+	Title          string `json:"title"`
+	Detail         string `json:"detail,omitempty"`
+	Recommendation string `json:"recommendation,omitempty"`
+}
+
+// SeverityRank orders severities from info (0) to critical (4); unknown values rank as info
+func SeverityRank(severity string) int {
+	return severityRank[severity]
+}
+
+// ValidSeverity reports whether severity is one of the known levels
+func ValidSeverity(severity string) bool {
+	_, ok := severityRank[severity]
+	return ok
+}
+
+// CollectFindings gathers the findings of every check in results, keyed by check
+// name. It understands both live results and results loaded back from JSON.
+func CollectFindings(results map[string]interface{}) map[string][]Finding {
+	collected := make(map[string][]Finding)
+	for check, value := range results {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		switch findings := m["findings"].(type) {
+		case []Finding:
+			if len(findings) > 0 {
+				collected[check] = findings
+			}
+		case []interface{}:
+			// Round-trip through JSON to get typed findings back
+			data, err := json.Marshal(findings)
+			if err != nil {
+				continue
+			}
+			var typed []Finding
+			if json.Unmarshal(data, &typed) == nil && len(typed) > 0 {
+				collected[check] = typed
+			}
+		}
+	}
+	return collected
+}
+
+// MaxSeverity is the highest severity among the findings, or info when there are none
+func MaxSeverity(findings map[string][]Finding) string {
+	severity := SeverityInfo
+	for _, list := range findings {
+		for _, f := range list {
+			if severityRank[f.Severity] > severityRank[severity] {
+				severity = f.Severity
+			}
+		}
+	}
+	return severity
+}
+
+// sortFindings orders findings most severe first
+func sortFindings(findings []Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		return severityRank[findings[i].Severity] > severityRank[findings[j].Severity]
+	})
+}
@@ -0,0 +1,43 @@
+package scanner
+
+import (
+	"context"
+	"sync"
+)
+
+// scanMemo lets checks of the same scan share expensive intermediate results, such
+// as the port scan, instead of repeating the work. Checks run concurrently, so the
+// first caller computes a value and later callers wait for it.
+type scanMemo struct {
+	mu      sync.Mutex
+	entries map[string]*memoEntry
+}
+
+type memoEntry struct {
+	once  sync.Once
+	value interface{}
+}
+
+func withScanMemo(ctx context.Context) context.Context {
+	return context.WithValue(ctx, scanMemoKey, &scanMemo{entries: make(map[string]*memoEntry)})
+}
+
+// memoize returns the value stored under key for this scan, computing it with fn on
+// first use. Outside a scan (no memo in ctx) fn is simply called.
+func memoize(ctx context.Context, key string, fn func() interface{}) interface{} {
+	memo, ok := ctx.Value(scanMemoKey).(*scanMemo)
+	if !ok {
+		return fn()
+	}
+
+	memo.mu.Lock()
+	entry, ok := memo.entries[key]
+	if !ok {
+		entry = &memoEntry{}
+		memo.entries[key] = entry
+	}
+	memo.mu.Unlock()
+
+	entry.once.Do(func() { entry.value = fn() })
+	return entry.value
+}
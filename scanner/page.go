@@ -0,0 +1,128 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// maxPageBody caps how much of the landing page is kept for checks that inspect it
+const maxPageBody = 1 << 20
+
+// page is the target's landing page after redirects, fetched once per scan and shared
+// by the checks that only need its headers or markup.
+type page struct {
+	URL    string
+	Status int
+	Header http.Header
+	Body   []byte
+	Err    error
+}
+
+// fetchPage GETs url through pluginClient, memoized for the current scan
+func fetchPage(ctx context.Context, url string) *page {
+	return memoize(ctx, "page:"+url, func() interface{} {
+		p := &page{URL: url}
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			p.Err = err
+			return p
+		}
+		req.Header.Set("User-Agent", "URLHawkScanner-Scanner/1.0")
+
+		resp, err := pluginClient.Do(req)
+		if err != nil {
+			p.Err = err
+			return p
+		}
+		defer resp.Body.Close()
+
+		p.URL = resp.Request.URL.String()
+		p.Status = resp.StatusCode
+		p.Header = resp.Header
+		p.Body, _ = io.ReadAll(io.LimitReader(resp.Body, maxPageBody))
+		return p
+	}).(*page)
+}
+
+// maxRedirects is how many redirects redirectChain follows, the same limit as net/http
+const maxRedirects = 10
+
+// hop is one response in a redirect chain. Location is the resolved redirect target,
+// empty for the last hop.
+type hop struct {
+	URL      string
+	Status   int
+	Location string
+	Header   http.Header
+	Duration time.Duration
+}
+
+// chain is the sequence of responses from the target to the final page. Err is set
+// when a hop failed, the chain looped or the redirect limit was hit; Hops holds what
+// was reached.
+type chain struct {
+	Hops []hop
+	Loop bool
+	Err  error
+}
+
+// Final is the URL the chain ended on
+func (c *chain) Final() string {
+	if len(c.Hops) == 0 {
+		return ""
+	}
+	last := c.Hops[len(c.Hops)-1]
+	if last.Location != "" {
+		return last.Location
+	}
+	return last.URL
+}
+
+// redirectChain follows redirects from url one hop at a time, keeping every
+// response's headers, memoized for the current scan
+func redirectChain(ctx context.Context, url string) *chain {
+	return memoize(ctx, "redirects:"+url, func() interface{} {
+		c := &chain{}
+		next := url
+		visited := make(map[string]bool)
+		for i := 0; i <= maxRedirects; i++ {
+			if visited[next] {
+				c.Loop = true
+				c.Err = fmt.Errorf("redirect loop at %s", next)
+				return c
+			}
+			visited[next] = true
+
+			req, err := http.NewRequestWithContext(ctx, "GET", next, nil)
+			if err != nil {
+				c.Err = err
+				return c
+			}
+			req.Header.Set("User-Agent", "URLHawkScanner-Scanner/1.0")
+
+			started := time.Now()
+			resp, err := redirectProbeClient.Do(req)
+			if err != nil {
+				c.Err = err
+				return c
+			}
+			resp.Body.Close()
+			h := hop{URL: next, Status: resp.StatusCode, Header: resp.Header, Duration: time.Since(started)}
+
+			// A 3xx without Location (e.g. 304) ends the chain
+			loc, err := resp.Location()
+			if resp.StatusCode < 300 || resp.StatusCode > 399 || err != nil {
+				c.Hops = append(c.Hops, h)
+				return c
+			}
+			h.Location = loc.String()
+			c.Hops = append(c.Hops, h)
+			next = h.Location
+		}
+		c.Err = fmt.Errorf("stopped after %d redirects", maxRedirects)
+		return c
+	}).(*chain)
+}
@@ -0,0 +1,339 @@
+package scanner
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// pgpSignature holds the parts of an OpenPGP signature packet (RFC 9580) worth
+// reporting. The signature itself is not verified: that needs the signer's key.
+type pgpSignature struct {
+	version     int
+	sigType     byte
+	keyAlgo     byte
+	hashAlgo    byte
+	created     time.Time
+	lifetime    time.Duration
+	keyID       string
+	fingerprint string
+	checksum    string
+}
+
+var pgpHashNames = map[byte]string{
+	1: "MD5", 2: "SHA-1", 3: "RIPEMD-160", 8: "SHA-256", 9: "SHA-384", 10: "SHA-512",
+	11: "SHA-224", 12: "SHA3-256", 14: "SHA3-512",
+}
+
+var pgpKeyNames = map[byte]string{
+	1: "RSA", 3: "RSA (sign only)", 17: "DSA", 19: "ECDSA", 22: "EdDSA (legacy)",
+	27: "Ed25519", 28: "Ed448",
+}
+
+// splitClearsigned separates a cleartext-signed document into the signed text, with
+// dash escaping removed, and the armored signature block. ok is false when data is
+// not cleartext-signed; armored is nil when the signature block is missing.
+func splitClearsigned(data []byte) (message, armored []byte, ok bool) {
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	start := 0
+	for start < len(lines) && strings.TrimSpace(lines[start]) == "" {
+		start++
+	}
+	if start == len(lines) || strings.TrimSpace(lines[start]) != "-----BEGIN PGP SIGNED MESSAGE-----" {
+		return nil, nil, false
+	}
+
+	// Hash: armor headers run up to the first blank line
+	i := start + 1
+	for i < len(lines) && strings.TrimSpace(lines[i]) != "" {
+		i++
+	}
+	var text []string
+	for i++; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "-----BEGIN PGP SIGNATURE-----" {
+			return []byte(strings.Join(text, "\n")), []byte(strings.Join(lines[i:], "\n")), true
+		}
+		text = append(text, strings.TrimPrefix(lines[i], "- "))
+	}
+	return []byte(strings.Join(text, "\n")), nil, true
+}
+
+// parsePGPSignature decodes an armored signature block, checks its optional CRC-24
+// checksum and parses the first signature packet in it
+func parsePGPSignature(armored []byte) (*pgpSignature, error) {
+	if armored == nil {
+		return nil, errors.New("the signed message has no BEGIN PGP SIGNATURE block")
+	}
+
+	lines := strings.Split(string(armored), "\n")
+	var encoded strings.Builder
+	checksum := ""
+	inHeaders, ended := true, false
+	for _, line := range lines[1:] {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "-----END PGP SIGNATURE-----":
+			ended = true
+		case inHeaders && strings.Contains(line, ": "):
+			continue
+		case inHeaders && line == "":
+			inHeaders = false
+		case strings.HasPrefix(line, "=") && len(line) == 5:
+			checksum = line[1:]
+		default:
+			inHeaders = false
+			encoded.WriteString(line)
+		}
+		if ended {
+			break
+		}
+	}
+	if !ended {
+		return nil, errors.New("the signature block has no END PGP SIGNATURE line")
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(encoded.String())
+	if err != nil {
+		return nil, fmt.Errorf("the signature is not valid base64: %v", err)
+	}
+
+	checksumStatus := "absent"
+	if checksum != "" {
+		want, err := base64.StdEncoding.DecodeString(checksum)
+		checksumStatus = "invalid"
+		if err == nil && len(want) == 3 && uint32(want[0])<<16|uint32(want[1])<<8|uint32(want[2]) == crc24(raw) {
+			checksumStatus = "valid"
+		}
+	}
+
+	for len(raw) > 0 {
+		tag, body, rest, err := nextPGPPacket(raw)
+		if err != nil {
+			return nil, err
+		}
+		if tag == 2 {
+			sig, err := parseSignaturePacket(body)
+			if err != nil {
+				return nil, err
+			}
+			sig.checksum = checksumStatus
+			return sig, nil
+		}
+		raw = rest
+	}
+	return nil, errors.New("the signature block contains no signature packet")
+}
+
+// nextPGPPacket splits the first packet off data, handling old- and new-format headers
+func nextPGPPacket(data []byte) (tag byte, body, rest []byte, err error) {
+	truncated := errors.New("truncated OpenPGP packet")
+	if len(data) < 2 || data[0]&0x80 == 0 {
+		return 0, nil, nil, errors.New("malformed OpenPGP packet header")
+	}
+
+	var length, offset int
+	if data[0]&0x40 != 0 {
+		tag = data[0] & 0x3f
+		switch first := int(data[1]); {
+		case first < 192:
+			length, offset = first, 2
+		case first < 224:
+			if len(data) < 3 {
+				return 0, nil, nil, truncated
+			}
+			length, offset = (first-192)<<8+int(data[2])+192, 3
+		case first == 255:
+			if len(data) < 6 {
+				return 0, nil, nil, truncated
+			}
+			length, offset = int(binary.BigEndian.Uint32(data[2:6])), 6
+		default:
+			return 0, nil, nil, errors.New("partial-length OpenPGP packets are not supported")
+		}
+	} else {
+		tag = (data[0] >> 2) & 0x0f
+		switch data[0] & 0x03 {
+		case 0:
+			length, offset = int(data[1]), 2
+		case 1:
+			if len(data) < 3 {
+				return 0, nil, nil, truncated
+			}
+			length, offset = int(binary.BigEndian.Uint16(data[1:3])), 3
+		case 2:
+			if len(data) < 5 {
+				return 0, nil, nil, truncated
+			}
+			length, offset = int(binary.BigEndian.Uint32(data[1:5])), 5
+		default:
+			length, offset = len(data)-1, 1
+		}
+	}
+	if length < 0 || offset+length > len(data) {
+		return 0, nil, nil, truncated
+	}
+	return tag, data[offset : offset+length], data[offset+length:], nil
+}
+
+// parseSignaturePacket reads version 3, 4, 5 and 6 signature packets
+func parseSignaturePacket(body []byte) (*pgpSignature, error) {
+	truncated := errors.New("truncated OpenPGP signature packet")
+	if len(body) < 1 {
+		return nil, truncated
+	}
+	sig := &pgpSignature{version: int(body[0])}
+
+	switch sig.version {
+	case 3:
+		if len(body) < 17 || body[1] != 5 {
+			return nil, truncated
+		}
+		sig.sigType = body[2]
+		sig.created = time.Unix(int64(binary.BigEndian.Uint32(body[3:7])), 0).UTC()
+		sig.keyID = strings.ToUpper(hex.EncodeToString(body[7:15]))
+		sig.keyAlgo, sig.hashAlgo = body[15], body[16]
+		return sig, nil
+	case 4, 5, 6:
+	default:
+		return nil, fmt.Errorf("unsupported OpenPGP signature version %d", sig.version)
+	}
+
+	if len(body) < 4 {
+		return nil, truncated
+	}
+	sig.sigType, sig.keyAlgo, sig.hashAlgo = body[1], body[2], body[3]
+	rest := body[4:]
+	// Version 6 uses four-octet subpacket area lengths
+	sizeLen := 2
+	if sig.version == 6 {
+		sizeLen = 4
+	}
+	for area := 0; area < 2; area++ {
+		if len(rest) < sizeLen {
+			return nil, truncated
+		}
+		var n int
+		if sizeLen == 2 {
+			n = int(binary.BigEndian.Uint16(rest))
+		} else {
+			n = int(binary.BigEndian.Uint32(rest))
+		}
+		rest = rest[sizeLen:]
+		if n < 0 || n > len(rest) {
+			return nil, truncated
+		}
+		if err := sig.readSubpackets(rest[:n]); err != nil {
+			return nil, err
+		}
+		rest = rest[n:]
+	}
+	return sig, nil
+}
+
+// readSubpackets picks the creation time, expiry and issuer out of a subpacket area
+func (sig *pgpSignature) readSubpackets(area []byte) error {
+	for len(area) > 0 {
+		var n, offset int
+		switch first := int(area[0]); {
+		case first < 192:
+			n, offset = first, 1
+		case first < 255:
+			if len(area) < 2 {
+				return errors.New("truncated signature subpacket")
+			}
+			n, offset = (first-192)<<8+int(area[1])+192, 2
+		default:
+			if len(area) < 5 {
+				return errors.New("truncated signature subpacket")
+			}
+			n, offset = int(binary.BigEndian.Uint32(area[1:5])), 5
+		}
+		if n < 1 || offset+n > len(area) {
+			return errors.New("truncated signature subpacket")
+		}
+		kind, data := area[offset]&0x7f, area[offset+1:offset+n]
+		area = area[offset+n:]
+
+		switch {
+		case kind == 2 && len(data) == 4:
+			sig.created = time.Unix(int64(binary.BigEndian.Uint32(data)), 0).UTC()
+		case kind == 3 && len(data) == 4:
+			sig.lifetime = time.Duration(binary.BigEndian.Uint32(data)) * time.Second
+		case kind == 16 && len(data) == 8:
+			sig.keyID = strings.ToUpper(hex.EncodeToString(data))
+		case kind == 33 && len(data) > 1:
+			sig.fingerprint = strings.ToUpper(hex.EncodeToString(data[1:]))
+		}
+	}
+	return nil
+}
+
+func (sig *pgpSignature) hashName() string {
+	if name, ok := pgpHashNames[sig.hashAlgo]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown (%d)", sig.hashAlgo)
+}
+
+// weakHash reports hashes that no longer resist collisions
+func (sig *pgpSignature) weakHash() bool {
+	return sig.hashAlgo == 1 || sig.hashAlgo == 2 || sig.hashAlgo == 3
+}
+
+func (sig *pgpSignature) describe() map[string]interface{} {
+	keyAlgo, ok := pgpKeyNames[sig.keyAlgo]
+	if !ok {
+		keyAlgo = fmt.Sprintf("unknown (%d)", sig.keyAlgo)
+	}
+	described := map[string]interface{}{
+		"Version":        sig.version,
+		"Hash Algorithm": sig.hashName(),
+		"Key Algorithm":  keyAlgo,
+		"Armor Checksum": sig.checksum,
+		"Signature Type": pgpSignatureType(sig.sigType),
+		"Verified":       "No (the signing key is not retrieved)",
+	}
+	if !sig.created.IsZero() {
+		described["Created"] = sig.created.Format(time.RFC3339)
+		if sig.lifetime > 0 {
+			described["Expires"] = sig.created.Add(sig.lifetime).Format(time.RFC3339)
+		}
+	}
+	if sig.keyID != "" {
+		described["Issuer Key ID"] = sig.keyID
+	}
+	if sig.fingerprint != "" {
+		described["Issuer Fingerprint"] = sig.fingerprint
+	}
+	return described
+}
+
+// pgpSignatureType names the document signature types; clearsigned files use text
+func pgpSignatureType(t byte) string {
+	switch t {
+	case 0x00:
+		return "binary"
+	case 0x01:
+		return "text"
+	}
+	return fmt.Sprintf("0x%02x", t)
+}
+
+// crc24 is the OpenPGP armor checksum
+func crc24(data []byte) uint32 {
+	crc := uint32(0xb704ce)
+	for _, b := range data {
+		crc ^= uint32(b) << 16
+		for i := 0; i < 8; i++ {
+			crc <<= 1
+			if crc&0x1000000 != 0 {
+				crc ^= 0x1864cfb
+			}
+		}
+	}
+	return crc & 0xffffff
+}
@@ -0,0 +1,131 @@
+package scanner
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+)
+
+// gnupgClearsigned was made with gpg --clearsign --digest-algo SHA256 and a throwaway
+// Ed25519 key, fingerprint EB9E20E18161F185E138680BC7A8E9DF609D912C
+const gnupgClearsigned = `-----BEGIN PGP SIGNED MESSAGE-----
+Hash: SHA256
+
+Contact: mailto:security@example.com
+Expires: 2030-01-01T00:00:00Z
+Canonical: https://example.com/.well-known/security.txt
+- - dash line
+-----BEGIN PGP SIGNATURE-----
+
+iHUEARYIAB0WIQTrniDhgWHxheE4aAvHqOnfYJ2RLAUCatXrggAKCRDHqOnfYJ2R
+LNWUAQDWUd13/Aa7hd0zV6S2PGCxkZrIeB78x4Ef2DylAWuPRQD/aA10LT1lUmjh
+byDFOvceMGXWUNJuRCB5jkqJtJ5Pmws=
+=Oehm
+-----END PGP SIGNATURE-----
+`
+
+// armorSignature wraps raw packets in a signature block without an armor checksum
+func armorSignature(raw []byte) []byte {
+	return []byte("-----BEGIN PGP SIGNATURE-----\n\n" + base64.StdEncoding.EncodeToString(raw) + "\n-----END PGP SIGNATURE-----")
+}
+
+func TestCRC24(t *testing.T) {
+	// The check value of CRC-24/OPENPGP
+	if got := crc24([]byte("123456789")); got != 0x21cf02 {
+		t.Errorf("crc24(123456789) = %06x, want 21cf02", got)
+	}
+	if got := crc24(nil); got != 0xb704ce {
+		t.Errorf("crc24(nil) = %06x, want the initial value b704ce", got)
+	}
+}
+
+func TestSplitClearsigned(t *testing.T) {
+	message, armored, ok := splitClearsigned([]byte(strings.ReplaceAll(gnupgClearsigned, "\n", "\r\n")))
+	if !ok {
+		t.Fatal("a clearsigned file was not recognised")
+	}
+	want := "Contact: mailto:security@example.com\nExpires: 2030-01-01T00:00:00Z\nCanonical: https://example.com/.well-known/security.txt\n- dash line"
+	if string(message) != want {
+		t.Errorf("message = %q, want %q", message, want)
+	}
+	if !strings.HasPrefix(string(armored), "-----BEGIN PGP SIGNATURE-----") {
+		t.Errorf("armored = %q", armored)
+	}
+
+	if _, _, ok := splitClearsigned([]byte("Contact: mailto:security@example.com\n")); ok {
+		t.Error("an unsigned file was taken as clearsigned")
+	}
+	unfinished := strings.Split(gnupgClearsigned, "-----BEGIN PGP SIGNATURE-----")[0]
+	if _, armored, ok := splitClearsigned([]byte(unfinished)); !ok || armored != nil {
+		t.Errorf("a file without its signature block = %q, %t", armored, ok)
+	}
+}
+
+func TestParsePGPSignatureGnuPG(t *testing.T) {
+	_, armored, _ := splitClearsigned([]byte(gnupgClearsigned))
+	sig, err := parsePGPSignature(armored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sig.version != 4 || sig.sigType != 0x01 || sig.hashName() != "SHA-256" || sig.weakHash() {
+		t.Errorf("signature = %+v", sig)
+	}
+	if sig.fingerprint != "EB9E20E18161F185E138680BC7A8E9DF609D912C" || sig.keyID != "C7A8E9DF609D912C" {
+		t.Errorf("issuer = %s, %s", sig.fingerprint, sig.keyID)
+	}
+	if sig.checksum != "valid" || sig.created.IsZero() {
+		t.Errorf("checksum = %s, created = %v", sig.checksum, sig.created)
+	}
+	described := sig.describe()
+	if described["Key Algorithm"] != "EdDSA (legacy)" || described["Signature Type"] != "text" {
+		t.Errorf("describe = %v", described)
+	}
+
+	corrupted := strings.Replace(string(armored), "=Oehm", "=AAAA", 1)
+	if sig, err := parsePGPSignature([]byte(corrupted)); err != nil || sig.checksum != "invalid" {
+		t.Errorf("corrupted checksum = %v, %v", sig, err)
+	}
+	stripped := strings.Replace(string(armored), "=Oehm\n", "", 1)
+	if sig, err := parsePGPSignature([]byte(stripped)); err != nil || sig.checksum != "absent" {
+		t.Errorf("missing checksum = %v, %v", sig, err)
+	}
+}
+
+func TestParsePGPSignatureVersion3(t *testing.T) {
+	// Old-format header, tag 2, followed by a v3 body signed with SHA-1
+	body := []byte{3, 5, 0x01, 0x65, 0x53, 0xf1, 0x00, 1, 2, 3, 4, 5, 6, 7, 8, 1, 2, 0xab, 0xcd}
+	packet := append([]byte{0x88, byte(len(body))}, body...)
+
+	sig, err := parsePGPSignature(armorSignature(packet))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sig.version != 3 || sig.keyID != "0102030405060708" || !sig.weakHash() || sig.checksum != "absent" {
+		t.Errorf("signature = %+v", sig)
+	}
+	if want := time.Unix(1700000000, 0).UTC(); !sig.created.Equal(want) {
+		t.Errorf("created = %v, want %v", sig.created, want)
+	}
+}
+
+func TestParsePGPSignatureErrors(t *testing.T) {
+	literal := []byte{0xcb, 3, 'b', 0, 0}
+	tests := []struct {
+		name    string
+		armored []byte
+	}{
+		{"missing block", nil},
+		{"no END line", []byte("-----BEGIN PGP SIGNATURE-----\n\niHUE\n")},
+		{"bad base64", []byte("-----BEGIN PGP SIGNATURE-----\n\n!!!!\n-----END PGP SIGNATURE-----")},
+		{"no signature packet", armorSignature(literal)},
+		{"unsupported version", armorSignature([]byte{0xc2, 1, 7})},
+		{"truncated packet", armorSignature([]byte{0xc2, 40, 4, 1})},
+		{"truncated subpackets", armorSignature([]byte{0xc2, 6, 4, 1, 22, 8, 0, 9})},
+	}
+	for _, tt := range tests {
+		if _, err := parsePGPSignature(tt.armored); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,333 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// sessionCookieMaxAge is the lifetime above which a session cookie is flagged
+	sessionCookieMaxAge = 30 * 24 * time.Hour
+	// cookieMaxAge is the lifetime above which any cookie is flagged
+	cookieMaxAge = 365 * 24 * time.Hour
+)
+
+// sessionCookieNames maps well-known session cookie names to the technology that
+// sets them. Names are matched case-insensitively.
+var sessionCookieNames = map[string]string{
+	"phpsessid":         "PHP",
+	"jsessionid":        "Java Servlet",
+	"connect.sid":       "Node.js (Express)",
+	"asp.net_sessionid": "ASP.NET",
+	"aspsessionid":      "Classic ASP",
+	"laravel_session":   "Laravel",
+	"ci_session":        "CodeIgniter",
+	"sessionid":         "Django",
+	"_session_id":       "Ruby on Rails",
+	"rack.session":      "Ruby (Rack)",
+	"cfid":              "ColdFusion",
+	"cftoken":           "ColdFusion",
+	"symfony":           "Symfony",
+	"frontend":          "Magento",
+	"koa.sess":          "Node.js (Koa)",
+	"express.sid":       "Node.js (Express)",
+}
+
+// sessionNameHints mark a cookie as session-bearing when its name is not a known one
+// and one of its _, - or . separated segments equals a hint
+var sessionNameHints = map[string]bool{
+	"sess": true, "session": true, "sessid": true, "sid": true, "auth": true,
+	"token": true, "login": true, "jwt": true,
+}
+
+// cookieIssue is one kind of weakness; cookies sharing it are reported together
+type cookieIssue struct {
+	severity       string
+	title          string
+	detail         string
+	recommendation string
+}
+
+// setCookie is a cookie together with the response that set it
+type setCookie struct {
+	cookie *http.Cookie
+	url    *neturl.URL
+}
+
+func init() {
+	RegisterCheck("cookie_security", "Audits Set-Cookie attributes across the redirect chain and homepage", checkCookiesPlugin)
+}
+
+func checkCookiesPlugin(ctx context.Context, url string) interface{} {
+	c := redirectChain(ctx, url)
+	if len(c.Hops) == 0 {
+		return map[string]string{"error": "Failed to reach host"}
+	}
+
+	cookies := chainCookies(c)
+	result := map[string]interface{}{}
+	summary := make(map[string]string)
+	var sessions []string
+
+	issues := make(map[cookieIssue][]string)
+	var order []cookieIssue
+	flag := func(issue cookieIssue, name string) {
+		if _, ok := issues[issue]; !ok {
+			order = append(order, issue)
+		}
+		issues[issue] = append(issues[issue], name)
+	}
+
+	for _, sc := range cookies {
+		ck := sc.cookie
+		tech, session := classifyCookie(ck.Name)
+		if session {
+			label := ck.Name
+			if tech != "" {
+				label += " (" + tech + ")"
+			}
+			sessions = append(sessions, label)
+		}
+		summary[ck.Name] = describeCookie(sc)
+
+		overHTTPS := sc.url.Scheme == "https"
+		lifetime := cookieLifetime(ck)
+
+		if !ck.Secure {
+			switch {
+			case session && !overHTTPS:
+				flag(cookieIssue{SeverityHigh, "Session cookie set over plain HTTP",
+					"The session identifier is sent in cleartext and can be sniffed or injected.",
+					"Set session cookies only over HTTPS, with the Secure attribute."}, ck.Name)
+			case session:
+				flag(cookieIssue{SeverityMedium, "Session cookie without Secure",
+					"The cookie is also sent over plain HTTP requests to the site.",
+					"Add Secure, e.g. Set-Cookie: <name>=<value>; Secure; HttpOnly; SameSite=Lax"}, ck.Name)
+			default:
+				flag(cookieIssue{SeverityLow, "Cookie without Secure",
+					"The cookie is also sent over plain HTTP requests to the site.",
+					"Add the Secure attribute."}, ck.Name)
+			}
+		}
+
+		if !ck.HttpOnly {
+			if session {
+				flag(cookieIssue{SeverityMedium, "Session cookie without HttpOnly",
+					"Scripts can read the session identifier, so any XSS can steal the session.",
+					"Add the HttpOnly attribute."}, ck.Name)
+			} else {
+				flag(cookieIssue{SeverityInfo, "Cookie readable by scripts (no HttpOnly)",
+					"Fine for cookies the frontend reads on purpose; otherwise add HttpOnly.",
+					"Add HttpOnly unless client-side code needs the cookie."}, ck.Name)
+			}
+		}
+
+		switch ck.SameSite {
+		case 0, http.SameSiteDefaultMode:
+			flag(cookieIssue{SeverityLow, "Cookie without SameSite",
+				"Browsers apply their own default (Lax in Chromium, none in older browsers), so cross-site requests may carry the cookie.",
+				"Add SameSite=Lax (or Strict)."}, ck.Name)
+		case http.SameSiteNoneMode:
+			if !ck.Secure {
+				flag(cookieIssue{SeverityMedium, "SameSite=None without Secure",
+					"Browsers reject SameSite=None cookies that are not Secure.",
+					"Add Secure, or use SameSite=Lax."}, ck.Name)
+			} else if session {
+				flag(cookieIssue{SeverityLow, "Session cookie sent on cross-site requests",
+					"SameSite=None sends the session with requests from any site (CSRF exposure).",
+					"Use SameSite=Lax unless the session must work in cross-site frames."}, ck.Name)
+			}
+		}
+
+		if ck.Domain != "" {
+			cookieDomain := strings.TrimPrefix(strings.ToLower(ck.Domain), ".")
+			host := strings.ToLower(sc.url.Hostname())
+			if cookieDomain != host && (cookieDomain == registrableDomain(host) || strings.HasSuffix(host, "."+cookieDomain)) {
+				flag(cookieIssue{SeverityLow, "Cookie shared with all subdomains",
+					"The Domain attribute covers every subdomain, so any of them (including compromised or user-content ones) receives and can overwrite it.",
+					"Drop the Domain attribute to keep the cookie host-only, or use the __Host- prefix."}, ck.Name)
+			}
+		}
+
+		if session && ck.Path == "/" && strings.Trim(sc.url.Path, "/") != "" {
+			flag(cookieIssue{SeverityInfo, "Session cookie scoped to the whole site",
+				"The cookie was set from a sub-path but Path=/ sends it to every application on the host.",
+				"Set Path to the application's path if other apps share the host."}, ck.Name)
+		}
+
+		switch {
+		case session && lifetime > sessionCookieMaxAge:
+			flag(cookieIssue{SeverityLow, "Long-lived session cookie",
+				"Session cookies lasting more than 30 days keep stolen or shared sessions usable for a long time.",
+				"Shorten the lifetime or use a browser-session cookie with server-side expiry."}, ck.Name)
+		case lifetime > cookieMaxAge:
+			flag(cookieIssue{SeverityInfo, "Cookie lifetime over a year",
+				"Browsers cap cookie lifetimes at 400 days; long-lived identifiers raise privacy concerns.",
+				"Limit the lifetime to what the feature needs."}, ck.Name)
+		}
+
+		if violation := prefixViolation(sc); violation != "" {
+			flag(cookieIssue{SeverityMedium, "Cookie prefix requirements not met",
+				"Browsers reject cookies whose __Secure-/__Host- prefix rules are broken: " + violation + ".",
+				"__Secure- needs Secure over HTTPS; __Host- additionally needs Path=/ and no Domain."}, ck.Name)
+		}
+	}
+
+	sort.Strings(sessions)
+	result["Cookies"] = summary
+	result["Session Cookies"] = sessions
+
+	var findings []Finding
+	for _, issue := range order {
+		names := issues[issue]
+		findings = append(findings, Finding{
+			Severity:       issue.severity,
+			Title:          issue.title,
+			Detail:         fmt.Sprintf("%s Cookies: %s.", issue.detail, strings.Join(names, ", ")),
+			Recommendation: issue.recommendation,
+		})
+	}
+	if len(findings) > 0 {
+		sortFindings(findings)
+		result["findings"] = findings
+	}
+	return result
+}
+
+// chainCookies returns the cookies set by every response in the chain. A cookie set
+// again later in the chain replaces the earlier one.
+func chainCookies(c *chain) []setCookie {
+	var cookies []setCookie
+	index := make(map[string]int)
+	for _, h := range c.Hops {
+		u, err := neturl.Parse(h.URL)
+		if err != nil {
+			continue
+		}
+		for _, ck := range (&http.Response{Header: h.Header}).Cookies() {
+			if i, ok := index[ck.Name]; ok {
+				cookies[i] = setCookie{ck, u}
+				continue
+			}
+			index[ck.Name] = len(cookies)
+			cookies = append(cookies, setCookie{ck, u})
+		}
+	}
+	return cookies
+}
+
+// classifyCookie reports whether name looks like a session cookie and, for well-known
+// names, which technology sets it
+func classifyCookie(name string) (string, bool) {
+	lower := strings.ToLower(name)
+	if tech, ok := sessionCookieNames[lower]; ok {
+		return tech, true
+	}
+	switch {
+	case strings.HasPrefix(lower, "aspsessionid"):
+		return "Classic ASP", true
+	case strings.HasPrefix(lower, "sess") && len(lower) > 30:
+		// Drupal uses SESS/SSESS followed by a hash
+		return "Drupal", true
+	case strings.HasPrefix(lower, "ssess") && len(lower) > 30:
+		return "Drupal", true
+	case strings.HasPrefix(lower, "wordpress_logged_in") || strings.HasPrefix(lower, "wordpress_sec"):
+		return "WordPress", true
+	case strings.HasPrefix(lower, "_") && strings.HasSuffix(lower, "_session"):
+		return "Ruby on Rails", true
+	}
+	segments := strings.FieldsFunc(lower, func(r rune) bool {
+		return r == '_' || r == '-' || r == '.'
+	})
+	// CSRF tokens (XSRF-TOKEN, csrftoken, _csrf) must stay readable by scripts
+	for _, segment := range segments {
+		if strings.Contains(segment, "csrf") || strings.Contains(segment, "xsrf") {
+			return "", false
+		}
+	}
+	for _, segment := range segments {
+		if sessionNameHints[segment] {
+			return "", true
+		}
+	}
+	return "", false
+}
+
+// sessionCookieTech lists the technologies revealed by session cookie names in the
+// chain, keyed by cookie name
+func sessionCookieTech(c *chain) map[string]string {
+	techs := make(map[string]string)
+	for _, sc := range chainCookies(c) {
+		if tech, _ := classifyCookie(sc.cookie.Name); tech != "" {
+			techs[sc.cookie.Name] = tech
+		}
+	}
+	return techs
+}
+
+// cookieLifetime is how long the cookie persists; zero for browser-session cookies
+func cookieLifetime(ck *http.Cookie) time.Duration {
+	switch {
+	case ck.MaxAge > 0:
+		return time.Duration(ck.MaxAge) * time.Second
+	case ck.MaxAge < 0:
+		return 0
+	case !ck.Expires.IsZero():
+		return time.Until(ck.Expires)
+	}
+	return 0
+}
+
+func prefixViolation(sc setCookie) string {
+	ck := sc.cookie
+	secure := ck.Secure && sc.url.Scheme == "https"
+	switch {
+	case strings.HasPrefix(ck.Name, "__Secure-") && !secure:
+		return "__Secure- cookie without Secure over HTTPS"
+	case strings.HasPrefix(ck.Name, "__Host-") && !secure:
+		return "__Host- cookie without Secure over HTTPS"
+	case strings.HasPrefix(ck.Name, "__Host-") && ck.Domain != "":
+		return "__Host- cookie with a Domain attribute"
+	case strings.HasPrefix(ck.Name, "__Host-") && ck.Path != "/":
+		return "__Host- cookie without Path=/"
+	}
+	return ""
+}
+
+// describeCookie summarises a cookie's attributes on one line
+func describeCookie(sc setCookie) string {
+	ck := sc.cookie
+	var attrs []string
+	if ck.Secure {
+		attrs = append(attrs, "Secure")
+	}
+	if ck.HttpOnly {
+		attrs = append(attrs, "HttpOnly")
+	}
+	switch ck.SameSite {
+	case http.SameSiteLaxMode:
+		attrs = append(attrs, "SameSite=Lax")
+	case http.SameSiteStrictMode:
+		attrs = append(attrs, "SameSite=Strict")
+	case http.SameSiteNoneMode:
+		attrs = append(attrs, "SameSite=None")
+	}
+	if ck.Domain != "" {
+		attrs = append(attrs, "Domain="+ck.Domain)
+	}
+	if ck.Path != "" {
+		attrs = append(attrs, "Path="+ck.Path)
+	}
+	// Only the kind of lifetime: an Expires countdown would show up as a change on every rescan
+	if cookieLifetime(ck) > 0 {
+		attrs = append(attrs, "persistent")
+	} else {
+		attrs = append(attrs, "session")
+	}
+	return strings.Join(attrs, "; ") + " (set by " + sc.url.String() + ")"
+}
@@ -0,0 +1,31 @@
+package scanner
+
+import "testing"
+
+func TestClassifyCookie(t *testing.T) {
+	tests := []struct {
+		name    string
+		tech    string
+		session bool
+	}{
+		{"PHPSESSID", "PHP", true},
+		{"laravel_session", "Laravel", true},
+		{"_myapp_session", "Ruby on Rails", true},
+		{"auth_token", "", true},
+		{"my.sid", "", true},
+		{"user-session", "", true},
+		{"XSRF-TOKEN", "", false},
+		{"csrftoken", "", false},
+		{"_csrf", "", false},
+		{"oauth_state", "", false},
+		{"sidebar", "", false},
+		{"tokenized", "", false},
+		{"_ga", "", false},
+	}
+	for _, tt := range tests {
+		tech, session := classifyCookie(tt.name)
+		if tech != tt.tech || session != tt.session {
+			t.Errorf("classifyCookie(%q) = %q, %t, want %q, %t", tt.name, tech, session, tt.tech, tt.session)
+		}
+	}
+}
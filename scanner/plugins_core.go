@@ -2,28 +2,6 @@ package scanner
 
 import (
 	"context"
-	"crypto/tls"
-	"io"
-	"net/http"
-	"strings"
-	"sync"
-)
-
-var (
-	pluginClient = &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
-			DisableKeepAlives: true,
-		},
-		// Timeouts are handled by the Context passed to each plugin
-	}
-
-	sensitivePaths = []string{
-		"/.env",
-		"/.git/config",
-		"/docker-compose.yml",
-		"/backup.sql",
-	}
 )
 
 func init() {
@@ -33,70 +11,24 @@ func init() {
 
 func checkHeadersPlugin(ctx context.Context, url string) interface{} {
 	var missing []string
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return missing
-	}
-	req.Header.Set("User-Agent", "URLHawkScanner-Scanner/1.0")
-
-	resp, err := pluginClient.Do(req)
-	if err != nil {
+	p := fetchPage(ctx, url)
+	if p.Err != nil {
 		return append(missing, "Host Unreachable")
 	}
-	defer resp.Body.Close()
 
-	if resp.Header.Get("X-Frame-Options") == "" {
-		missing = append(missing, "X-Frame-Options")
-	}
-	if resp.Header.Get("Content-Security-Policy") == "" {
-		missing = append(missing, "Content-Security-Policy")
-	}
-	if resp.Header.Get("Strict-Transport-Security") == "" {
-		missing = append(missing, "Strict-Transport-Security")
+	for _, header := range []string{"X-Frame-Options", "Content-Security-Policy", "Strict-Transport-Security"} {
+		if p.Header.Get(header) == "" {
+			missing = append(missing, header)
+		}
 	}
 	return missing
 }
 
-func checkSensitiveFilesPlugin(ctx context.Context, baseURL string) interface{} {
-	var exposed []string
-	var wg sync.WaitGroup
-	var mu sync.Mutex
-
-	for _, path := range sensitivePaths {
-		wg.Add(1)
-		go func(p string) {
-			defer wg.Done()
-			target := baseURL + p
-			req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
-			if err != nil {
-				return
-			}
-			req.Header.Set("User-Agent", "URLHawkScanner-Scanner/1.0")
-
-			resp, err := pluginClient.Do(req)
-			if err != nil {
-				return
-			}
-			defer resp.Body.Close()
-
-			if resp.StatusCode == 200 {
-				body, err := io.ReadAll(io.LimitReader(resp.Body, 512))
-				if err != nil {
-					return
-				}
-
-				content := string(body)
-				isFalsePositive := strings.Contains(strings.ToLower(content), "<html") || strings.Contains(strings.ToLower(content), "<body")
-
-				if !isFalsePositive {
-					mu.Lock()
-					exposed = append(exposed, p)
-					mu.Unlock()
-				}
-			}
-		}(path)
-	}
-
-	wg.Wait()
-	return exposed
+// checkSensitiveFilesPlugin probes the paths in data/sensitive_paths.txt (or the list
+// set with SetSensitivePaths). A file only counts when its content matches the
+// path's signature and does not look like the server's catch-all page.
+func checkSensitiveFilesPlugin(ctx context.Context, url string) interface{} {
+	baseURL := landingBase(ctx, url)
+	host := extractDomain(baseURL)
+	return probeSensitivePaths(ctx, baseURL, host)
 }
@@ -0,0 +1,263 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"strings"
+)
+
+// corsAttacker is the domain used for crafted origins; it is never contacted
+const corsAttacker = "urlhawk-attacker.example"
+
+// dangerousMethods are flagged when a preflight allows them for an untrusted origin
+var dangerousMethods = []string{"PUT", "DELETE", "PATCH"}
+
+// corsProbe is one crafted Origin and what it means if the server trusts it
+type corsProbe struct {
+	label  string
+	origin string
+	// severity of trusting the origin with credentials; without credentials the
+	// impact is lower and is graded separately
+	severity string
+	title    string
+}
+
+type corsResponse struct {
+	allowOrigin      string
+	allowCredentials bool
+	err              error
+}
+
+func init() {
+	RegisterCheck("cors_policy", "Tests CORS with crafted Origin headers and preflight requests for reflected or permissive policies", checkCORSPlugin)
+}
+
+// corsProbes builds the origins to test for a target host
+func corsProbes(scheme, host string) []corsProbe {
+	probes := []corsProbe{
+		{"Arbitrary origin", "https://" + corsAttacker, SeverityHigh, "Arbitrary origins are trusted"},
+		{"null origin", "null", SeverityHigh, "The null origin is trusted"},
+		{"Prefix match", "https://" + host + "." + corsAttacker, SeverityHigh, "Origin check only matches a prefix"},
+		{"Suffix match", "https://urlhawk" + host, SeverityHigh, "Origin check only matches a suffix"},
+		{"Subdomain", "https://urlhawk-attacker." + host, SeverityLow, "All subdomains are trusted"},
+	}
+	// An unescaped dot in a regex lets wwwXexample.com match www.example.com
+	if i := strings.Index(host, "."); i > 0 && strings.Count(host, ".") > 1 {
+		probes = append(probes, corsProbe{"Unescaped dot", "https://" + host[:i] + "x" + host[i+1:], SeverityHigh, "Origin regex does not escape dots"})
+	}
+	if scheme == "https" {
+		probes = append(probes, corsProbe{"Plain HTTP origin", "http://" + host, SeverityMedium, "Insecure http:// origin is trusted"})
+	}
+	return probes
+}
+
+func checkCORSPlugin(ctx context.Context, url string) interface{} {
+	u, err := neturl.Parse(url)
+	if err != nil || u.Hostname() == "" {
+		return map[string]string{"error": "Invalid URL"}
+	}
+	host := u.Hostname()
+
+	probes := corsProbes(u.Scheme, host)
+	responses := make([]corsResponse, len(probes))
+	probeEach(len(probes), func(i int) {
+		responses[i] = corsRequest(ctx, url, probes[i].origin)
+	})
+
+	// When any origin is reflected, the prefix/suffix/subdomain variants say nothing new
+	reflectsAny := responses[0].err == nil && responses[0].allowOrigin == probes[0].origin
+
+	origins := make(map[string]string)
+	var findings []Finding
+	reachable := false
+	var trusted *corsProbe
+	for i, probe := range probes {
+		resp := responses[i]
+		if resp.err != nil {
+			origins[probe.label] = "error"
+			continue
+		}
+		reachable = true
+		report := !reflectsAny || i == 0 || probe.origin == "null"
+
+		switch {
+		case resp.allowOrigin == probe.origin && resp.allowCredentials:
+			origins[probe.label] = "reflected with credentials"
+			if !report {
+				break
+			}
+			findings = append(findings, Finding{
+				Severity:       probe.severity,
+				Title:          probe.title + " with credentials",
+				Detail:         fmt.Sprintf("Origin: %s is echoed in Access-Control-Allow-Origin together with Access-Control-Allow-Credentials: true, so that origin can read authenticated responses.", probe.origin),
+				Recommendation: "Compare the Origin against an exact allowlist of trusted origins before reflecting it.",
+			})
+		case resp.allowOrigin == probe.origin:
+			origins[probe.label] = "reflected"
+			if !report {
+				break
+			}
+			severity := SeverityLow
+			if probe.severity == SeverityLow {
+				severity = SeverityInfo
+			}
+			findings = append(findings, Finding{
+				Severity:       severity,
+				Title:          probe.title,
+				Detail:         fmt.Sprintf("Origin: %s is echoed in Access-Control-Allow-Origin (without credentials), so it can read unauthenticated responses, including content only reachable from the victim's network.", probe.origin),
+				Recommendation: "Only reflect origins from an exact allowlist.",
+			})
+		case resp.allowOrigin == "*":
+			origins[probe.label] = "wildcard"
+		case resp.allowOrigin == "":
+			origins[probe.label] = "not allowed"
+		default:
+			origins[probe.label] = "allows " + resp.allowOrigin
+		}
+		if resp.allowOrigin == probe.origin && trusted == nil && probe.severity != SeverityLow {
+			trusted = &probes[i]
+		}
+	}
+	if !reachable {
+		return map[string]string{"error": "Failed to reach host"}
+	}
+
+	result := map[string]interface{}{"Origins": origins}
+
+	// The wildcard is read from the arbitrary-origin probe; browsers refuse to combine it
+	// with credentials, but it shows a policy written without much thought
+	if first := responses[0]; first.err == nil && first.allowOrigin == "*" {
+		if first.allowCredentials {
+			findings = append(findings, Finding{
+				Severity:       SeverityLow,
+				Title:          "Wildcard origin combined with credentials",
+				Detail:         "Browsers ignore Access-Control-Allow-Credentials with Access-Control-Allow-Origin: *, so credentialed requests fail; a fix that reflects the origin instead would be exploitable.",
+				Recommendation: "List the exact origins that need credentialed access.",
+			})
+		} else {
+			findings = append(findings, Finding{
+				Severity: SeverityInfo,
+				Title:    "Any origin can read responses (Access-Control-Allow-Origin: *)",
+				Detail:   "Fine for public resources; make sure nothing on this host depends on network position for access control.",
+			})
+		}
+	}
+
+	// Preflight as an attacker-controlled origin, or as the origin that was already
+	// trusted, to see which methods and headers the policy opens up
+	preflightOrigin := probes[0].origin
+	if trusted != nil {
+		preflightOrigin = trusted.origin
+	}
+	if preflight, ok := corsPreflight(ctx, url, preflightOrigin); ok {
+		result["Preflight"] = preflight
+		originAllowed := preflight["Allow-Origin"] == preflightOrigin || preflight["Allow-Origin"] == "*"
+		if originAllowed {
+			findings = append(findings, preflightFindings(preflight, preflightOrigin)...)
+		}
+	}
+
+	if len(findings) > 0 {
+		sortFindings(findings)
+		result["findings"] = findings
+	}
+	return result
+}
+
+func corsRequest(ctx context.Context, url, origin string) corsResponse {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return corsResponse{err: err}
+	}
+	req.Header.Set("User-Agent", "URLHawkScanner-Scanner/1.0")
+	req.Header.Set("Origin", origin)
+
+	resp, err := pluginClient.Do(req)
+	if err != nil {
+		return corsResponse{err: err}
+	}
+	resp.Body.Close()
+	return corsResponse{
+		allowOrigin:      strings.TrimSpace(resp.Header.Get("Access-Control-Allow-Origin")),
+		allowCredentials: strings.EqualFold(strings.TrimSpace(resp.Header.Get("Access-Control-Allow-Credentials")), "true"),
+	}
+}
+
+// corsPreflight sends an OPTIONS preflight asking for a state-changing method and
+// custom headers and returns the Access-Control-* answer
+func corsPreflight(ctx context.Context, url, origin string) (map[string]string, bool) {
+	req, err := http.NewRequestWithContext(ctx, "OPTIONS", url, nil)
+	if err != nil {
+		return nil, false
+	}
+	req.Header.Set("User-Agent", "URLHawkScanner-Scanner/1.0")
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", "DELETE")
+	req.Header.Set("Access-Control-Request-Headers", "authorization, x-urlhawk-probe")
+
+	resp, err := pluginClient.Do(req)
+	if err != nil {
+		return nil, false
+	}
+	resp.Body.Close()
+
+	preflight := map[string]string{
+		"Status":        fmt.Sprintf("%d", resp.StatusCode),
+		"Origin":        origin,
+		"Allow-Origin":  strings.TrimSpace(resp.Header.Get("Access-Control-Allow-Origin")),
+		"Allow-Methods": strings.TrimSpace(resp.Header.Get("Access-Control-Allow-Methods")),
+		"Allow-Headers": strings.TrimSpace(resp.Header.Get("Access-Control-Allow-Headers")),
+	}
+	if maxAge := resp.Header.Get("Access-Control-Max-Age"); maxAge != "" {
+		preflight["Max-Age"] = maxAge
+	}
+	return preflight, true
+}
+
+func preflightFindings(preflight map[string]string, origin string) []Finding {
+	var findings []Finding
+
+	methods := strings.ToUpper(preflight["Allow-Methods"])
+	var allowed []string
+	if strings.Contains(methods, "*") {
+		allowed = []string{"*"}
+	} else {
+		for _, m := range strings.Split(methods, ",") {
+			m = strings.TrimSpace(m)
+			for _, dangerous := range dangerousMethods {
+				if m == dangerous {
+					allowed = append(allowed, m)
+				}
+			}
+		}
+	}
+	if len(allowed) > 0 {
+		findings = append(findings, Finding{
+			Severity:       SeverityMedium,
+			Title:          "Preflight allows state-changing methods for an untrusted origin",
+			Detail:         fmt.Sprintf("Origin %s may send %s requests.", origin, strings.Join(allowed, ", ")),
+			Recommendation: "Only allow the methods cross-origin clients need, and only for trusted origins.",
+		})
+	}
+
+	headers := strings.ToLower(preflight["Allow-Headers"])
+	switch {
+	case strings.Contains(headers, "*"):
+		findings = append(findings, Finding{
+			Severity:       SeverityLow,
+			Title:          "Preflight allows any request header",
+			Detail:         "Access-Control-Allow-Headers: * lets the origin send arbitrary headers.",
+			Recommendation: "List the headers clients need explicitly.",
+		})
+	case strings.Contains(headers, "x-urlhawk-probe"):
+		findings = append(findings, Finding{
+			Severity:       SeverityLow,
+			Title:          "Preflight reflects requested headers",
+			Detail:         "Access-Control-Allow-Headers echoes whatever headers the client asks for, including Authorization.",
+			Recommendation: "List the headers clients need explicitly.",
+		})
+	}
+	return findings
+}
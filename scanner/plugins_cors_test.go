@@ -0,0 +1,160 @@
+package scanner
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCORSProbes(t *testing.T) {
+	origins := func(probes []corsProbe) []string {
+		var list []string
+		for _, p := range probes {
+			list = append(list, p.origin)
+		}
+		return list
+	}
+
+	got := origins(corsProbes("https", "www.example.com"))
+	want := []string{
+		"https://" + corsAttacker,
+		"null",
+		"https://www.example.com." + corsAttacker,
+		"https://urlhawkwww.example.com",
+		"https://urlhawk-attacker.www.example.com",
+		"https://wwwxexample.com",
+		"http://www.example.com",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("probes for https://www.example.com = %q, want %q", got, want)
+	}
+
+	// No dot to leave unescaped in a bare domain, and no downgrade from http
+	got = origins(corsProbes("http", "example.com"))
+	if len(got) != 5 || slices.Contains(got, "http://example.com") || slices.Contains(got, "https://examplexcom") {
+		t.Errorf("probes for http://example.com = %q", got)
+	}
+}
+
+// corsPolicy is how a test server answers a CORS request
+type corsPolicy struct {
+	// allow returns the Access-Control-Allow-Origin value for origin on host
+	allow       func(origin, host string) string
+	credentials bool
+	methods     string
+	// headers is sent as Access-Control-Allow-Headers; "reflect" echoes the request
+	headers string
+}
+
+func corsServer(t *testing.T, policy corsPolicy) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.Host)
+		if allowed := policy.allow(r.Header.Get("Origin"), host); allowed != "" {
+			w.Header().Set("Access-Control-Allow-Origin", allowed)
+			if policy.credentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+		}
+		if r.Method == "OPTIONS" {
+			if policy.methods != "" {
+				w.Header().Set("Access-Control-Allow-Methods", policy.methods)
+			}
+			switch policy.headers {
+			case "":
+			case "reflect":
+				w.Header().Set("Access-Control-Allow-Headers", r.Header.Get("Access-Control-Request-Headers"))
+			default:
+				w.Header().Set("Access-Control-Allow-Headers", policy.headers)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func reflectIf(match func(origin, host string) bool) func(origin, host string) string {
+	return func(origin, host string) string {
+		if match(origin, host) {
+			return origin
+		}
+		return ""
+	}
+}
+
+func TestCORSPolicyFindings(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy corsPolicy
+		want   []string
+	}{
+		{"exact allowlist", corsPolicy{
+			allow: reflectIf(func(origin, host string) bool { return origin == "https://app."+host }),
+		}, nil},
+		// Reflecting everything is reported once, not once per crafted origin
+		{"reflects any origin", corsPolicy{
+			allow:       reflectIf(func(origin, host string) bool { return true }),
+			credentials: true,
+			methods:     "GET, POST, DELETE",
+			headers:     "reflect",
+		}, []string{
+			"Arbitrary origins are trusted with credentials",
+			"The null origin is trusted with credentials",
+			"Preflight allows state-changing methods for an untrusted origin",
+			"Preflight reflects requested headers",
+		}},
+		{"suffix match", corsPolicy{
+			allow: reflectIf(func(origin, host string) bool { return strings.HasSuffix(origin, host) }),
+		}, []string{"Origin check only matches a suffix", "All subdomains are trusted"}},
+		{"prefix match", corsPolicy{
+			allow:       reflectIf(func(origin, host string) bool { return strings.HasPrefix(origin, "https://"+host) }),
+			credentials: true,
+			methods:     "*",
+		}, []string{"Origin check only matches a prefix with credentials", "Preflight allows state-changing methods for an untrusted origin"}},
+		{"unescaped dot", corsPolicy{
+			allow: reflectIf(func(origin, host string) bool {
+				return regexp.MustCompile("^https://" + host + "$").MatchString(origin)
+			}),
+			credentials: true,
+		}, []string{"Origin regex does not escape dots with credentials"}},
+		{"wildcard", corsPolicy{
+			allow:   func(origin, host string) string { return "*" },
+			methods: "GET",
+			headers: "*",
+		}, []string{"Any origin can read responses (Access-Control-Allow-Origin: *)", "Preflight allows any request header"}},
+		{"wildcard with credentials", corsPolicy{
+			allow:       func(origin, host string) string { return "*" },
+			credentials: true,
+		}, []string{"Wildcard origin combined with credentials"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, ok := checkCORSPlugin(context.Background(), corsServer(t, tt.policy)).(map[string]interface{})
+			if !ok {
+				t.Fatalf("checkCORSPlugin = %v", result)
+			}
+			findings, _ := result["findings"].([]Finding)
+			got := findingTitles(findings)
+			slices.Sort(got)
+			want := slices.Clone(tt.want)
+			slices.Sort(want)
+			if !slices.Equal(got, want) {
+				t.Errorf("findings = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestCORSUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	if result, ok := checkCORSPlugin(context.Background(), srv.URL).(map[string]string); !ok || result["error"] == "" {
+		t.Errorf("checkCORSPlugin on a closed server = %v", result)
+	}
+}
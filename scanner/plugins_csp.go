@@ -0,0 +1,377 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// jsonpHosts are commonly allowlisted script hosts that serve JSONP endpoints or
+// arbitrary libraries (e.g. old AngularJS), either of which bypasses a host allowlist
+var jsonpHosts = []string{
+	"www.google.com",
+	"accounts.google.com",
+	"ajax.googleapis.com",
+	"www.googleapis.com",
+	"*.googleapis.com",
+	"www.google-analytics.com",
+	"*.google-analytics.com",
+	"www.youtube.com",
+	"*.youtube.com",
+	"graph.facebook.com",
+	"connect.facebook.net",
+	"api.twitter.com",
+	"*.yandex.ru",
+	"cdnjs.cloudflare.com",
+	"cdn.jsdelivr.net",
+	"unpkg.com",
+	"raw.githubusercontent.com",
+	"*.herokuapp.com",
+	"*.appspot.com",
+}
+
+// metaIgnored are directives browsers ignore in <meta> policies
+var metaIgnored = []string{"frame-ancestors", "report-uri", "sandbox"}
+
+// cspPolicy is one policy with the place it came from
+type cspPolicy struct {
+	source     string
+	raw        string
+	directives map[string][]string
+}
+
+func init() {
+	RegisterCheck("content_security", "Evaluates Content-Security-Policy headers and meta tags for bypassable or missing directives", checkCSPPlugin)
+}
+
+func checkCSPPlugin(ctx context.Context, url string) interface{} {
+	p := fetchPage(ctx, url)
+	if p.Err != nil {
+		return map[string]string{"error": "Failed to reach host"}
+	}
+
+	var enforced, reportOnly []cspPolicy
+	for _, policy := range splitPolicies(p.Header.Values("Content-Security-Policy")) {
+		enforced = append(enforced, cspPolicy{source: "header", raw: policy, directives: parseCSP(policy)})
+	}
+	for _, policy := range metaPolicies(p.Body) {
+		directives := parseCSP(policy)
+		for _, name := range metaIgnored {
+			delete(directives, name)
+		}
+		enforced = append(enforced, cspPolicy{source: "meta", raw: policy, directives: directives})
+	}
+	for _, policy := range splitPolicies(p.Header.Values("Content-Security-Policy-Report-Only")) {
+		reportOnly = append(reportOnly, cspPolicy{source: "report-only", raw: policy, directives: parseCSP(policy)})
+	}
+
+	var listed []string
+	for _, policy := range append(append([]cspPolicy{}, enforced...), reportOnly...) {
+		listed = append(listed, policy.source+": "+policy.raw)
+	}
+
+	result := map[string]interface{}{"Policies": listed}
+	var findings []Finding
+
+	switch {
+	case len(enforced) > 0:
+		result["Mode"] = "enforced"
+		findings = commonWeaknesses(enforced)
+	case len(reportOnly) > 0:
+		result["Mode"] = "report-only"
+		findings = append(findings, Finding{
+			Severity:       SeverityMedium,
+			Title:          "Content-Security-Policy is report-only",
+			Detail:         "The policy is only reported, not enforced; the weaknesses below describe it as if it were enforced.",
+			Recommendation: "Send the policy as Content-Security-Policy once violation reports are clean.",
+		})
+		for _, f := range commonWeaknesses(reportOnly) {
+			f.Severity = SeverityInfo
+			f.Title = "(report-only) " + f.Title
+			findings = append(findings, f)
+		}
+	default:
+		result["Mode"] = "none"
+		findings = append(findings, Finding{
+			Severity:       SeverityMedium,
+			Title:          "No Content-Security-Policy",
+			Detail:         "Neither a header nor a <meta> tag sets a policy.",
+			Recommendation: "Content-Security-Policy: script-src 'nonce-{random}' 'strict-dynamic'; object-src 'none'; base-uri 'none'",
+		})
+	}
+
+	if len(enforced) > 0 || len(reportOnly) > 0 {
+		policies := enforced
+		if len(policies) == 0 {
+			policies = reportOnly
+		}
+		var nonces, hashes, strictDynamic bool
+		for _, policy := range policies {
+			sources, _ := effectiveSources(policy.directives, "script-src")
+			for _, src := range sources {
+				src = strings.ToLower(src)
+				nonces = nonces || strings.HasPrefix(src, "'nonce-")
+				hashes = hashes || isHashSource(src)
+				strictDynamic = strictDynamic || src == "'strict-dynamic'"
+			}
+		}
+		result["Nonces"] = fmt.Sprintf("%t", nonces)
+		result["Hashes"] = fmt.Sprintf("%t", hashes)
+		result["Strict Dynamic"] = fmt.Sprintf("%t", strictDynamic)
+	}
+
+	if len(findings) > 0 {
+		sortFindings(findings)
+		result["findings"] = findings
+	}
+	return result
+}
+
+// splitPolicies splits header values into individual policies; a comma separates
+// policies that are each enforced
+func splitPolicies(values []string) []string {
+	var policies []string
+	for _, value := range values {
+		for _, policy := range strings.Split(value, ",") {
+			if policy = strings.TrimSpace(policy); policy != "" {
+				policies = append(policies, policy)
+			}
+		}
+	}
+	return policies
+}
+
+// metaPolicies returns the content of <meta http-equiv="Content-Security-Policy"> tags
+func metaPolicies(body []byte) []string {
+	var policies []string
+	tokens := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		switch tokens.Next() {
+		case html.ErrorToken:
+			return policies
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokens.Token()
+			if token.Data == "body" {
+				// Browsers only honour the policy in <head>
+				return policies
+			}
+			if token.Data != "meta" {
+				continue
+			}
+			var equiv, content string
+			for _, attr := range token.Attr {
+				switch strings.ToLower(attr.Key) {
+				case "http-equiv":
+					equiv = attr.Val
+				case "content":
+					content = attr.Val
+				}
+			}
+			if strings.EqualFold(strings.TrimSpace(equiv), "Content-Security-Policy") && strings.TrimSpace(content) != "" {
+				policies = append(policies, strings.TrimSpace(content))
+			}
+		}
+	}
+}
+
+// commonWeaknesses evaluates every policy and keeps the weaknesses all of them share:
+// when several policies are enforced, a resource must pass each one, so a hole in one
+// policy is closed by any other policy that does not have it.
+func commonWeaknesses(policies []cspPolicy) []Finding {
+	var common []Finding
+	for i, policy := range policies {
+		findings := evaluateCSP(policy.directives)
+		if i == 0 {
+			common = findings
+			continue
+		}
+		titles := make(map[string]bool)
+		for _, f := range findings {
+			titles[f.Title] = true
+		}
+		kept := common[:0]
+		for _, f := range common {
+			if titles[f.Title] {
+				kept = append(kept, f)
+			}
+		}
+		common = kept
+	}
+	return common
+}
+
+// effectiveSources is the source list governing a fetch directive, falling back to
+// default-src
+func effectiveSources(directives map[string][]string, name string) ([]string, bool) {
+	if sources, ok := directives[name]; ok {
+		return sources, true
+	}
+	sources, ok := directives["default-src"]
+	return sources, ok
+}
+
+func isHashSource(src string) bool {
+	return strings.HasPrefix(src, "'sha256-") || strings.HasPrefix(src, "'sha384-") || strings.HasPrefix(src, "'sha512-")
+}
+
+// evaluateCSP lists the weaknesses of a single policy
+func evaluateCSP(directives map[string][]string) []Finding {
+	var findings []Finding
+
+	script, restricted := effectiveSources(directives, "script-src")
+	if !restricted {
+		findings = append(findings, Finding{
+			Severity:       SeverityHigh,
+			Title:          "Scripts are not restricted",
+			Detail:         "The policy has neither script-src nor default-src, so any script may run.",
+			Recommendation: "Add script-src 'nonce-{random}' 'strict-dynamic' (or a tight allowlist).",
+		})
+	}
+
+	var nonceOrHash, strictDynamic, unsafeInline, unsafeEval, wildcard bool
+	var schemes, bypassHosts []string
+	for _, src := range script {
+		lower := strings.ToLower(src)
+		switch {
+		case strings.HasPrefix(lower, "'nonce-") || isHashSource(lower):
+			nonceOrHash = true
+		case lower == "'strict-dynamic'":
+			strictDynamic = true
+		case lower == "'unsafe-inline'":
+			unsafeInline = true
+		case lower == "'unsafe-eval'":
+			unsafeEval = true
+		case lower == "*":
+			wildcard = true
+		case lower == "http:" || lower == "https:" || lower == "data:" || lower == "blob:" || lower == "filesystem:":
+			schemes = append(schemes, lower)
+		case isJSONPHost(lower):
+			bypassHosts = append(bypassHosts, src)
+		}
+	}
+
+	// With a nonce or hash, CSP2+ browsers ignore 'unsafe-inline'; with 'strict-dynamic'
+	// they also ignore host and scheme allowlists
+	if unsafeInline && !nonceOrHash && !strictDynamic {
+		findings = append(findings, Finding{
+			Severity:       SeverityHigh,
+			Title:          "script-src allows 'unsafe-inline'",
+			Detail:         "Inline scripts and event handlers run, so the policy does not stop XSS.",
+			Recommendation: "Replace 'unsafe-inline' with nonces or hashes for the inline scripts you need.",
+		})
+	}
+	if unsafeEval {
+		findings = append(findings, Finding{
+			Severity:       SeverityMedium,
+			Title:          "script-src allows 'unsafe-eval'",
+			Detail:         "eval(), new Function() and string timers can turn injected data into code.",
+			Recommendation: "Remove 'unsafe-eval' and refactor code that relies on eval.",
+		})
+	}
+	if !strictDynamic {
+		if wildcard {
+			findings = append(findings, Finding{
+				Severity:       SeverityHigh,
+				Title:          "script-src allows any host (*)",
+				Recommendation: "Restrict scripts to 'self' and specific hosts, or use nonces with 'strict-dynamic'.",
+			})
+		}
+		if len(schemes) > 0 {
+			findings = append(findings, Finding{
+				Severity:       SeverityHigh,
+				Title:          "script-src allows entire schemes",
+				Detail:         fmt.Sprintf("%s lets scripts load from any matching URL.", strings.Join(schemes, " ")),
+				Recommendation: "Remove scheme sources from script-src.",
+			})
+		}
+		if len(bypassHosts) > 0 {
+			findings = append(findings, Finding{
+				Severity:       SeverityHigh,
+				Title:          "script-src allowlists hosts that allow CSP bypasses",
+				Detail:         "JSONP endpoints or arbitrary libraries on " + strings.Join(bypassHosts, ", ") + " can execute attacker-controlled code.",
+				Recommendation: "Use nonces with 'strict-dynamic' instead of a host allowlist, or drop these hosts.",
+			})
+		}
+	}
+
+	if object, ok := effectiveSources(directives, "object-src"); !ok || !(len(object) == 1 && object[0] == "'none'") {
+		findings = append(findings, Finding{
+			Severity:       SeverityMedium,
+			Title:          "object-src is not 'none'",
+			Detail:         "Plugin content (<object>, <embed>) can be used to run script.",
+			Recommendation: "Add object-src 'none'.",
+		})
+	}
+
+	// base-uri does not fall back to default-src
+	if _, ok := directives["base-uri"]; !ok {
+		severity := SeverityLow
+		if nonceOrHash || strictDynamic {
+			// An injected <base> redirects nonced relative script URLs to the attacker
+			severity = SeverityMedium
+		}
+		findings = append(findings, Finding{
+			Severity:       severity,
+			Title:          "base-uri is not restricted",
+			Detail:         "An injected <base> tag can change where relative script URLs are loaded from.",
+			Recommendation: "Add base-uri 'none' (or 'self').",
+		})
+	}
+
+	if sources, ok := directives["default-src"]; ok {
+		for _, src := range sources {
+			if src == "*" || src == "http:" || src == "https:" {
+				findings = append(findings, Finding{
+					Severity:       SeverityLow,
+					Title:          "default-src is overly permissive",
+					Detail:         fmt.Sprintf("default-src %s allows most resource types from anywhere.", src),
+					Recommendation: "Use default-src 'self' and widen individual directives as needed.",
+				})
+				break
+			}
+		}
+	}
+
+	var insecure []string
+	for name, sources := range directives {
+		for _, src := range sources {
+			if lower := strings.ToLower(src); strings.HasPrefix(lower, "http://") || lower == "http:" {
+				insecure = append(insecure, name+" "+src)
+			}
+		}
+	}
+	if len(insecure) > 0 {
+		sort.Strings(insecure)
+		findings = append(findings, Finding{
+			Severity:       SeverityLow,
+			Title:          "Policy allows insecure http: sources",
+			Detail:         fmt.Sprintf("%s can be tampered with in transit.", strings.Join(insecure, ", ")),
+			Recommendation: "Use https: sources only.",
+		})
+	}
+	return findings
+}
+
+// isJSONPHost reports whether a host source matches a known bypassable host
+func isJSONPHost(src string) bool {
+	host := src
+	if i := strings.Index(host, "://"); i >= 0 {
+		host = host[i+3:]
+	}
+	if i := strings.IndexAny(host, "/:"); i >= 0 {
+		host = host[:i]
+	}
+	for _, known := range jsonpHosts {
+		if host == known {
+			return true
+		}
+		if suffix, ok := strings.CutPrefix(known, "*."); ok && strings.HasSuffix(host, "."+suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+const strictCSP = "script-src 'nonce-r4nd0m' 'strict-dynamic'; object-src 'none'; base-uri 'none'"
+
+// policyServer serves a page with the given headers and optional <meta> policy
+func policyServer(t *testing.T, headers map[string]string, meta string) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for name, value := range headers {
+			w.Header().Set(name, value)
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><head>")
+		if meta != "" {
+			fmt.Fprintf(w, `<meta http-equiv="Content-Security-Policy" content="%s">`, meta)
+		}
+		fmt.Fprint(w, "</head><body>hello</body></html>")
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestCSPFindingReportedOnce(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		meta    string
+		mode    string
+		// finding is what content_security alone raises about the policy's mode
+		finding    string
+		headerMode string
+	}{
+		{"none", nil, "", "none", "No Content-Security-Policy", "none"},
+		{"report-only", map[string]string{"Content-Security-Policy-Report-Only": strictCSP}, "", "report-only", "Content-Security-Policy is report-only", "report-only"},
+		{"enforced", map[string]string{"Content-Security-Policy": strictCSP}, "", "enforced", "", "enforced"},
+		{"meta only", nil, strictCSP, "enforced", "", "none"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url := policyServer(t, tt.headers, tt.meta)
+
+			csp := checkCSPPlugin(context.Background(), url).(map[string]interface{})
+			if csp["Mode"] != tt.mode {
+				t.Errorf("content_security Mode = %v, want %s", csp["Mode"], tt.mode)
+			}
+			findings, _ := csp["findings"].([]Finding)
+			titles := findingTitles(findings)
+			for _, title := range []string{"No Content-Security-Policy", "Content-Security-Policy is report-only"} {
+				if slices.Contains(titles, title) != (title == tt.finding) {
+					t.Errorf("content_security findings = %q", titles)
+				}
+			}
+
+			headers := checkSecurityHeadersPlugin(context.Background(), url).(map[string]interface{})
+			if headers["CSP Mode"] != tt.headerMode {
+				t.Errorf("security_headers CSP Mode = %v, want %s", headers["CSP Mode"], tt.headerMode)
+			}
+			headerFindings, _ := headers["findings"].([]Finding)
+			for _, f := range headerFindings {
+				if f.Title == tt.finding || slices.Contains([]string{"Content-Security-Policy missing", "Content-Security-Policy only in report-only mode"}, f.Title) {
+					t.Errorf("security_headers repeats the CSP finding %q", f.Title)
+				}
+			}
+		})
+	}
+}
+
+func TestSecurityHeadersGradeCountsCSP(t *testing.T) {
+	score := func(headers map[string]string) int {
+		result := checkSecurityHeadersPlugin(context.Background(), policyServer(t, headers, "")).(map[string]interface{})
+		return result["Score"].(int)
+	}
+	enforced := score(map[string]string{"Content-Security-Policy": strictCSP})
+	if missing := score(nil); enforced-missing != headerPenalty[SeverityMedium] {
+		t.Errorf("a missing CSP cost %d points, want %d", enforced-missing, headerPenalty[SeverityMedium])
+	}
+	if reportOnly := score(map[string]string{"Content-Security-Policy-Report-Only": strictCSP}); enforced-reportOnly != headerPenalty[SeverityMedium] {
+		t.Errorf("a report-only CSP cost %d points, want %d", enforced-reportOnly, headerPenalty[SeverityMedium])
+	}
+}
+
+func TestCommonWeaknesses(t *testing.T) {
+	tests := []struct {
+		policy string
+		want   []string
+	}{
+		{strictCSP, nil},
+		{"default-src 'self'; object-src 'none'; base-uri 'self'", nil},
+		{"script-src 'self' 'unsafe-inline' 'unsafe-eval'; object-src 'none'; base-uri 'none'", []string{"script-src allows 'unsafe-inline'", "script-src allows 'unsafe-eval'"}},
+		{"script-src 'self' https://cdnjs.cloudflare.com; object-src 'none'; base-uri 'none'", []string{"script-src allowlists hosts that allow CSP bypasses"}},
+		{"script-src *; object-src 'none'; base-uri 'none'", []string{"script-src allows any host (*)"}},
+		{"script-src 'self'", []string{"object-src is not 'none'", "base-uri is not restricted"}},
+		{"img-src 'self'", []string{"Scripts are not restricted"}},
+	}
+	for _, tt := range tests {
+		findings := commonWeaknesses([]cspPolicy{{source: "header", raw: tt.policy, directives: parseCSP(tt.policy)}})
+		titles := findingTitles(findings)
+		for _, want := range tt.want {
+			if !slices.Contains(titles, want) {
+				t.Errorf("commonWeaknesses(%q) = %q, missing %q", tt.policy, titles, want)
+			}
+		}
+		if tt.want == nil && len(findings) > 0 {
+			t.Errorf("commonWeaknesses(%q) = %q, want none", tt.policy, titles)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package scanner
+
+import (
+	"context"
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/crypto/ocsp"
+)
+
+var (
+	// oidEmbeddedSCTs is the precertificate SCT list extension (RFC 6962, 3.3)
+	oidEmbeddedSCTs = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 2}
+	// oidOCSPSCTs carries SCTs inside a stapled OCSP response
+	oidOCSPSCTs = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 5}
+)
+
+func init() {
+	RegisterCheck("certificate_transparency", "Verifies the certificate carries Signed Certificate Timestamps (embedded, TLS extension or OCSP)", checkCTPlugin)
+}
+
+// signedTimestamp is the part of an SCT worth reporting; signatures are not verified
+// because that needs the public keys of every CT log.
+type signedTimestamp struct {
+	version   uint8
+	logID     []byte
+	timestamp time.Time
+}
+
+func checkCTPlugin(ctx context.Context, url string) interface{} {
+	domain, addr := tlsAddress(url)
+	if domain == "" {
+		return map[string]string{"error": "Invalid domain"}
+	}
+
+	state, err := peerTLSState(ctx, addr)
+	if err != nil || len(state.PeerCertificates) == 0 {
+		return map[string]string{"error": "No SSL/TLS on " + addr + " (or timed out)"}
+	}
+	leaf := state.PeerCertificates[0]
+
+	type source struct {
+		name string
+		scts []signedTimestamp
+		err  error
+	}
+	var sources []source
+
+	for _, ext := range leaf.Extensions {
+		if ext.Id.Equal(oidEmbeddedSCTs) {
+			scts, err := sctExtension(ext.Value)
+			sources = append(sources, source{name: "certificate", scts: scts, err: err})
+			break
+		}
+	}
+
+	if len(state.SignedCertificateTimestamps) > 0 {
+		var scts []signedTimestamp
+		var firstErr error
+		for _, raw := range state.SignedCertificateTimestamps {
+			sct, err := parseSCT(raw)
+			if err != nil {
+				firstErr = err
+				continue
+			}
+			scts = append(scts, sct)
+		}
+		sources = append(sources, source{name: "TLS extension", scts: scts, err: firstErr})
+	}
+
+	if len(state.OCSPResponse) > 0 && len(state.PeerCertificates) > 1 {
+		if resp, err := ocsp.ParseResponseForCert(state.OCSPResponse, leaf, state.PeerCertificates[1]); err == nil {
+			for _, ext := range resp.Extensions {
+				if ext.Id.Equal(oidOCSPSCTs) {
+					scts, err := sctExtension(ext.Value)
+					sources = append(sources, source{name: "OCSP response", scts: scts, err: err})
+					break
+				}
+			}
+		}
+	}
+
+	result := map[string]interface{}{}
+	var findings []Finding
+	var entries []string
+	total := 0
+	for _, src := range sources {
+		if src.err != nil {
+			findings = append(findings, Finding{
+				Severity: SeverityLow,
+				Title:    "Malformed SCT list",
+				Detail:   fmt.Sprintf("SCTs from the %s could not be parsed: %v.", src.name, src.err),
+			})
+		}
+		for _, sct := range src.scts {
+			entries = append(entries, fmt.Sprintf("%s: log %s at %s", src.name,
+				base64.StdEncoding.EncodeToString(sct.logID), sct.timestamp.UTC().Format(time.RFC3339)))
+		}
+		total += len(src.scts)
+	}
+	result["SCT Count"] = total
+	result["SCTs"] = entries
+
+	switch {
+	case total == 0:
+		findings = append(findings, Finding{
+			Severity:       SeverityMedium,
+			Title:          "No Signed Certificate Timestamps",
+			Detail:         "The certificate has no embedded SCTs and the server sent none via TLS or OCSP; Chrome and Safari reject such publicly trusted certificates.",
+			Recommendation: "Use a certificate logged to Certificate Transparency (any public CA does this by default).",
+		})
+	case total < 2:
+		findings = append(findings, Finding{
+			Severity:       SeverityLow,
+			Title:          "Fewer SCTs than browser CT policies require",
+			Detail:         "Browser CT policies require SCTs from at least two distinct logs.",
+			Recommendation: "Reissue the certificate from a CA that logs to multiple CT logs.",
+		})
+	}
+
+	if len(findings) > 0 {
+		sortFindings(findings)
+		result["findings"] = findings
+	}
+	return result
+}
+
+// parseSCTList decodes a TLS-encoded SignedCertificateTimestampList
+func parseSCTList(data []byte) ([]signedTimestamp, error) {
+	input := cryptobyte.String(data)
+	var list cryptobyte.String
+	if !input.ReadUint16LengthPrefixed(&list) || !input.Empty() {
+		return nil, errors.New("invalid SCT list encoding")
+	}
+	var scts []signedTimestamp
+	for !list.Empty() {
+		var raw cryptobyte.String
+		if !list.ReadUint16LengthPrefixed(&raw) {
+			return scts, errors.New("truncated SCT list")
+		}
+		sct, err := parseSCT(raw)
+		if err != nil {
+			return scts, err
+		}
+		scts = append(scts, sct)
+	}
+	return scts, nil
+}
+
+// parseSCT decodes one serialized SignedCertificateTimestamp (RFC 6962, 3.2)
+func parseSCT(data []byte) (signedTimestamp, error) {
+	input := cryptobyte.String(data)
+	var sct signedTimestamp
+	var logID []byte
+	var millis uint64
+	var extensions cryptobyte.String
+	if !input.ReadUint8(&sct.version) || !input.ReadBytes(&logID, 32) ||
+		!input.ReadUint64(&millis) || !input.ReadUint16LengthPrefixed(&extensions) {
+		return sct, errors.New("truncated SCT")
+	}
+	if sct.version != 0 {
+		return sct, fmt.Errorf("unsupported SCT version %d", sct.version+1)
+	}
+	sct.logID = logID
+	sct.timestamp = time.UnixMilli(int64(millis))
+	return sct, nil
+}
+
+// sctExtension decodes the OCTET STRING wrapping an SCT list in a certificate or OCSP
+// extension
+func sctExtension(value []byte) ([]signedTimestamp, error) {
+	var list []byte
+	if _, err := asn1.Unmarshal(value, &list); err != nil {
+		return nil, err
+	}
+	return parseSCTList(list)
+}
@@ -0,0 +1,287 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"io"
+	neturl "net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const (
+	// discoveryMaxDirs caps how many discovered directories recursion descends into
+	discoveryMaxDirs = 20
+	// discoveryMaxTitle is how much of a page title is reported
+	discoveryMaxTitle = 80
+)
+
+//go:embed data/content_wordlist.txt
+var builtinWordlist []byte
+
+// defaultMatchStatus are the status codes content_discovery reports by default
+var defaultMatchStatus = []int{200, 204, 301, 302, 307, 308, 401, 403, 405, 500}
+
+var titlePattern = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// DiscoveryOptions configures the content_discovery check
+type DiscoveryOptions struct {
+	// Wordlist replaces the built-in wordlist when not empty
+	Wordlist []string
+	// Extensions are tried on every word in addition to the bare word, e.g. "php"
+	Extensions []string
+	// Depth is how many directory levels are brute forced; 1 covers only the root
+	Depth int
+	// MatchStatus lists the status codes that count as found; empty uses the default
+	MatchStatus []int
+	// FilterSizes drops responses whose body has one of these sizes
+	FilterSizes []int64
+}
+
+var (
+	discoveryMu      sync.RWMutex
+	discoveryOptions = DiscoveryOptions{Depth: 1}
+)
+
+func init() {
+	RegisterIntrusiveCheck("content_discovery", "Brute forces directories and files from a wordlist, calibrated against wildcard responses", checkContentDiscoveryPlugin)
+}
+
+// SetDiscoveryOptions configures content_discovery for subsequent scans
+func SetDiscoveryOptions(opts DiscoveryOptions) {
+	if opts.Depth < 1 {
+		opts.Depth = 1
+	}
+	discoveryMu.Lock()
+	defer discoveryMu.Unlock()
+	discoveryOptions = opts
+}
+
+func currentDiscoveryOptions() DiscoveryOptions {
+	discoveryMu.RLock()
+	defer discoveryMu.RUnlock()
+	return discoveryOptions
+}
+
+// LoadWordlist reads a wordlist with one path segment per line; blank lines and lines
+// starting with # are skipped
+func LoadWordlist(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	words, err := parseWordlist(f)
+	if err != nil {
+		return nil, err
+	}
+	if len(words) == 0 {
+		return nil, fmt.Errorf("%s contains no words", path)
+	}
+	return words, nil
+}
+
+func parseWordlist(r io.Reader) ([]string, error) {
+	var words []string
+	seen := make(map[string]bool)
+	lines := bufio.NewScanner(r)
+	for lines.Scan() {
+		word := strings.Trim(strings.TrimSpace(lines.Text()), "/")
+		if word == "" || strings.HasPrefix(word, "#") || seen[word] {
+			continue
+		}
+		seen[word] = true
+		words = append(words, word)
+	}
+	return words, lines.Err()
+}
+
+func checkContentDiscoveryPlugin(ctx context.Context, url string) interface{} {
+	opts := currentDiscoveryOptions()
+	words := opts.Wordlist
+	wordlist := fmt.Sprintf("custom (%d words)", len(words))
+	if len(words) == 0 {
+		words, _ = parseWordlist(bytes.NewReader(builtinWordlist))
+		wordlist = fmt.Sprintf("built-in (%d words)", len(words))
+	}
+	matchStatus := opts.MatchStatus
+	if len(matchStatus) == 0 {
+		matchStatus = defaultMatchStatus
+	}
+
+	baseURL := landingBase(ctx, url)
+	paths := make(map[string]interface{})
+	var wildcards []string
+	answered, planned := 0, 0
+
+	dirs := []string{""}
+	descended := 0
+	for level := 1; level <= opts.Depth && len(dirs) > 0; level++ {
+		var next []string
+		for _, dir := range dirs {
+			candidates := discoveryCandidates(dir, words, opts.Extensions)
+			planned += len(candidates)
+
+			calibration := calibrateDir(ctx, baseURL, dir, opts.Extensions)
+			for _, cal := range calibration {
+				if slices.Contains(matchStatus, cal.status) {
+					wildcards = append(wildcards, fmt.Sprintf("%s/ (%d)", dir, cal.status))
+					break
+				}
+			}
+
+			responses := make([]*probeResponse, len(candidates))
+			probeEach(len(candidates), func(i int) {
+				if ctx.Err() != nil {
+					return
+				}
+				responses[i], _ = fetchProbe(ctx, redirectProbeClient, baseURL, candidates[i])
+			})
+
+			for i, resp := range responses {
+				if resp == nil {
+					continue
+				}
+				answered++
+				p := candidates[i]
+				if !slices.Contains(matchStatus, resp.status) || slices.Contains(opts.FilterSizes, resp.length) || calibrated(p, resp, calibration) {
+					continue
+				}
+				paths[p] = describeHit(resp)
+
+				if isDirectoryRedirect(p, resp) && level < opts.Depth && descended < discoveryMaxDirs {
+					next = append(next, p)
+					descended++
+				}
+			}
+		}
+		dirs = next
+	}
+
+	result := map[string]interface{}{
+		"Paths":    paths,
+		"Wordlist": wordlist,
+	}
+	if len(wildcards) > 0 {
+		result["Wildcard Responses"] = wildcards
+	}
+	if ctx.Err() != nil {
+		result["Incomplete"] = fmt.Sprintf("Stopped at the scan timeout after %d of %d requests; raise -timeout for a full run", answered, planned)
+	}
+	return result
+}
+
+// discoveryCandidates lists the paths to try in dir: every word, and every word with
+// each extension unless it already has one
+func discoveryCandidates(dir string, words, extensions []string) []string {
+	candidates := make([]string, 0, len(words)*(1+len(extensions)))
+	for _, word := range words {
+		candidates = append(candidates, dir+"/"+word)
+		if strings.Contains(word, ".") {
+			continue
+		}
+		for _, ext := range extensions {
+			candidates = append(candidates, dir+"/"+word+"."+ext)
+		}
+	}
+	return candidates
+}
+
+// calibrateDir requests random paths in dir, bare, as a dotfile and with each
+// extension, to learn how the server answers for content that does not exist
+func calibrateDir(ctx context.Context, baseURL, dir string, extensions []string) []*probeResponse {
+	token := make([]byte, 8)
+	if _, err := rand.Read(token); err != nil {
+		return nil
+	}
+	name := "urlhawk" + hex.EncodeToString(token)
+	probes := []string{dir + "/" + name, dir + "/." + name}
+	for _, ext := range extensions {
+		probes = append(probes, dir+"/"+name+"."+ext)
+	}
+
+	responses := make([]*probeResponse, len(probes))
+	probeEach(len(probes), func(i int) {
+		responses[i], _ = fetchProbe(ctx, redirectProbeClient, baseURL, probes[i])
+	})
+	var calibration []*probeResponse
+	for i, resp := range responses {
+		if resp != nil {
+			// Remember which path produced it so its echoes can be stripped from Location
+			resp.location = strings.ReplaceAll(resp.location, probes[i], "")
+			calibration = append(calibration, resp)
+		}
+	}
+	return calibration
+}
+
+// calibrated reports whether resp for path p matches one of the wildcard responses:
+// same status and the same content or a size within 5%, and for redirects the same
+// target once the requested path is removed
+func calibrated(p string, resp *probeResponse, calibration []*probeResponse) bool {
+	location := strings.ReplaceAll(resp.location, p, "")
+	for _, cal := range calibration {
+		if resp.status != cal.status {
+			continue
+		}
+		if resp.sum == cal.sum {
+			return true
+		}
+		if location != cal.location {
+			continue
+		}
+		diff := resp.size - cal.size
+		if diff < 0 {
+			diff = -diff
+		}
+		if diff <= cal.size/20 {
+			return true
+		}
+	}
+	return false
+}
+
+// isDirectoryRedirect reports whether p redirects to itself with a trailing slash
+func isDirectoryRedirect(p string, resp *probeResponse) bool {
+	if resp.status < 300 || resp.status > 399 || resp.location == "" {
+		return false
+	}
+	loc, err := neturl.Parse(resp.location)
+	return err == nil && strings.HasSuffix(loc.Path, p+"/")
+}
+
+func pageTitle(body []byte) string {
+	m := titlePattern.FindSubmatch(body)
+	if m == nil {
+		return ""
+	}
+	title := strings.Join(strings.Fields(html.UnescapeString(string(m[1]))), " ")
+	if runes := []rune(title); len(runes) > discoveryMaxTitle {
+		title = string(runes[:discoveryMaxTitle]) + "..."
+	}
+	return title
+}
+
+// describeHit reports a found path's status, size, title and redirect target
+func describeHit(resp *probeResponse) map[string]interface{} {
+	described := map[string]interface{}{
+		"Status": resp.status,
+		"Size":   resp.length,
+	}
+	if title := pageTitle(resp.body); title != "" {
+		described["Title"] = title
+	}
+	if resp.location != "" {
+		described["Redirect"] = resp.location
+	}
+	return described
+}
@@ -0,0 +1,100 @@
+package scanner
+
+import (
+	"crypto/sha256"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseWordlist(t *testing.T) {
+	words, err := parseWordlist(strings.NewReader("# admin panels\nadmin\n/admin/\n\n  backup  \n#old\napi/v1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"admin", "backup", "api/v1"}
+	if !slices.Equal(words, want) {
+		t.Errorf("words = %q, want %q", words, want)
+	}
+}
+
+func TestDiscoveryCandidates(t *testing.T) {
+	got := discoveryCandidates("/app", []string{"admin", "robots.txt"}, []string{"php", "bak"})
+	want := []string{"/app/admin", "/app/admin.php", "/app/admin.bak", "/app/robots.txt"}
+	if !slices.Equal(got, want) {
+		t.Errorf("candidates = %q, want %q", got, want)
+	}
+}
+
+func TestCalibrated(t *testing.T) {
+	page := func(status int, body, location string) *probeResponse {
+		return &probeResponse{status: status, sum: sha256.Sum256([]byte(body + location)), size: len(body), location: location}
+	}
+	catchAll := strings.Repeat("x", 1000)
+	// calibrateDir strips its random path from Location; calibrated strips p
+	calibration := []*probeResponse{
+		page(http.StatusOK, catchAll, ""),
+		page(http.StatusFound, "", "https://example.com/login?next="),
+	}
+
+	tests := []struct {
+		name string
+		p    string
+		resp *probeResponse
+		want bool
+	}{
+		{"same page", "/admin", page(http.StatusOK, catchAll, ""), true},
+		{"size within 5%", "/admin", page(http.StatusOK, catchAll+"/admin", ""), true},
+		{"different page", "/admin", page(http.StatusOK, "<h1>Admin</h1>", ""), false},
+		{"different status", "/admin", page(http.StatusForbidden, catchAll, ""), false},
+		{"same redirect", "/admin", page(http.StatusFound, "", "https://example.com/login?next=/admin"), true},
+		{"other redirect", "/admin", page(http.StatusFound, "", "https://example.com/admin/"), false},
+	}
+	for _, tt := range tests {
+		if got := calibrated(tt.p, tt.resp, calibration); got != tt.want {
+			t.Errorf("%s: calibrated = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+	if calibrated("/admin", page(http.StatusOK, catchAll, ""), nil) {
+		t.Error("a response without calibration counted as a wildcard")
+	}
+}
+
+func TestIsDirectoryRedirect(t *testing.T) {
+	tests := []struct {
+		p        string
+		status   int
+		location string
+		want     bool
+	}{
+		{"/admin", http.StatusMovedPermanently, "https://example.com/admin/", true},
+		{"/admin", http.StatusFound, "/app/admin/", true},
+		{"/admin", http.StatusFound, "https://example.com/login", false},
+		{"/admin", http.StatusFound, "", false},
+		{"/admin", http.StatusOK, "https://example.com/admin/", false},
+	}
+	for _, tt := range tests {
+		resp := &probeResponse{status: tt.status, location: tt.location}
+		if got := isDirectoryRedirect(tt.p, resp); got != tt.want {
+			t.Errorf("isDirectoryRedirect(%q, %d %q) = %t, want %t", tt.p, tt.status, tt.location, got, tt.want)
+		}
+	}
+}
+
+func TestPageTitle(t *testing.T) {
+	long := strings.Repeat("é", discoveryMaxTitle+5)
+	tests := []struct {
+		body, want string
+	}{
+		{"<html><head><TITLE>Admin &amp; Login</TITLE></head>", "Admin & Login"},
+		{"<title lang=\"en\">\n  Index of\n  /backup\n</title>", "Index of /backup"},
+		{"<h1>No title</h1>", ""},
+		{"<title>" + long + "</title>", strings.Repeat("é", discoveryMaxTitle) + "..."},
+	}
+	for _, tt := range tests {
+		if got := pageTitle([]byte(tt.body)); got != tt.want {
+			t.Errorf("pageTitle(%q) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
@@ -93,6 +93,11 @@ func extractDomain(url string) string {
 	domain = strings.TrimPrefix(domain, "https://")
 	parts := strings.Split(domain, "/")
 	if len(parts) > 0 {
+		// IPv6 literals are bracketed and contain colons themselves
+		if host, ok := strings.CutPrefix(parts[0], "["); ok {
+			host, _, _ = strings.Cut(host, "]")
+			return host
+		}
 		// remove port if it exists just for safety on DNS lookups
 		return strings.Split(parts[0], ":")[0]
 	}
@@ -31,7 +31,7 @@ func checkGeoPlugin(ctx context.Context, url string) interface{} {
 		return map[string]string{"error": "Failed to create request"}
 	}
 
-	resp, err := pluginClient.Do(req)
+	resp, err := osintClient.Do(req)
 	if err != nil {
 		return map[string]string{"error": "Geo API unavailable"}
 	}
@@ -0,0 +1,593 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// gitMaxFile caps metadata downloads; the index of a large repository runs to a few MB
+	gitMaxFile = 16 << 20
+	// gitMaxObject caps a single object, compressed or inflated
+	gitMaxObject = 16 << 20
+	// gitMaxCommits is how many commits are read back from HEAD
+	gitMaxCommits = 10
+	// gitMaxListedFiles is how many tracked files are listed in the result
+	gitMaxListedFiles = 200
+	// gitMaxDumpFiles caps how many blobs a dump downloads
+	gitMaxDumpFiles = 1000
+)
+
+var (
+	gitDumpMu  sync.RWMutex
+	gitDumpDir string
+
+	gitHeadPattern   = regexp.MustCompile(`^(ref: refs/\S+|[0-9a-f]{40})\s*$`)
+	gitConfigPattern = regexp.MustCompile(`(?m)^\[(core|remote|branch)`)
+	gitSHAPattern    = regexp.MustCompile(`^[0-9a-f]{40}$`)
+	gitFetchedBranch = regexp.MustCompile(`branch '([^']+)' of`)
+	gitCheckout      = regexp.MustCompile(`checkout: moving from (\S+) to (\S+)`)
+)
+
+// gitMetadata is fetched from every exposed repository; all of it is plain text
+// except the index
+var gitMetadata = []string{"config", "packed-refs", "index", "logs/HEAD", "FETCH_HEAD", "ORIG_HEAD", "description"}
+
+// gitSecretNames mark tracked files that usually hold credentials
+var gitSecretNames = regexp.MustCompile(`(?i)(^|/)(\.env(\.[\w.-]+)?|id_(rsa|dsa|ecdsa|ed25519)|[\w.-]+\.(pem|key|p12|pfx|kdbx)|\.htpasswd|\.npmrc|\.pypirc|credentials(\.json)?|secrets?\.(ya?ml|json)|wp-config\.php|settings\.py|database\.yml|master\.key)$`)
+
+// gitIndexEntry is a tracked file from .git/index
+type gitIndexEntry struct {
+	name string
+	sha  string
+	mode uint32
+}
+
+// gitCommit is the part of a commit object that is reported
+type gitCommit struct {
+	sha     string
+	parent  string
+	author  string
+	when    time.Time
+	subject string
+}
+
+func init() {
+	RegisterCheck("git_exposure", "Reconstructs an exposed .git directory: remote URL, branches, recent commits and tracked files", checkGitExposurePlugin)
+}
+
+// SetGitDumpDir makes git_exposure save what it recovers from an exposed repository
+// (metadata, objects and the tracked files it can rebuild) under dir/<host>. An
+// empty dir disables dumping.
+func SetGitDumpDir(dir string) {
+	gitDumpMu.Lock()
+	defer gitDumpMu.Unlock()
+	gitDumpDir = dir
+}
+
+func currentGitDumpDir() string {
+	gitDumpMu.RLock()
+	defer gitDumpMu.RUnlock()
+	return gitDumpDir
+}
+
+// gitRepo is an exposed .git directory. Everything fetched is kept for the dump.
+type gitRepo struct {
+	base string
+
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+func (r *gitRepo) fetch(ctx context.Context, name string) ([]byte, bool) {
+	req, err := http.NewRequestWithContext(ctx, "GET", r.base+name, nil)
+	if err != nil {
+		return nil, false
+	}
+	req.Header.Set("User-Agent", "URLHawkScanner-Scanner/1.0")
+
+	resp, err := pluginClient.Do(req)
+	if err != nil {
+		return nil, false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, false
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, gitMaxFile))
+	if err != nil || len(data) == 0 {
+		return nil, false
+	}
+
+	r.mu.Lock()
+	r.files[name] = data
+	r.mu.Unlock()
+	return data, true
+}
+
+func (r *gitRepo) file(name string) []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.files[name]
+}
+
+// object fetches and inflates a loose object, returning its type and content
+func (r *gitRepo) object(ctx context.Context, sha string) (string, []byte, bool) {
+	raw, ok := r.fetch(ctx, "objects/"+sha[:2]+"/"+sha[2:])
+	if !ok {
+		return "", nil, false
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return "", nil, false
+	}
+	defer zr.Close()
+	data, err := io.ReadAll(io.LimitReader(zr, gitMaxObject))
+	if err != nil {
+		return "", nil, false
+	}
+	header, content, ok := bytes.Cut(data, []byte{0})
+	if !ok {
+		return "", nil, false
+	}
+	kind, _, _ := strings.Cut(string(header), " ")
+	return kind, content, true
+}
+
+func checkGitExposurePlugin(ctx context.Context, url string) interface{} {
+	baseURL := landingBase(ctx, url)
+	repo := &gitRepo{base: baseURL + "/.git/", files: make(map[string][]byte)}
+
+	head, _ := repo.fetch(ctx, "HEAD")
+	if !gitHeadPattern.Match(head) {
+		// Some servers block HEAD but still serve the config
+		config, _ := repo.fetch(ctx, "config")
+		if !gitConfigPattern.Match(config) {
+			return map[string]interface{}{}
+		}
+	}
+	probeEach(len(gitMetadata), func(i int) {
+		if repo.file(gitMetadata[i]) == nil {
+			repo.fetch(ctx, gitMetadata[i])
+		}
+	})
+
+	result := map[string]interface{}{}
+	var findings []Finding
+
+	remote, credentials := gitRemote(repo.file("config"))
+	if remote != "" {
+		result["Remote URL"] = remote
+	}
+	if credentials {
+		findings = append(findings, Finding{
+			Severity:       SeverityCritical,
+			Title:          "Credentials in exposed git remote URL",
+			Detail:         "The remote URL in /.git/config embeds a username and password or access token: " + remote,
+			Recommendation: "Revoke the credential, remove it from the remote URL and block access to /.git/.",
+		})
+	}
+
+	refs := gitRefs(repo.file("packed-refs"))
+	branch := ""
+	headRef, isRef := strings.CutPrefix(strings.TrimSpace(string(head)), "ref: ")
+	headSHA := ""
+	switch {
+	case isRef:
+		branch = strings.TrimPrefix(headRef, "refs/heads/")
+		if data, ok := repo.fetch(ctx, headRef); ok && gitSHAPattern.Match(bytes.TrimSpace(data)) {
+			headSHA = string(bytes.TrimSpace(data))
+		} else {
+			headSHA = refs[headRef]
+		}
+	case gitSHAPattern.MatchString(strings.TrimSpace(string(head))):
+		headSHA = strings.TrimSpace(string(head))
+		branch = "(detached HEAD)"
+	}
+	if branch != "" {
+		result["Current Branch"] = branch
+	}
+	if branches := gitBranches(refs, headRef, repo.file("FETCH_HEAD"), repo.file("logs/HEAD")); len(branches) > 0 {
+		result["Branches"] = branches
+	}
+
+	commits := gitHistory(ctx, repo, headSHA)
+	looseObjects := len(commits) > 0
+	if len(commits) == 0 {
+		commits = gitReflog(repo.file("logs/HEAD"))
+	}
+	if len(commits) > 0 {
+		described := make([]string, 0, len(commits))
+		for _, c := range commits {
+			described = append(described, fmt.Sprintf("%s %s %s: %s", c.sha[:7], c.when.UTC().Format("2006-01-02"), c.author, c.subject))
+		}
+		result["Recent Commits"] = described
+	}
+
+	entries, _ := parseGitIndex(repo.file("index"))
+	if len(entries) > 0 {
+		names := make([]string, 0, len(entries))
+		var secrets []string
+		for _, e := range entries {
+			names = append(names, e.name)
+			if gitSecretNames.MatchString(e.name) {
+				secrets = append(secrets, e.name)
+			}
+		}
+		result["Tracked File Count"] = len(names)
+		if len(names) > gitMaxListedFiles {
+			names = append(names[:gitMaxListedFiles], fmt.Sprintf("... and %d more", len(entries)-gitMaxListedFiles))
+		}
+		result["Tracked Files"] = names
+		if len(secrets) > 0 {
+			findings = append(findings, Finding{
+				Severity:       SeverityHigh,
+				Title:          "Secret files tracked in the exposed repository",
+				Detail:         "The index lists files that usually hold credentials: " + strings.Join(secrets, ", "),
+				Recommendation: "Rotate the secrets in these files and remove them from the repository history.",
+			})
+		}
+		if !looseObjects {
+			_, _, looseObjects = repo.object(ctx, entries[0].sha)
+		}
+	}
+
+	detail := "The /.git/ directory is readable over HTTP"
+	switch {
+	case looseObjects:
+		detail += ", including loose objects, so source files can be downloaded and rebuilt."
+	case len(entries) > 0:
+		detail += "; the index reveals every tracked file name, and packed objects may still be downloadable."
+	default:
+		detail += "."
+	}
+	findings = append(findings, Finding{
+		Severity:       SeverityHigh,
+		Title:          "Git repository exposed",
+		Detail:         detail,
+		Recommendation: "Deny access to /.git/ in the web server (or deploy without it) and review the history for committed secrets.",
+	})
+
+	if dir := currentGitDumpDir(); dir != "" {
+		dest := filepath.Join(dir, gitDumpName(baseURL))
+		recovered, err := dumpGitRepo(ctx, repo, entries, dest)
+		if err != nil {
+			result["Dump Error"] = err.Error()
+		} else {
+			result["Dumped To"] = dest
+			result["Recovered Files"] = recovered
+		}
+	}
+
+	sortFindings(findings)
+	result["findings"] = findings
+	return result
+}
+
+// gitRemote returns the origin URL from .git/config (or the first remote when there is
+// no origin) with any credentials redacted, and whether it carried credentials
+func gitRemote(config []byte) (string, bool) {
+	var section, first, origin string
+	lines := bufio.NewScanner(bytes.NewReader(config))
+	for lines.Scan() {
+		line := strings.TrimSpace(lines.Text())
+		if strings.HasPrefix(line, "[") {
+			section = line
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(key) != "url" || !strings.HasPrefix(section, "[remote ") {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if first == "" {
+			first = value
+		}
+		if section == `[remote "origin"]` {
+			origin = value
+		}
+	}
+	remote := origin
+	if remote == "" {
+		remote = first
+	}
+
+	u, err := neturl.Parse(remote)
+	if err != nil || u.User == nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return remote, false
+	}
+	// A bare user name is usually an access token
+	u.User = neturl.User("REDACTED")
+	return u.String(), true
+}
+
+// gitRefs maps ref names to commit hashes from packed-refs
+func gitRefs(packed []byte) map[string]string {
+	refs := make(map[string]string)
+	for _, line := range strings.Split(string(packed), "\n") {
+		sha, name, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if ok && gitSHAPattern.MatchString(sha) {
+			refs[name] = sha
+		}
+	}
+	return refs
+}
+
+// gitBranches collects branch names from packed-refs, HEAD, FETCH_HEAD and checkouts
+// recorded in the reflog
+func gitBranches(refs map[string]string, headRef string, fetchHead, reflog []byte) []string {
+	set := make(map[string]bool)
+	for name := range refs {
+		switch {
+		case strings.HasPrefix(name, "refs/heads/"):
+			set[strings.TrimPrefix(name, "refs/heads/")] = true
+		case strings.HasPrefix(name, "refs/remotes/") && !strings.HasSuffix(name, "/HEAD"):
+			set[strings.TrimPrefix(name, "refs/")] = true
+		}
+	}
+	if strings.HasPrefix(headRef, "refs/heads/") {
+		set[strings.TrimPrefix(headRef, "refs/heads/")] = true
+	}
+	for _, m := range gitFetchedBranch.FindAllSubmatch(fetchHead, -1) {
+		set[string(m[1])] = true
+	}
+	for _, m := range gitCheckout.FindAllSubmatch(reflog, -1) {
+		for _, name := range m[1:] {
+			if !gitSHAPattern.Match(name) {
+				set[string(name)] = true
+			}
+		}
+	}
+	branches := make([]string, 0, len(set))
+	for name := range set {
+		branches = append(branches, name)
+	}
+	sort.Strings(branches)
+	return branches
+}
+
+// gitHistory walks first parents from sha through loose commit objects
+func gitHistory(ctx context.Context, repo *gitRepo, sha string) []gitCommit {
+	var commits []gitCommit
+	for len(commits) < gitMaxCommits && gitSHAPattern.MatchString(sha) {
+		kind, content, ok := repo.object(ctx, sha)
+		if !ok || kind != "commit" {
+			break
+		}
+		c := parseGitCommit(sha, content)
+		commits = append(commits, c)
+		sha = c.parent
+	}
+	return commits
+}
+
+func parseGitCommit(sha string, content []byte) gitCommit {
+	c := gitCommit{sha: sha}
+	header, message, _ := strings.Cut(string(content), "\n\n")
+	for _, line := range strings.Split(header, "\n") {
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "parent":
+			if c.parent == "" {
+				c.parent = value
+			}
+		case "author":
+			c.author, c.when = parseGitSignature(value)
+		}
+	}
+	c.subject, _, _ = strings.Cut(strings.TrimSpace(message), "\n")
+	return c
+}
+
+// parseGitSignature splits "Name <email> 1700000000 +0100" into who and when
+func parseGitSignature(sig string) (string, time.Time) {
+	end := strings.LastIndex(sig, ">")
+	if end < 0 {
+		return sig, time.Time{}
+	}
+	fields := strings.Fields(sig[end+1:])
+	var when time.Time
+	if len(fields) > 0 {
+		if secs, err := strconv.ParseInt(fields[0], 10, 64); err == nil {
+			when = time.Unix(secs, 0)
+		}
+	}
+	return sig[:end+1], when
+}
+
+// gitReflog reads recent commits from logs/HEAD, newest first, for repositories
+// whose objects are packed
+func gitReflog(reflog []byte) []gitCommit {
+	var commits []gitCommit
+	lines := strings.Split(strings.TrimSpace(string(reflog)), "\n")
+	for i := len(lines) - 1; i >= 0 && len(commits) < gitMaxCommits; i-- {
+		entry, message, _ := strings.Cut(lines[i], "\t")
+		fields := strings.SplitN(entry, " ", 3)
+		// Checkouts move HEAD without creating a commit
+		if len(fields) < 3 || !gitSHAPattern.MatchString(fields[1]) || strings.HasPrefix(message, "checkout:") {
+			continue
+		}
+		author, when := parseGitSignature(fields[2])
+		commits = append(commits, gitCommit{sha: fields[1], author: author, when: when, subject: message})
+	}
+	return commits
+}
+
+// parseGitIndex lists the entries of a .git/index file (versions 2 to 4)
+func parseGitIndex(data []byte) ([]gitIndexEntry, error) {
+	if len(data) < 12 || string(data[:4]) != "DIRC" {
+		return nil, fmt.Errorf("not a git index")
+	}
+	version := binary.BigEndian.Uint32(data[4:8])
+	count := binary.BigEndian.Uint32(data[8:12])
+	if version < 2 || version > 4 {
+		return nil, fmt.Errorf("unsupported index version %d", version)
+	}
+
+	var entries []gitIndexEntry
+	var previous string
+	pos := 12
+	for i := uint32(0); i < count; i++ {
+		start := pos
+		if pos+62 > len(data) {
+			return entries, fmt.Errorf("truncated index")
+		}
+		mode := binary.BigEndian.Uint32(data[pos+24 : pos+28])
+		sha := hex.EncodeToString(data[pos+40 : pos+60])
+		flags := binary.BigEndian.Uint16(data[pos+60 : pos+62])
+		pos += 62
+		if version >= 3 && flags&0x4000 != 0 {
+			pos += 2
+		}
+		if pos > len(data) {
+			return entries, fmt.Errorf("truncated index")
+		}
+
+		var name string
+		if version == 4 {
+			// The name is the previous name minus N bytes plus a NUL-terminated suffix
+			strip, n := gitIndexVarint(data[pos:])
+			if n == 0 || strip > len(previous) {
+				return entries, fmt.Errorf("corrupt index entry %d", i)
+			}
+			pos += n
+			end := bytes.IndexByte(data[pos:], 0)
+			if end < 0 {
+				return entries, fmt.Errorf("truncated index")
+			}
+			name = previous[:len(previous)-strip] + string(data[pos:pos+end])
+			pos += end + 1
+		} else {
+			end := bytes.IndexByte(data[pos:], 0)
+			if end < 0 {
+				return entries, fmt.Errorf("truncated index")
+			}
+			name = string(data[pos : pos+end])
+			// Entries are NUL-padded to a multiple of 8 bytes
+			pos = start + (pos+end-start+8)&^7
+		}
+		previous = name
+		entries = append(entries, gitIndexEntry{name: name, sha: sha, mode: mode})
+	}
+	return entries, nil
+}
+
+// gitIndexVarint decodes git's offset varint, returning the value and bytes read
+func gitIndexVarint(b []byte) (int, int) {
+	if len(b) == 0 {
+		return 0, 0
+	}
+	value := int(b[0] & 0x7f)
+	i := 1
+	for b[i-1]&0x80 != 0 {
+		if i >= len(b) || i > 8 {
+			return 0, 0
+		}
+		value = ((value + 1) << 7) | int(b[i]&0x7f)
+		i++
+	}
+	return value, i
+}
+
+// gitDumpName is the directory a target's dump goes to, e.g. "example.com_8080"
+func gitDumpName(baseURL string) string {
+	u, err := neturl.Parse(baseURL)
+	if err != nil || u.Host == "" {
+		return "unknown"
+	}
+	return strings.ReplaceAll(u.Host, ":", "_")
+}
+
+// dumpGitRepo writes the fetched metadata and objects to dest/.git and rebuilds the
+// tracked files it can download as loose blobs. Paths come from the server, so
+// anything that would leave dest or write into a .git directory is skipped.
+func dumpGitRepo(ctx context.Context, repo *gitRepo, entries []gitIndexEntry, dest string) (int, error) {
+	var blobs []gitIndexEntry
+	for _, e := range entries {
+		// Regular files and symlinks (saved as a file holding the target); skip gitlinks
+		if e.mode&0o170000 != 0o100000 && e.mode&0o170000 != 0o120000 {
+			continue
+		}
+		if !safeDumpPath(e.name) {
+			continue
+		}
+		blobs = append(blobs, e)
+		if len(blobs) == gitMaxDumpFiles {
+			break
+		}
+	}
+
+	contents := make([][]byte, len(blobs))
+	probeEach(len(blobs), func(i int) {
+		if kind, content, ok := repo.object(ctx, blobs[i].sha); ok && kind == "blob" {
+			contents[i] = content
+		}
+	})
+
+	if err := os.MkdirAll(filepath.Join(dest, ".git"), 0o700); err != nil {
+		return 0, err
+	}
+	repo.mu.Lock()
+	files := make(map[string][]byte, len(repo.files))
+	for name, data := range repo.files {
+		files[name] = data
+	}
+	repo.mu.Unlock()
+	for name, data := range files {
+		if !safeDumpPath(name) {
+			continue
+		}
+		if err := writeDumpFile(filepath.Join(dest, ".git"), name, data); err != nil {
+			return 0, err
+		}
+	}
+
+	recovered := 0
+	for i, e := range blobs {
+		if contents[i] == nil {
+			continue
+		}
+		if err := writeDumpFile(dest, e.name, contents[i]); err != nil {
+			return recovered, err
+		}
+		recovered++
+	}
+	return recovered, nil
+}
+
+// safeDumpPath rejects absolute paths, .. components and any .git component
+func safeDumpPath(name string) bool {
+	if !filepath.IsLocal(filepath.FromSlash(name)) {
+		return false
+	}
+	for _, part := range strings.Split(name, "/") {
+		if strings.EqualFold(part, ".git") {
+			return false
+		}
+	}
+	return true
+}
+
+func writeDumpFile(root, name string, data []byte) error {
+	path := filepath.Join(root, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
@@ -0,0 +1,442 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// hstsMinMaxAge is the max-age below which HSTS is considered too short (180 days)
+const hstsMinMaxAge = 15552000
+
+// scoredHeaders are reported in the scorecard, present or not
+var scoredHeaders = []string{
+	"Content-Security-Policy",
+	"Strict-Transport-Security",
+	"X-Frame-Options",
+	"X-Content-Type-Options",
+	"Referrer-Policy",
+	"Permissions-Policy",
+	"Cross-Origin-Opener-Policy",
+	"Cross-Origin-Embedder-Policy",
+	"Cross-Origin-Resource-Policy",
+}
+
+// headerPenalty is the score deducted per finding of each severity
+var headerPenalty = map[string]int{
+	SeverityCritical: 40,
+	SeverityHigh:     25,
+	SeverityMedium:   15,
+	SeverityLow:      5,
+	SeverityInfo:     0,
+}
+
+func init() {
+	RegisterCheck("security_headers", "Grades security headers by parsing their values (CSP, HSTS, XFO, COOP/COEP/CORP and more)", checkSecurityHeadersPlugin)
+}
+
+func checkSecurityHeadersPlugin(ctx context.Context, url string) interface{} {
+	p := fetchPage(ctx, url)
+	if p.Err != nil {
+		return map[string]string{"error": "Failed to reach host"}
+	}
+
+	headers := make(map[string]string)
+	for _, name := range scoredHeaders {
+		value := strings.Join(p.Header.Values(name), ", ")
+		if value == "" {
+			value = "missing"
+		}
+		headers[name] = value
+	}
+
+	https := strings.HasPrefix(p.URL, "https://")
+	var findings []Finding
+	findings = append(findings, hstsFindings(p.Header.Get("Strict-Transport-Security"), https)...)
+	findings = append(findings, frameFindings(p.Header)...)
+	findings = append(findings, nosniffFindings(p.Header.Get("X-Content-Type-Options"))...)
+	findings = append(findings, referrerFindings(p.Header.Values("Referrer-Policy"))...)
+	findings = append(findings, permissionsFindings(p.Header)...)
+	findings = append(findings, crossOriginFindings(p.Header)...)
+	findings = append(findings, deprecatedHeaderFindings(p.Header)...)
+
+	// content_security owns the finding for a missing or report-only policy; the
+	// grade still counts it
+	mode := cspHeaderMode(p.Header)
+	score := 100
+	if mode != "enforced" {
+		score -= headerPenalty[SeverityMedium]
+	}
+	for _, f := range findings {
+		score -= headerPenalty[f.Severity]
+	}
+	if score < 0 {
+		score = 0
+	}
+
+	result := map[string]interface{}{
+		"Headers":  headers,
+		"CSP Mode": mode,
+		"Score":    score,
+		"Grade":    headerGrade(score),
+	}
+	if len(findings) > 0 {
+		sortFindings(findings)
+		result["findings"] = findings
+	}
+	return result
+}
+
+func headerGrade(score int) string {
+	switch {
+	case score >= 90:
+		return "A"
+	case score >= 75:
+		return "B"
+	case score >= 60:
+		return "C"
+	case score >= 40:
+		return "D"
+	}
+	return "F"
+}
+
+// parseCSP splits a policy into lower-cased directive names and their source lists.
+// As in browsers, only the first occurrence of a directive counts.
+func parseCSP(policy string) map[string][]string {
+	directives := make(map[string][]string)
+	for _, part := range strings.Split(policy, ";") {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+		name := strings.ToLower(fields[0])
+		if _, seen := directives[name]; !seen {
+			directives[name] = fields[1:]
+		}
+	}
+	return directives
+}
+
+// enforcedPolicies returns the parsed Content-Security-Policy headers; several headers
+// (or comma-joined policies) are all enforced
+func enforcedPolicies(h http.Header) []map[string][]string {
+	var policies []map[string][]string
+	for _, policy := range splitPolicies(h.Values("Content-Security-Policy")) {
+		policies = append(policies, parseCSP(policy))
+	}
+	return policies
+}
+
+// cspHeaderMode describes the Content-Security-Policy headers as enforced,
+// report-only or none, the same modes content_security reports
+func cspHeaderMode(h http.Header) string {
+	switch {
+	case len(enforcedPolicies(h)) > 0:
+		return "enforced"
+	case h.Get("Content-Security-Policy-Report-Only") != "":
+		return "report-only"
+	}
+	return "none"
+}
+
+// parseHSTS reads a Strict-Transport-Security value; maxAge is -1 when it is
+// missing or invalid
+func parseHSTS(value string) (maxAge int, includeSubDomains, preload bool) {
+	maxAge = -1
+	for _, directive := range strings.Split(value, ";") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "max-age":
+			if n, err := strconv.Atoi(strings.Trim(strings.TrimSpace(arg), `"`)); err == nil && n >= 0 {
+				maxAge = n
+			}
+		case "includesubdomains":
+			includeSubDomains = true
+		case "preload":
+			preload = true
+		}
+	}
+	return maxAge, includeSubDomains, preload
+}
+
+func hstsFindings(value string, https bool) []Finding {
+	const snippet = "Strict-Transport-Security: max-age=31536000; includeSubDomains"
+	if !https {
+		return []Finding{{
+			Severity:       SeverityHigh,
+			Title:          "Page not served over HTTPS",
+			Detail:         "HSTS is only honoured over HTTPS, so traffic to this page can be intercepted or downgraded.",
+			Recommendation: "Redirect HTTP to HTTPS and send " + snippet,
+		}}
+	}
+	if value == "" {
+		return []Finding{{
+			Severity:       SeverityMedium,
+			Title:          "Strict-Transport-Security missing",
+			Detail:         "First visits and links over http:// can be downgraded to plaintext.",
+			Recommendation: snippet,
+		}}
+	}
+
+	maxAge, includeSubDomains, _ := parseHSTS(value)
+
+	var findings []Finding
+	switch {
+	case maxAge < 0:
+		findings = append(findings, Finding{
+			Severity:       SeverityMedium,
+			Title:          "Strict-Transport-Security has no valid max-age",
+			Detail:         fmt.Sprintf("Browsers ignore the header %q.", value),
+			Recommendation: snippet,
+		})
+	case maxAge == 0:
+		findings = append(findings, Finding{
+			Severity:       SeverityMedium,
+			Title:          "Strict-Transport-Security disabled with max-age=0",
+			Recommendation: snippet,
+		})
+	case maxAge < hstsMinMaxAge:
+		findings = append(findings, Finding{
+			Severity:       SeverityLow,
+			Title:          "Strict-Transport-Security max-age too short",
+			Detail:         fmt.Sprintf("max-age is %d seconds (%d days); at least 180 days is recommended.", maxAge, maxAge/86400),
+			Recommendation: snippet,
+		})
+	}
+	if maxAge > 0 && !includeSubDomains {
+		findings = append(findings, Finding{
+			Severity:       SeverityLow,
+			Title:          "Strict-Transport-Security without includeSubDomains",
+			Detail:         "Subdomains can still be reached over plain HTTP and used to set cookies for this domain.",
+			Recommendation: snippet,
+		})
+	}
+	return findings
+}
+
+// frameFindings checks clickjacking protection. CSP frame-ancestors supersedes
+// X-Frame-Options, so either one is enough.
+func frameFindings(h http.Header) []Finding {
+	frameAncestors := false
+	for _, policy := range enforcedPolicies(h) {
+		if _, ok := policy["frame-ancestors"]; ok {
+			frameAncestors = true
+		}
+	}
+
+	xfo := strings.ToUpper(strings.TrimSpace(h.Get("X-Frame-Options")))
+	switch {
+	case xfo == "" && !frameAncestors:
+		return []Finding{{
+			Severity:       SeverityMedium,
+			Title:          "No clickjacking protection",
+			Detail:         "Neither X-Frame-Options nor CSP frame-ancestors is set, so any site can frame this page.",
+			Recommendation: "Content-Security-Policy: frame-ancestors 'self' (and X-Frame-Options: SAMEORIGIN for old browsers)",
+		}}
+	case xfo == "" || xfo == "DENY" || xfo == "SAMEORIGIN":
+		return nil
+	case strings.HasPrefix(xfo, "ALLOW-FROM"):
+		if frameAncestors {
+			return nil
+		}
+		return []Finding{{
+			Severity:       SeverityLow,
+			Title:          "X-Frame-Options ALLOW-FROM is not supported",
+			Detail:         "Current browsers ignore ALLOW-FROM, leaving the page frameable by anyone.",
+			Recommendation: "Content-Security-Policy: frame-ancestors 'self' https://partner.example",
+		}}
+	}
+	if frameAncestors {
+		return nil
+	}
+	return []Finding{{
+		Severity:       SeverityMedium,
+		Title:          "Invalid X-Frame-Options value",
+		Detail:         fmt.Sprintf("%q is not DENY or SAMEORIGIN and is ignored.", h.Get("X-Frame-Options")),
+		Recommendation: "X-Frame-Options: SAMEORIGIN",
+	}}
+}
+
+func nosniffFindings(value string) []Finding {
+	if strings.EqualFold(strings.TrimSpace(value), "nosniff") {
+		return nil
+	}
+	title := "X-Content-Type-Options missing"
+	if value != "" {
+		title = "Invalid X-Content-Type-Options value"
+	}
+	return []Finding{{
+		Severity:       SeverityLow,
+		Title:          title,
+		Detail:         "Browsers may MIME-sniff responses and execute uploaded content as script.",
+		Recommendation: "X-Content-Type-Options: nosniff",
+	}}
+}
+
+var referrerPolicies = map[string]bool{
+	"no-referrer": true, "no-referrer-when-downgrade": true, "origin": true,
+	"origin-when-cross-origin": true, "same-origin": true, "strict-origin": true,
+	"strict-origin-when-cross-origin": true, "unsafe-url": true,
+}
+
+func referrerFindings(values []string) []Finding {
+	const snippet = "Referrer-Policy: strict-origin-when-cross-origin"
+
+	// The last policy the browser recognises wins
+	effective := ""
+	for _, value := range values {
+		for _, token := range strings.Split(value, ",") {
+			if token = strings.ToLower(strings.TrimSpace(token)); referrerPolicies[token] {
+				effective = token
+			}
+		}
+	}
+
+	switch {
+	case len(values) == 0:
+		return []Finding{{
+			Severity:       SeverityLow,
+			Title:          "Referrer-Policy missing",
+			Detail:         "Browsers fall back to their default, which may not match what the site expects.",
+			Recommendation: snippet,
+		}}
+	case effective == "":
+		return []Finding{{
+			Severity:       SeverityLow,
+			Title:          "Invalid Referrer-Policy value",
+			Detail:         fmt.Sprintf("%q contains no recognised policy.", strings.Join(values, ", ")),
+			Recommendation: snippet,
+		}}
+	case effective == "unsafe-url" || effective == "no-referrer-when-downgrade":
+		return []Finding{{
+			Severity:       SeverityLow,
+			Title:          "Referrer-Policy leaks full URLs",
+			Detail:         fmt.Sprintf("%s sends the full URL, including path and query, to other origins.", effective),
+			Recommendation: snippet,
+		}}
+	}
+	return nil
+}
+
+func permissionsFindings(h http.Header) []Finding {
+	const snippet = "Permissions-Policy: camera=(), microphone=(), geolocation=(), payment=()"
+
+	value := strings.Join(h.Values("Permissions-Policy"), ", ")
+	if value == "" {
+		return []Finding{{
+			Severity:       SeverityLow,
+			Title:          "Permissions-Policy missing",
+			Detail:         "Powerful browser features are available to the page and every embedded frame.",
+			Recommendation: snippet,
+		}}
+	}
+
+	// Structured-field dictionary: feature=(allowlist), feature=*, ...
+	var invalid []string
+	for _, member := range strings.Split(value, ",") {
+		member = strings.TrimSpace(member)
+		if member == "" {
+			continue
+		}
+		name, allow, ok := strings.Cut(member, "=")
+		allow = strings.TrimSpace(allow)
+		if !ok || strings.TrimSpace(name) == "" || (allow != "*" && !strings.HasPrefix(allow, "(") && !strings.HasPrefix(allow, "self")) {
+			invalid = append(invalid, member)
+		}
+	}
+	if len(invalid) > 0 {
+		return []Finding{{
+			Severity:       SeverityLow,
+			Title:          "Malformed Permissions-Policy",
+			Detail:         fmt.Sprintf("Browsers drop the whole header on syntax errors; invalid entries: %s. Note the syntax differs from Feature-Policy.", strings.Join(invalid, "; ")),
+			Recommendation: snippet,
+		}}
+	}
+	return nil
+}
+
+// crossOriginPolicies lists the valid values of each cross-origin isolation header
+// and the snippet recommended when it is missing or invalid
+var crossOriginPolicies = []struct {
+	header  string
+	valid   []string
+	snippet string
+}{
+	{"Cross-Origin-Opener-Policy", []string{"same-origin", "same-origin-allow-popups", "noopener-allow-popups", "unsafe-none"}, "Cross-Origin-Opener-Policy: same-origin"},
+	{"Cross-Origin-Embedder-Policy", []string{"require-corp", "credentialless", "unsafe-none"}, "Cross-Origin-Embedder-Policy: require-corp"},
+	{"Cross-Origin-Resource-Policy", []string{"same-origin", "same-site", "cross-origin"}, "Cross-Origin-Resource-Policy: same-origin"},
+}
+
+func crossOriginFindings(h http.Header) []Finding {
+	var findings []Finding
+	for _, p := range crossOriginPolicies {
+		raw := h.Get(p.header)
+		// COOP/COEP may carry parameters such as report-to
+		value, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(raw)), ";")
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+		switch {
+		case raw == "":
+			findings = append(findings, Finding{
+				Severity:       SeverityInfo,
+				Title:          p.header + " missing",
+				Recommendation: p.snippet,
+			})
+		case !slices.Contains(p.valid, value):
+			findings = append(findings, Finding{
+				Severity:       SeverityLow,
+				Title:          "Invalid " + p.header + " value",
+				Detail:         fmt.Sprintf("%q is not one of %s.", raw, strings.Join(p.valid, ", ")),
+				Recommendation: p.snippet,
+			})
+		case p.header == "Cross-Origin-Opener-Policy" && value == "unsafe-none":
+			findings = append(findings, Finding{
+				Severity:       SeverityInfo,
+				Title:          "Cross-Origin-Opener-Policy explicitly disabled",
+				Detail:         "Cross-origin windows keep a reference to this page (XS-Leaks, tabnabbing).",
+				Recommendation: p.snippet,
+			})
+		}
+	}
+	return findings
+}
+
+func deprecatedHeaderFindings(h http.Header) []Finding {
+	var findings []Finding
+	if xss := strings.TrimSpace(h.Get("X-XSS-Protection")); xss != "" && xss != "0" {
+		findings = append(findings, Finding{
+			Severity:       SeverityLow,
+			Title:          "X-XSS-Protection enabled",
+			Detail:         "The XSS auditor was removed from browsers and could itself be abused to leak data.",
+			Recommendation: "X-XSS-Protection: 0 (or drop the header) and rely on Content-Security-Policy.",
+		})
+	}
+	if h.Get("Public-Key-Pins") != "" {
+		findings = append(findings, Finding{
+			Severity:       SeverityLow,
+			Title:          "Deprecated Public-Key-Pins header",
+			Detail:         "HPKP is no longer supported and risks locking users out in older clients.",
+			Recommendation: "Remove Public-Key-Pins.",
+		})
+	}
+	if h.Get("Expect-CT") != "" {
+		findings = append(findings, Finding{
+			Severity:       SeverityInfo,
+			Title:          "Deprecated Expect-CT header",
+			Detail:         "Certificate Transparency is enforced by browsers without it.",
+			Recommendation: "Remove Expect-CT.",
+		})
+	}
+	if h.Get("Feature-Policy") != "" {
+		findings = append(findings, Finding{
+			Severity:       SeverityInfo,
+			Title:          "Deprecated Feature-Policy header",
+			Detail:         "Feature-Policy was replaced by Permissions-Policy, which uses a different syntax.",
+			Recommendation: "Permissions-Policy: camera=(), microphone=(), geolocation=()",
+		})
+	}
+	return findings
+}
@@ -0,0 +1,96 @@
+package scanner
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestParseHSTS(t *testing.T) {
+	tests := []struct {
+		value             string
+		maxAge            int
+		includeSubDomains bool
+		preload           bool
+	}{
+		{"", -1, false, false},
+		{"max-age=31536000", 31536000, false, false},
+		{"max-age=63072000; includeSubDomains; preload", 63072000, true, true},
+		{"MAX-AGE=300;INCLUDESUBDOMAINS", 300, true, false},
+		{` max-age = "86400" ; preload`, 86400, false, true},
+		{"max-age=0", 0, false, false},
+		{"max-age=-5; includeSubDomains", -1, true, false},
+		{"max-age=forever", -1, false, false},
+		{"max-age", -1, false, false},
+		{"includeSubDomains; preload", -1, true, true},
+	}
+	for _, tt := range tests {
+		maxAge, sub, preload := parseHSTS(tt.value)
+		if maxAge != tt.maxAge || sub != tt.includeSubDomains || preload != tt.preload {
+			t.Errorf("parseHSTS(%q) = %d, %t, %t, want %d, %t, %t", tt.value, maxAge, sub, preload, tt.maxAge, tt.includeSubDomains, tt.preload)
+		}
+	}
+}
+
+func TestHSTSFindings(t *testing.T) {
+	tests := []struct {
+		value string
+		https bool
+		want  []string
+	}{
+		{"max-age=31536000; includeSubDomains", true, nil},
+		{"max-age=31536000; includeSubDomains", false, []string{"Page not served over HTTPS"}},
+		{"", true, []string{"Strict-Transport-Security missing"}},
+		{"max-age=31536000", true, []string{"Strict-Transport-Security without includeSubDomains"}},
+		{"max-age=86400; includeSubDomains", true, []string{"Strict-Transport-Security max-age too short"}},
+		{"max-age=0", true, []string{"Strict-Transport-Security disabled with max-age=0"}},
+		{"includeSubDomains", true, []string{"Strict-Transport-Security has no valid max-age"}},
+	}
+	for _, tt := range tests {
+		if got := findingTitles(hstsFindings(tt.value, tt.https)); !slices.Equal(got, tt.want) {
+			t.Errorf("hstsFindings(%q, %t) = %q, want %q", tt.value, tt.https, got, tt.want)
+		}
+	}
+}
+
+func TestFrameFindings(t *testing.T) {
+	tests := []struct {
+		name    string
+		xfo     string
+		csp     string
+		finding string
+	}{
+		{"nothing set", "", "", "No clickjacking protection"},
+		{"DENY", "DENY", "", ""},
+		{"sameorigin lower case", " sameorigin ", "", ""},
+		{"frame-ancestors only", "", "frame-ancestors 'self'", ""},
+		{"other CSP directives only", "", "default-src 'self'", "No clickjacking protection"},
+		{"ALLOW-FROM", "ALLOW-FROM https://partner.example", "", "X-Frame-Options ALLOW-FROM is not supported"},
+		{"ALLOW-FROM with frame-ancestors", "ALLOW-FROM https://partner.example", "frame-ancestors https://partner.example", ""},
+		{"invalid value", "ALLOWALL", "", "Invalid X-Frame-Options value"},
+		{"invalid value with frame-ancestors", "ALLOWALL", "frame-ancestors 'none'", ""},
+	}
+	for _, tt := range tests {
+		h := http.Header{}
+		if tt.xfo != "" {
+			h.Set("X-Frame-Options", tt.xfo)
+		}
+		if tt.csp != "" {
+			h.Set("Content-Security-Policy", tt.csp)
+		}
+		var want []string
+		if tt.finding != "" {
+			want = []string{tt.finding}
+		}
+		if got := findingTitles(frameFindings(h)); !slices.Equal(got, want) {
+			t.Errorf("%s: frameFindings = %q, want %q", tt.name, got, want)
+		}
+	}
+
+	// A report-only policy does not stop framing
+	h := http.Header{}
+	h.Set("Content-Security-Policy-Report-Only", "frame-ancestors 'self'")
+	if got := findingTitles(frameFindings(h)); !slices.Equal(got, []string{"No clickjacking protection"}) {
+		t.Errorf("report-only frame-ancestors: frameFindings = %q", got)
+	}
+}
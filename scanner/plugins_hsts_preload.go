@@ -0,0 +1,217 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"net"
+	neturl "net/url"
+	"strings"
+	"time"
+)
+
+// hstsPreloadMaxAge is the minimum max-age the preload list accepts (one year)
+const hstsPreloadMaxAge = 31536000
+
+// preloadHeader is the header the preload list asks for
+const preloadHeader = "Strict-Transport-Security: max-age=63072000; includeSubDomains; preload"
+
+// preloadRequirement is one of the hstspreload.org submission requirements. Status
+// is "pass", "fail" or "n/a"; Detail explains a failure.
+type preloadRequirement struct {
+	Name   string
+	Status string
+	Detail string
+}
+
+func init() {
+	RegisterCheck("hsts_preload", "Checks the base domain against the HSTS preload list submission requirements", checkHSTSPreloadPlugin)
+}
+
+func checkHSTSPreloadPlugin(ctx context.Context, url string) interface{} {
+	u, err := neturl.Parse(url)
+	if err != nil || u.Hostname() == "" {
+		return map[string]string{"error": "Invalid URL"}
+	}
+	if net.ParseIP(u.Hostname()) != nil {
+		return map[string]string{"error": "HSTS preload applies to domain names, not IP addresses"}
+	}
+	// Preloading is requested for the registrable domain and covers every subdomain
+	domain := registrableDomain(u.Hostname())
+	if !TargetInScope(ctx, domain) {
+		return map[string]string{"error": "Base domain " + domain + " is out of scope"}
+	}
+
+	httpsChain := redirectChain(ctx, "https://"+domain)
+	httpChain := redirectChain(ctx, "http://"+domain)
+	if len(httpsChain.Hops) == 0 && len(httpChain.Hops) == 0 {
+		return map[string]string{"error": "Failed to reach " + domain}
+	}
+
+	requirements := []preloadRequirement{preloadCertificate(ctx, domain, "Valid certificate")}
+	requirements = append(requirements, preloadHTTPRedirect(domain, httpChain))
+	requirements = append(requirements, preloadHeaderRequirements(domain, httpsChain)...)
+	requirements = append(requirements, preloadWWW(ctx, domain))
+
+	result := map[string]interface{}{"Domain": domain}
+	statuses := make(map[string]string)
+	eligible := true
+	var findings []Finding
+	for _, r := range requirements {
+		statuses[r.Name] = r.Status
+		if r.Status != "fail" {
+			continue
+		}
+		eligible = false
+		findings = append(findings, Finding{
+			Severity:       SeverityMedium,
+			Title:          "HSTS preload requirement not met: " + r.Name,
+			Detail:         r.Detail,
+			Recommendation: preloadRecommendation(r.Name),
+		})
+	}
+	result["Requirements"] = statuses
+	result["Eligible"] = fmt.Sprintf("%t", eligible)
+	if len(httpsChain.Hops) > 0 {
+		if value := httpsChain.Hops[0].Header.Get("Strict-Transport-Security"); value != "" {
+			result["HSTS Header"] = value
+		}
+	}
+
+	if len(findings) > 0 {
+		sortFindings(findings)
+		result["findings"] = findings
+	}
+	return result
+}
+
+// preloadCertificate requires a trusted, unexpired certificate covering host
+func preloadCertificate(ctx context.Context, host, name string) preloadRequirement {
+	r := preloadRequirement{Name: name, Status: "fail"}
+	state, err := peerTLSState(ctx, net.JoinHostPort(host, "443"))
+	if err != nil || len(state.PeerCertificates) == 0 {
+		r.Detail = "No TLS handshake with " + host + " on port 443."
+		return r
+	}
+	trusted, hostnameOK, _ := verifyChain(state.PeerCertificates, host, time.Now())
+	switch {
+	case !trusted:
+		r.Detail = "The certificate for " + host + " does not chain to a trusted root or is expired."
+	case !hostnameOK:
+		r.Detail = "The certificate does not cover " + host + "."
+	default:
+		r.Status = "pass"
+	}
+	return r
+}
+
+// preloadHTTPRedirect requires plain HTTP, when served, to redirect to HTTPS on the
+// same host before going anywhere else
+func preloadHTTPRedirect(domain string, c *chain) preloadRequirement {
+	r := preloadRequirement{Name: "HTTP redirects to HTTPS on the same host", Status: "fail"}
+	if len(c.Hops) == 0 {
+		r.Status = "n/a"
+		return r
+	}
+	first := c.Hops[0]
+	loc, err := neturl.Parse(first.Location)
+	switch {
+	case first.Location == "" || err != nil:
+		r.Detail = fmt.Sprintf("http://%s answers %d without redirecting.", domain, first.Status)
+	case loc.Scheme != "https":
+		r.Detail = "http://" + domain + " redirects to " + first.Location + " instead of HTTPS."
+	case !strings.EqualFold(loc.Host, domain):
+		r.Detail = "http://" + domain + " redirects to " + first.Location + "; the first redirect must go to https://" + domain + "."
+	default:
+		r.Status = "pass"
+	}
+	return r
+}
+
+// preloadHeaderRequirements checks the HSTS header served by https://domain itself.
+// When that response is a redirect the header must be on the redirect, not only on
+// the page it leads to.
+func preloadHeaderRequirements(domain string, c *chain) []preloadRequirement {
+	present := preloadRequirement{Name: "HSTS on the base domain", Status: "fail"}
+	maxAge := preloadRequirement{Name: "max-age of at least one year", Status: "fail"}
+	subdomains := preloadRequirement{Name: "includeSubDomains directive", Status: "fail"}
+	preload := preloadRequirement{Name: "preload directive", Status: "fail"}
+	onRedirect := preloadRequirement{Name: "HSTS on the HTTPS redirect", Status: "n/a"}
+
+	if len(c.Hops) == 0 {
+		present.Detail = "https://" + domain + " did not respond."
+		maxAge.Status, subdomains.Status, preload.Status = "n/a", "n/a", "n/a"
+		return []preloadRequirement{present, maxAge, subdomains, preload, onRedirect}
+	}
+
+	first := c.Hops[0]
+	values := first.Header.Values("Strict-Transport-Security")
+	if first.Location != "" {
+		onRedirect.Status = "pass"
+		if len(values) == 0 {
+			onRedirect.Status = "fail"
+			onRedirect.Detail = "https://" + domain + " redirects to " + first.Location + " without sending Strict-Transport-Security."
+			// Still grade the directives on the page the redirect leads to
+			if last := c.Hops[len(c.Hops)-1]; strings.HasPrefix(last.URL, "https://") {
+				values = last.Header.Values("Strict-Transport-Security")
+			}
+		}
+	}
+
+	switch {
+	case len(values) == 0:
+		present.Detail = "https://" + domain + " does not send Strict-Transport-Security."
+		maxAge.Status, subdomains.Status, preload.Status = "n/a", "n/a", "n/a"
+		return []preloadRequirement{present, maxAge, subdomains, preload, onRedirect}
+	case len(values) > 1:
+		present.Detail = fmt.Sprintf("%d Strict-Transport-Security headers are sent; exactly one is allowed.", len(values))
+	default:
+		present.Status = "pass"
+	}
+
+	seconds, includeSubDomains, hasPreload := parseHSTS(values[0])
+	switch {
+	case seconds < 0:
+		maxAge.Detail = fmt.Sprintf("%q has no valid max-age.", values[0])
+	case seconds < hstsPreloadMaxAge:
+		maxAge.Detail = fmt.Sprintf("max-age is %d seconds (%d days); at least 31536000 (one year) is required.", seconds, seconds/86400)
+	default:
+		maxAge.Status = "pass"
+	}
+	if includeSubDomains {
+		subdomains.Status = "pass"
+	} else {
+		subdomains.Detail = "The header lacks includeSubDomains."
+	}
+	if hasPreload {
+		preload.Status = "pass"
+	} else {
+		preload.Detail = "The header lacks the preload directive."
+	}
+	return []preloadRequirement{present, maxAge, subdomains, preload, onRedirect}
+}
+
+// preloadWWW requires the www subdomain, when it exists, to serve HTTPS with a valid
+// certificate; preloading with includeSubDomains would otherwise lock visitors out
+func preloadWWW(ctx context.Context, domain string) preloadRequirement {
+	www := "www." + domain
+	name := "HTTPS on " + www
+	if !TargetInScope(ctx, www) {
+		return preloadRequirement{Name: name, Status: "n/a"}
+	}
+	if len(redirectChain(ctx, "http://"+www).Hops) == 0 && len(redirectChain(ctx, "https://"+www).Hops) == 0 {
+		return preloadRequirement{Name: name, Status: "n/a"}
+	}
+	return preloadCertificate(ctx, www, name)
+}
+
+func preloadRecommendation(name string) string {
+	switch {
+	case name == "Valid certificate" || strings.HasPrefix(name, "HTTPS on "):
+		return "Serve a publicly trusted certificate that covers the host."
+	case name == "HTTP redirects to HTTPS on the same host":
+		return "Redirect http:// to the same host on https:// first; redirect to www (if wanted) afterwards."
+	case name == "HSTS on the HTTPS redirect":
+		return "Send the HSTS header on the redirect response as well: " + preloadHeader
+	}
+	return preloadHeader
+}
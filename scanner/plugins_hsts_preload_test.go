@@ -0,0 +1,109 @@
+package scanner
+
+import (
+	"net/http"
+	"testing"
+)
+
+// withHSTS sets the Strict-Transport-Security values on hop i of c
+func withHSTS(c *chain, i int, values ...string) *chain {
+	c.Hops[i].Header = http.Header{}
+	for _, v := range values {
+		c.Hops[i].Header.Add("Strict-Transport-Security", v)
+	}
+	return c
+}
+
+func requirementStatuses(requirements ...preloadRequirement) map[string]string {
+	statuses := make(map[string]string)
+	for _, r := range requirements {
+		statuses[r.Name] = r.Status
+		if r.Status == "fail" && r.Detail == "" {
+			statuses[r.Name] = "fail without detail"
+		}
+	}
+	return statuses
+}
+
+func TestPreloadHTTPRedirect(t *testing.T) {
+	tests := []struct {
+		name  string
+		chain *chain
+		want  string
+	}{
+		{"same host", hops("301 http://example.com/ -> https://example.com/", "200 https://example.com/"), "pass"},
+		{"host case", hops("308 http://example.com/ -> https://EXAMPLE.com/"), "pass"},
+		{"no redirect", hops("200 http://example.com/"), "fail"},
+		{"stays on http", hops("301 http://example.com/ -> http://www.example.com/"), "fail"},
+		{"straight to www", hops("301 http://example.com/ -> https://www.example.com/"), "fail"},
+		{"http not served", &chain{}, "n/a"},
+	}
+	for _, tt := range tests {
+		r := preloadHTTPRedirect("example.com", tt.chain)
+		if got := requirementStatuses(r)[r.Name]; got != tt.want {
+			t.Errorf("%s: status %q (%s), want %q", tt.name, got, r.Detail, tt.want)
+		}
+	}
+}
+
+func TestPreloadHeaderRequirements(t *testing.T) {
+	const (
+		present    = "HSTS on the base domain"
+		maxAge     = "max-age of at least one year"
+		subdomains = "includeSubDomains directive"
+		preload    = "preload directive"
+		onRedirect = "HSTS on the HTTPS redirect"
+	)
+	page := func(values ...string) *chain {
+		return withHSTS(hops("200 https://example.com/"), 0, values...)
+	}
+
+	tests := []struct {
+		name  string
+		chain *chain
+		want  map[string]string
+	}{
+		{"eligible", page("max-age=63072000; includeSubDomains; preload"), map[string]string{
+			present: "pass", maxAge: "pass", subdomains: "pass", preload: "pass", onRedirect: "n/a",
+		}},
+		{"exactly one year", page("max-age=31536000; includeSubDomains; preload"), map[string]string{
+			present: "pass", maxAge: "pass", subdomains: "pass", preload: "pass", onRedirect: "n/a",
+		}},
+		{"short max-age", page("max-age=86400; includeSubDomains; preload"), map[string]string{
+			present: "pass", maxAge: "fail", subdomains: "pass", preload: "pass", onRedirect: "n/a",
+		}},
+		{"invalid max-age", page("max-age=forever; includeSubDomains; preload"), map[string]string{
+			present: "pass", maxAge: "fail", subdomains: "pass", preload: "pass", onRedirect: "n/a",
+		}},
+		{"missing directives", page("max-age=63072000"), map[string]string{
+			present: "pass", maxAge: "pass", subdomains: "fail", preload: "fail", onRedirect: "n/a",
+		}},
+		{"two headers", page("max-age=63072000; includeSubDomains; preload", "max-age=0"), map[string]string{
+			present: "fail", maxAge: "pass", subdomains: "pass", preload: "pass", onRedirect: "n/a",
+		}},
+		{"no header", page(), map[string]string{
+			present: "fail", maxAge: "n/a", subdomains: "n/a", preload: "n/a", onRedirect: "n/a",
+		}},
+		{"not served", &chain{}, map[string]string{
+			present: "fail", maxAge: "n/a", subdomains: "n/a", preload: "n/a", onRedirect: "n/a",
+		}},
+		{"header on the redirect", withHSTS(hops("301 https://example.com/ -> https://www.example.com/", "200 https://www.example.com/"), 0, "max-age=63072000; includeSubDomains; preload"), map[string]string{
+			present: "pass", maxAge: "pass", subdomains: "pass", preload: "pass", onRedirect: "pass",
+		}},
+		// The directives are still graded from the page the redirect leads to
+		{"header only after the redirect", withHSTS(withHSTS(hops("301 https://example.com/ -> https://www.example.com/", "200 https://www.example.com/"), 0), 1, "max-age=63072000; preload"), map[string]string{
+			present: "pass", maxAge: "pass", subdomains: "fail", preload: "pass", onRedirect: "fail",
+		}},
+	}
+	for _, tt := range tests {
+		got := requirementStatuses(preloadHeaderRequirements("example.com", tt.chain)...)
+		for name, want := range tt.want {
+			if got[name] != want {
+				t.Errorf("%s: %s = %q, want %q", tt.name, name, got[name], want)
+			}
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: requirements = %v", tt.name, got)
+		}
+	}
+}
@@ -2,8 +2,9 @@ package scanner
 
 import (
 	"context"
-	"fmt"
 	"net"
+	"sort"
+	"strconv"
 	"sync"
 	"time"
 )
@@ -19,10 +20,15 @@ var commonPorts = []int{
 	143,  // IMAP
 	443,  // HTTPS
 	445,  // SMB
+	465,  // SMTPS
+	587,  // SMTP submission
+	993,  // IMAPS
+	995,  // POP3S
 	3306, // MySQL
 	5432, // PostgreSQL
 	6379, // Redis
 	8080, // HTTP Alt
+	8443, // HTTPS Alt
 }
 
 func init() {
@@ -36,32 +42,43 @@ func checkPortsPlugin(ctx context.Context, url string) interface{} {
 	}
 
 	var exposed []string
-	var wg sync.WaitGroup
-	var mu sync.Mutex
+	for _, p := range openPorts(ctx, domain) {
+		exposed = append(exposed, strconv.Itoa(p))
+	}
+	return exposed
+}
 
-	dialer := &net.Dialer{}
+// openPorts connects to each of the common ports of domain and returns the open ones
+// in ascending order. The result is shared by all checks of the same scan.
+func openPorts(ctx context.Context, domain string) []int {
+	return memoize(ctx, "open_ports:"+domain, func() interface{} {
+		var open []int
+		var wg sync.WaitGroup
+		var mu sync.Mutex
 
-	for _, port := range commonPorts {
-		wg.Add(1)
-		go func(p int) {
-			defer wg.Done()
-			target := fmt.Sprintf("%s:%d", domain, p)
+		for _, port := range commonPorts {
+			wg.Add(1)
+			go func(p int) {
+				defer wg.Done()
+				target := net.JoinHostPort(domain, strconv.Itoa(p))
 
-			// Vercel Egress Safety: Super fast 1-second timeout per port.
-			// If it hangs here, the global context timeout will also catch it.
-			timeoutCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
-			defer cancel()
+				// Vercel Egress Safety: Super fast 1-second timeout per port.
+				// If it hangs here, the global context timeout will also catch it.
+				timeoutCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+				defer cancel()
 
-			conn, err := dialer.DialContext(timeoutCtx, "tcp", target)
-			if err == nil {
-				conn.Close()
-				mu.Lock()
-				exposed = append(exposed, fmt.Sprintf("%d", p))
-				mu.Unlock()
-			}
-		}(port)
-	}
+				conn, err := scanDial(timeoutCtx, "tcp", target)
+				if err == nil {
+					conn.Close()
+					mu.Lock()
+					open = append(open, p)
+					mu.Unlock()
+				}
+			}(port)
+		}
 
-	wg.Wait()
-	return exposed
+		wg.Wait()
+		sort.Ints(open)
+		return open
+	}).([]int)
 }
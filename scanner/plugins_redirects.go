@@ -0,0 +1,300 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"net"
+	neturl "net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// longChainHops is the chain length above which an extra round trip is reported
+const longChainHops = 3
+
+func init() {
+	RegisterCheck("redirects", "Follows redirect chains for http/https and www/apex variants and checks HTTPS enforcement", checkRedirectsPlugin)
+}
+
+// landingBase is url rewritten to the scheme and host the target finally redirects
+// to, so path-based checks probe the real site rather than a redirect. Redirects to
+// other sites (SSO, parking pages) are not followed.
+func landingBase(ctx context.Context, url string) string {
+	c := redirectChain(ctx, url)
+	if c.Err != nil {
+		return url
+	}
+	start, err1 := neturl.Parse(url)
+	final, err2 := neturl.Parse(c.Final())
+	if err1 != nil || err2 != nil || final.Host == "" {
+		return url
+	}
+	if registrableDomain(final.Hostname()) != registrableDomain(start.Hostname()) {
+		return url
+	}
+	return final.Scheme + "://" + final.Host + strings.TrimRight(start.Path, "/")
+}
+
+// redirectStarts lists the URLs whose chains are compared: url itself, the other
+// scheme, and both schemes of the www/apex counterpart when it is in scope. Targets
+// with an explicit port are only followed as given.
+func redirectStarts(ctx context.Context, u *neturl.URL) []string {
+	starts := []string{u.String()}
+	if u.Port() != "" {
+		return starts
+	}
+
+	other := "https"
+	if u.Scheme == "https" {
+		other = "http"
+	}
+	starts = append(starts, other+"://"+u.Host+u.Path)
+
+	host := u.Hostname()
+	if net.ParseIP(host) != nil {
+		return starts
+	}
+	apex := registrableDomain(host)
+	var alt string
+	switch host {
+	case apex:
+		alt = "www." + apex
+	case "www." + apex:
+		alt = apex
+	}
+	if alt != "" && TargetInScope(ctx, alt) {
+		starts = append(starts, "http://"+alt, "https://"+alt)
+	}
+	return starts
+}
+
+func checkRedirectsPlugin(ctx context.Context, url string) interface{} {
+	u, err := neturl.Parse(url)
+	if err != nil || u.Hostname() == "" {
+		return map[string]string{"error": "Invalid URL"}
+	}
+
+	starts := redirectStarts(ctx, u)
+	chains := make([]*chain, len(starts))
+	var wg sync.WaitGroup
+	for i, start := range starts {
+		wg.Add(1)
+		go func(i int, start string) {
+			defer wg.Done()
+			chains[i] = redirectChain(ctx, start)
+		}(i, start)
+	}
+	wg.Wait()
+
+	if len(chains[0].Hops) == 0 {
+		return map[string]string{"error": "Failed to reach host"}
+	}
+
+	reported := make(map[string]interface{})
+	for i, start := range starts {
+		reported[start] = describeChain(chains[i])
+	}
+	result := map[string]interface{}{
+		"Final URL": chains[0].Final(),
+		"Chains":    reported,
+	}
+
+	if findings := redirectFindings(u, starts, chains); len(findings) > 0 {
+		sortFindings(findings)
+		result["findings"] = findings
+	}
+	return result
+}
+
+// describeChain renders a chain as "status url -> location" lines. Timings are kept
+// apart because they differ on every run.
+func describeChain(c *chain) map[string]interface{} {
+	var hops, timing []string
+	for _, h := range c.Hops {
+		line := fmt.Sprintf("%d %s", h.Status, h.URL)
+		if h.Location != "" {
+			line += " -> " + h.Location
+		}
+		hops = append(hops, line)
+		timing = append(timing, h.Duration.Round(time.Millisecond).String())
+	}
+	described := map[string]interface{}{
+		"Hops":      hops,
+		"Final URL": c.Final(),
+		"Timing":    timing,
+	}
+	if c.Err != nil {
+		described["Error"] = c.Err.Error()
+	}
+	return described
+}
+
+func redirectFindings(u *neturl.URL, starts []string, chains []*chain) []Finding {
+	var findings []Finding
+	site := registrableDomain(u.Hostname())
+
+	var notUpgraded, temporary, downgrades, loops, tooLong, offsite, slow []string
+	httpsTried, httpsReachable := false, false
+	for i, start := range starts {
+		c := chains[i]
+		if strings.HasPrefix(start, "https://") {
+			httpsTried = true
+			httpsReachable = httpsReachable || (c.Err == nil && len(c.Hops) > 0)
+		}
+		if len(c.Hops) == 0 {
+			continue
+		}
+
+		switch {
+		case c.Loop:
+			loops = append(loops, start)
+		case c.Err != nil && len(c.Hops) > maxRedirects:
+			tooLong = append(tooLong, start)
+		}
+
+		if strings.HasPrefix(start, "http://") && c.Err == nil {
+			if strings.HasPrefix(c.Final(), "http://") {
+				notUpgraded = append(notUpgraded, start)
+			}
+			// Judge the hop that actually switches to https
+			for _, h := range c.Hops {
+				if strings.HasPrefix(h.URL, "http://") && strings.HasPrefix(h.Location, "https://") {
+					if h.Status != 301 && h.Status != 308 {
+						temporary = append(temporary, fmt.Sprintf("%s (%d)", h.URL, h.Status))
+					}
+					break
+				}
+			}
+		}
+
+		for _, h := range c.Hops {
+			if strings.HasPrefix(h.URL, "https://") && strings.HasPrefix(h.Location, "http://") {
+				downgrades = append(downgrades, h.URL+" -> "+h.Location)
+			}
+			if loc, err := neturl.Parse(h.Location); err == nil && loc.Hostname() != "" && registrableDomain(loc.Hostname()) != site {
+				offsite = append(offsite, h.URL+" -> "+h.Location)
+			}
+		}
+		// A chain that never ended is already reported as a loop or as too many redirects
+		if c.Err == nil && len(c.Hops) > longChainHops+1 {
+			var total int64
+			for _, h := range c.Hops {
+				total += h.Duration.Milliseconds()
+			}
+			slow = append(slow, fmt.Sprintf("%s (%d redirects, %dms)", start, len(c.Hops)-1, total))
+		}
+	}
+
+	if len(downgrades) > 0 {
+		findings = append(findings, Finding{
+			Severity:       SeverityHigh,
+			Title:          "HTTPS redirects to plain HTTP",
+			Detail:         strings.Join(dedupe(downgrades), "; "),
+			Recommendation: "Keep every redirect on https:// so traffic is never downgraded.",
+		})
+	}
+	if len(notUpgraded) > 0 && httpsTried {
+		if httpsReachable {
+			findings = append(findings, Finding{
+				Severity:       SeverityMedium,
+				Title:          "HTTP is not redirected to HTTPS",
+				Detail:         strings.Join(notUpgraded, ", ") + " is served over plain HTTP although HTTPS is available.",
+				Recommendation: "Redirect all HTTP requests to the same path on https:// with a 301, and enable HSTS.",
+			})
+		} else {
+			findings = append(findings, Finding{
+				Severity:       SeverityHigh,
+				Title:          "Site is not available over HTTPS",
+				Detail:         strings.Join(notUpgraded, ", ") + " is only served over plain HTTP.",
+				Recommendation: "Serve the site over HTTPS and redirect HTTP to it.",
+			})
+		}
+	}
+	if len(temporary) > 0 {
+		findings = append(findings, Finding{
+			Severity:       SeverityLow,
+			Title:          "HTTP to HTTPS upgrade uses a temporary redirect",
+			Detail:         strings.Join(temporary, ", "),
+			Recommendation: "Use 301 or 308 so clients and search engines remember the HTTPS location.",
+		})
+	}
+	if len(loops) > 0 {
+		findings = append(findings, Finding{
+			Severity:       SeverityMedium,
+			Title:          "Redirect loop",
+			Detail:         "Following redirects from " + strings.Join(loops, ", ") + " returns to an earlier URL.",
+			Recommendation: "Fix the redirect rules so every chain ends on a page.",
+		})
+	}
+	if len(tooLong) > 0 {
+		findings = append(findings, Finding{
+			Severity: SeverityLow,
+			Title:    "Too many redirects",
+			Detail:   fmt.Sprintf("%s did not reach a page within %d redirects.", strings.Join(tooLong, ", "), maxRedirects),
+		})
+	}
+	if len(offsite) > 0 {
+		findings = append(findings, Finding{
+			Severity: SeverityInfo,
+			Title:    "Redirects leave the site",
+			Detail:   strings.Join(dedupe(offsite), "; "),
+		})
+	}
+	if len(slow) > 0 {
+		findings = append(findings, Finding{
+			Severity:       SeverityInfo,
+			Title:          "Long redirect chain",
+			Detail:         strings.Join(slow, ", "),
+			Recommendation: "Redirect straight to the canonical HTTPS URL in one hop.",
+		})
+	}
+
+	// www and apex should end up on the same canonical host
+	if len(starts) == 4 {
+		finalHost := func(c *chain) string {
+			if c.Err != nil || len(c.Hops) == 0 {
+				return ""
+			}
+			if f, err := neturl.Parse(c.Final()); err == nil {
+				return f.Host
+			}
+			return ""
+		}
+		own, alt := finalHost(chains[1]), finalHost(chains[3])
+		if u.Scheme == "https" {
+			own = finalHost(chains[0])
+		}
+		altHost := strings.TrimPrefix(starts[3], "https://")
+		switch {
+		case alt == "" && len(chains[2].Hops) == 0 && len(chains[3].Hops) == 0:
+			findings = append(findings, Finding{
+				Severity: SeverityInfo,
+				Title:    altHost + " does not respond",
+				Detail:   "Visitors typing the other www/apex variant get an error.",
+			})
+		case own != "" && alt != "" && own != alt:
+			findings = append(findings, Finding{
+				Severity:       SeverityLow,
+				Title:          "www and apex are not consolidated",
+				Detail:         fmt.Sprintf("%s ends on %s, %s ends on %s.", u.Hostname(), own, altHost, alt),
+				Recommendation: "Redirect one variant to the other so there is a single canonical host.",
+			})
+		}
+	}
+	return findings
+}
+
+func dedupe(list []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, s := range list {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,85 @@
+package scanner
+
+import (
+	"errors"
+	"fmt"
+	neturl "net/url"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// hops builds a chain from "status url [-> location]" lines
+func hops(lines ...string) *chain {
+	c := &chain{}
+	for _, line := range lines {
+		var h hop
+		rest := line
+		if before, after, ok := strings.Cut(line, " -> "); ok {
+			rest, h.Location = before, after
+		}
+		fmt.Sscanf(rest, "%d %s", &h.Status, &h.URL)
+		c.Hops = append(c.Hops, h)
+	}
+	return c
+}
+
+func failed(c *chain, err string) *chain {
+	c.Err = errors.New(err)
+	return c
+}
+
+func TestRedirectFindings(t *testing.T) {
+	secure := hops("200 https://example.com/")
+	upgraded := hops("301 http://example.com/ -> https://example.com/", "200 https://example.com/")
+	wwwUpgraded := hops("301 http://www.example.com/ -> https://example.com/", "200 https://example.com/")
+	wwwToApex := hops("301 https://www.example.com/ -> https://example.com/", "200 https://example.com/")
+
+	looping := hops("302 http://example.com/ -> https://example.com/a", "302 https://example.com/a -> https://example.com/b", "302 https://example.com/b -> https://example.com/a")
+	looping.Loop, looping.Err = true, errors.New("redirect loop")
+	var endless []string
+	for i := 0; i <= maxRedirects; i++ {
+		endless = append(endless, fmt.Sprintf("302 https://example.com/%d -> https://example.com/%d", i, i+1))
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		chains []*chain
+		want   []string
+	}{
+		{"upgraded", "https://example.com", []*chain{secure, upgraded}, nil},
+		{"consolidated www", "https://example.com", []*chain{secure, upgraded, wwwUpgraded, wwwToApex}, nil},
+		{"not upgraded", "https://example.com", []*chain{secure, hops("200 http://example.com/")}, []string{"HTTP is not redirected to HTTPS"}},
+		{"no HTTPS", "http://example.com", []*chain{hops("200 http://example.com/"), failed(&chain{}, "connection refused")}, []string{"Site is not available over HTTPS"}},
+		{"temporary upgrade", "https://example.com", []*chain{secure, hops("302 http://example.com/ -> https://example.com/", "200 https://example.com/")}, []string{"HTTP to HTTPS upgrade uses a temporary redirect"}},
+		{"downgrade", "https://example.com", []*chain{hops("301 https://example.com/ -> http://example.com/home", "200 http://example.com/home"), upgraded}, []string{"HTTPS redirects to plain HTTP"}},
+		{"loop", "https://example.com", []*chain{secure, looping}, []string{"Redirect loop"}},
+		{"too many", "https://example.com", []*chain{failed(hops(endless...), "stopped after 10 redirects"), upgraded}, []string{"Too many redirects"}},
+		{"offsite", "https://example.com", []*chain{hops("302 https://example.com/ -> https://login.example.net/sso", "200 https://login.example.net/sso"), upgraded}, []string{"Redirects leave the site"}},
+		{"long chain", "https://example.com", []*chain{secure, hops(
+			"301 http://example.com/ -> https://example.com/",
+			"301 https://example.com/ -> https://example.com/en",
+			"301 https://example.com/en -> https://example.com/en/",
+			"302 https://example.com/en/ -> https://example.com/en/home",
+			"200 https://example.com/en/home",
+		)}, []string{"Long redirect chain"}},
+		{"www not consolidated", "https://example.com", []*chain{secure, upgraded,
+			hops("301 http://www.example.com/ -> https://www.example.com/", "200 https://www.example.com/"),
+			hops("200 https://www.example.com/"),
+		}, []string{"www and apex are not consolidated"}},
+		{"www down", "https://example.com", []*chain{secure, upgraded, failed(&chain{}, "no such host"), failed(&chain{}, "no such host")}, []string{"www.example.com does not respond"}},
+	}
+	for _, tt := range tests {
+		u, _ := neturl.Parse(tt.target)
+		starts := []string{tt.target, "http://example.com", "http://www.example.com", "https://www.example.com"}[:len(tt.chains)]
+		if u.Scheme == "http" {
+			starts[1] = "https://example.com"
+		}
+		got := findingTitles(redirectFindings(u, starts, tt.chains))
+		slices.Sort(got)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: redirectFindings = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
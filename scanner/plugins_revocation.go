@@ -0,0 +1,238 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/asn1"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// maxCRLSize caps CRL downloads; some CAs publish lists of tens of megabytes
+const maxCRLSize = 10 << 20
+
+// oidMustStaple is the TLS Feature extension (RFC 7633) that requires OCSP stapling
+var oidMustStaple = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 24}
+
+var (
+	revocationMu sync.RWMutex
+	ocspOverride string
+	crlOverride  string
+)
+
+func init() {
+	RegisterCheck("certificate_revocation", "Checks certificate revocation via OCSP (including stapled responses) and CRLs", checkRevocationPlugin)
+}
+
+// SetRevocationEndpoints sends every OCSP request and CRL download to the given URLs
+// instead of the ones named in the certificate, e.g. a local responder in tests or
+// an internal mirror. Empty values restore the certificate's own endpoints.
+func SetRevocationEndpoints(ocspURL, crlURL string) {
+	revocationMu.Lock()
+	defer revocationMu.Unlock()
+	ocspOverride = ocspURL
+	crlOverride = crlURL
+}
+
+func revocationEndpoints(cert *x509.Certificate) (string, string) {
+	revocationMu.RLock()
+	defer revocationMu.RUnlock()
+
+	ocspURL, crlURL := ocspOverride, crlOverride
+	if ocspURL == "" && len(cert.OCSPServer) > 0 {
+		ocspURL = cert.OCSPServer[0]
+	}
+	if crlURL == "" && len(cert.CRLDistributionPoints) > 0 {
+		crlURL = cert.CRLDistributionPoints[0]
+	}
+	return ocspURL, crlURL
+}
+
+func checkRevocationPlugin(ctx context.Context, url string) interface{} {
+	domain, addr := tlsAddress(url)
+	if domain == "" {
+		return map[string]string{"error": "Invalid domain"}
+	}
+
+	state, err := peerTLSState(ctx, addr)
+	if err != nil || len(state.PeerCertificates) == 0 {
+		return map[string]string{"error": "No SSL/TLS on " + addr + " (or timed out)"}
+	}
+	leaf := state.PeerCertificates[0]
+	if len(state.PeerCertificates) < 2 {
+		// Both OCSP and CRL signatures are checked against the issuer
+		return map[string]string{"error": "Server did not send the issuing certificate, revocation cannot be checked"}
+	}
+	issuer := state.PeerCertificates[1]
+
+	ocspURL, crlURL := revocationEndpoints(leaf)
+	result := map[string]interface{}{
+		"OCSP Stapled": fmt.Sprintf("%t", len(state.OCSPResponse) > 0),
+	}
+	var findings []Finding
+
+	// OCSP: prefer the stapled response, fall back to asking the responder
+	var resp *ocsp.Response
+	var ocspErr error
+	switch {
+	case len(state.OCSPResponse) > 0:
+		resp, ocspErr = ocsp.ParseResponseForCert(state.OCSPResponse, leaf, issuer)
+		result["OCSP Source"] = "stapled"
+	case ocspURL != "":
+		resp, ocspErr = queryOCSP(ctx, ocspURL, leaf, issuer)
+		result["OCSP Source"] = ocspURL
+	default:
+		result["OCSP Status"] = "no responder"
+	}
+	if resp != nil {
+		result["OCSP Status"] = ocspStatusName(resp.Status)
+		if resp.Status == ocsp.Revoked {
+			result["OCSP Revoked At"] = resp.RevokedAt.Format(time.RFC822)
+			findings = append(findings, revokedFinding("OCSP", resp.RevokedAt))
+		}
+	} else if ocspErr != nil {
+		result["OCSP Status"] = "error: " + ocspErr.Error()
+	}
+
+	if hasExtension(leaf, oidMustStaple) && len(state.OCSPResponse) == 0 {
+		findings = append(findings, Finding{
+			Severity:       SeverityHigh,
+			Title:          "OCSP Must-Staple certificate without stapled response",
+			Detail:         "The certificate requires OCSP stapling, but the server did not staple a response; browsers that enforce it will refuse the connection.",
+			Recommendation: "Enable OCSP stapling on the server.",
+		})
+	} else if len(state.OCSPResponse) == 0 && ocspURL != "" {
+		findings = append(findings, Finding{
+			Severity:       SeverityInfo,
+			Title:          "OCSP stapling not enabled",
+			Recommendation: "Staple OCSP responses so clients do not have to contact the CA.",
+		})
+	}
+
+	// CRL
+	if crlURL == "" {
+		result["CRL Status"] = "no distribution point"
+	} else {
+		result["CRL URL"] = crlURL
+		revokedAt, nextUpdate, err := checkCRL(ctx, crlURL, leaf, issuer)
+		switch {
+		case err != nil:
+			result["CRL Status"] = "error: " + err.Error()
+		case !revokedAt.IsZero():
+			result["CRL Status"] = "revoked"
+			findings = append(findings, revokedFinding("CRL", revokedAt))
+		default:
+			result["CRL Status"] = "good"
+		}
+		if !nextUpdate.IsZero() {
+			result["CRL Next Update"] = nextUpdate.Format(time.RFC822)
+		}
+	}
+
+	if len(findings) > 0 {
+		sortFindings(findings)
+		result["findings"] = findings
+	}
+	return result
+}
+
+func queryOCSP(ctx context.Context, responder string, leaf, issuer *x509.Certificate) (*ocsp.Response, error) {
+	request, err := ocsp.CreateRequest(leaf, issuer, nil)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, responder, bytes.NewReader(request))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/ocsp-request")
+	req.Header.Set("User-Agent", "URLHawkScanner-Scanner/1.0")
+
+	resp, err := caClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("responder returned %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	return ocsp.ParseResponseForCert(body, leaf, issuer)
+}
+
+// checkCRL downloads the CRL, verifies it was signed by issuer and looks up the leaf's
+// serial. A zero revokedAt means the certificate is not listed.
+func checkCRL(ctx context.Context, crlURL string, leaf, issuer *x509.Certificate) (revokedAt, nextUpdate time.Time, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, crlURL, nil)
+	if err != nil {
+		return revokedAt, nextUpdate, err
+	}
+	req.Header.Set("User-Agent", "URLHawkScanner-Scanner/1.0")
+
+	resp, err := caClient.Do(req)
+	if err != nil {
+		return revokedAt, nextUpdate, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return revokedAt, nextUpdate, fmt.Errorf("CRL download returned %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCRLSize+1))
+	if err != nil {
+		return revokedAt, nextUpdate, err
+	}
+	if len(body) > maxCRLSize {
+		return revokedAt, nextUpdate, fmt.Errorf("CRL larger than %d MB", maxCRLSize>>20)
+	}
+
+	crl, err := x509.ParseRevocationList(body)
+	if err != nil {
+		return revokedAt, nextUpdate, err
+	}
+	if err := crl.CheckSignatureFrom(issuer); err != nil {
+		return revokedAt, crl.NextUpdate, fmt.Errorf("CRL signature invalid: %v", err)
+	}
+	for _, entry := range crl.RevokedCertificateEntries {
+		if entry.SerialNumber.Cmp(leaf.SerialNumber) == 0 {
+			return entry.RevocationTime, crl.NextUpdate, nil
+		}
+	}
+	return revokedAt, crl.NextUpdate, nil
+}
+
+func revokedFinding(source string, at time.Time) Finding {
+	return Finding{
+		Severity:       SeverityCritical,
+		Title:          "Certificate revoked",
+		Detail:         fmt.Sprintf("%s reports the certificate as revoked since %s.", source, at.Format("2006-01-02")),
+		Recommendation: "Deploy a newly issued certificate immediately.",
+	}
+}
+
+func ocspStatusName(status int) string {
+	switch status {
+	case ocsp.Good:
+		return "good"
+	case ocsp.Revoked:
+		return "revoked"
+	}
+	return "unknown"
+}
+
+func hasExtension(cert *x509.Certificate, oid asn1.ObjectIdentifier) bool {
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oid) {
+			return true
+		}
+	}
+	return false
+}
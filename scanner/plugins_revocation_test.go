@@ -0,0 +1,219 @@
+package scanner
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// testPKI is a throwaway CA with one leaf certificate for 127.0.0.1
+type testPKI struct {
+	ca      *x509.Certificate
+	caKey   crypto.Signer
+	leaf    *x509.Certificate
+	leafKey crypto.Signer
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	t.Helper()
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "URLHawk Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, caKey.Public(), caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ := x509.ParseCertificate(caDER)
+
+	leafKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	leafTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(4242),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTemplate, ca, leafKey.Public(), caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, _ := x509.ParseCertificate(leafDER)
+	return &testPKI{ca: ca, caKey: caKey, leaf: leaf, leafKey: leafKey}
+}
+
+// ocspResponse is a response for the leaf signed by the CA
+func (p *testPKI) ocspResponse(t *testing.T, status int) []byte {
+	t.Helper()
+	template := ocsp.Response{
+		Status:       status,
+		SerialNumber: p.leaf.SerialNumber,
+		ThisUpdate:   time.Now().Add(-time.Minute),
+		NextUpdate:   time.Now().Add(time.Hour),
+	}
+	if status == ocsp.Revoked {
+		template.RevokedAt = time.Now().Add(-48 * time.Hour)
+	}
+	der, err := ocsp.CreateResponse(p.ca, p.ca, template, p.caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+// crl lists revoked serials, signed by signer (normally the CA key)
+func (p *testPKI) crl(t *testing.T, signer crypto.Signer, revoked ...*big.Int) []byte {
+	t.Helper()
+	template := &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: time.Now().Add(-time.Minute),
+		NextUpdate: time.Now().Add(time.Hour),
+	}
+	for _, serial := range revoked {
+		template.RevokedCertificateEntries = append(template.RevokedCertificateEntries, x509.RevocationListEntry{
+			SerialNumber:   serial,
+			RevocationTime: time.Now().Add(-48 * time.Hour),
+		})
+	}
+	der, err := x509.CreateRevocationList(rand.Reader, template, p.ca, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+// server serves TLS with the leaf and CA chain, stapling staple when set
+func (p *testPKI) server(t *testing.T, staple []byte) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(http.NotFoundHandler())
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{{
+		Certificate: [][]byte{p.leaf.Raw, p.ca.Raw},
+		PrivateKey:  p.leafKey,
+		OCSPStaple:  staple,
+	}}}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func staticServer(t *testing.T, contentType string, body []byte) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRevocationAgainstLocalResponders(t *testing.T) {
+	pki := newTestPKI(t)
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	tests := []struct {
+		name       string
+		staple     []byte
+		ocsp       []byte
+		crl        []byte
+		ocspStatus string
+		ocspSource string
+		crlStatus  string
+		titles     []string
+	}{
+		{
+			name:       "good",
+			ocsp:       pki.ocspResponse(t, ocsp.Good),
+			crl:        pki.crl(t, pki.caKey, big.NewInt(7)),
+			ocspStatus: "good",
+			crlStatus:  "good",
+			titles:     []string{"OCSP stapling not enabled"},
+		},
+		{
+			name:       "revoked",
+			ocsp:       pki.ocspResponse(t, ocsp.Revoked),
+			crl:        pki.crl(t, pki.caKey, pki.leaf.SerialNumber),
+			ocspStatus: "revoked",
+			crlStatus:  "revoked",
+			titles:     []string{"Certificate revoked", "Certificate revoked", "OCSP stapling not enabled"},
+		},
+		{
+			name:       "stapled revoked",
+			staple:     pki.ocspResponse(t, ocsp.Revoked),
+			ocsp:       pki.ocspResponse(t, ocsp.Good),
+			crl:        pki.crl(t, pki.caKey),
+			ocspStatus: "revoked",
+			ocspSource: "stapled",
+			crlStatus:  "good",
+			titles:     []string{"Certificate revoked"},
+		},
+		{
+			name:       "CRL signed by another key",
+			ocsp:       pki.ocspResponse(t, ocsp.Good),
+			crl:        pki.crl(t, otherKey, pki.leaf.SerialNumber),
+			ocspStatus: "good",
+			crlStatus:  "error: CRL signature invalid",
+			titles:     []string{"OCSP stapling not enabled"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			responder := staticServer(t, "application/ocsp-response", tt.ocsp)
+			crlServer := staticServer(t, "application/pkix-crl", tt.crl)
+			SetRevocationEndpoints(responder.URL, crlServer.URL)
+			t.Cleanup(func() { SetRevocationEndpoints("", "") })
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			srv := pki.server(t, tt.staple)
+			result, ok := checkRevocationPlugin(ctx, srv.URL).(map[string]interface{})
+			if !ok {
+				t.Fatalf("unexpected result %#v", checkRevocationPlugin(ctx, srv.URL))
+			}
+
+			if result["OCSP Status"] != tt.ocspStatus {
+				t.Errorf("OCSP Status = %v, want %s", result["OCSP Status"], tt.ocspStatus)
+			}
+			wantSource := tt.ocspSource
+			if wantSource == "" {
+				wantSource = responder.URL
+			}
+			if result["OCSP Source"] != wantSource {
+				t.Errorf("OCSP Source = %v, want %s", result["OCSP Source"], wantSource)
+			}
+			if status, _ := result["CRL Status"].(string); !strings.HasPrefix(status, tt.crlStatus) {
+				t.Errorf("CRL Status = %q, want %q", status, tt.crlStatus)
+			}
+
+			findings, _ := result["findings"].([]Finding)
+			titles := findingTitles(findings)
+			if strings.Join(titles, "|") != strings.Join(tt.titles, "|") {
+				t.Errorf("findings = %q, want %q", titles, tt.titles)
+			}
+		})
+	}
+}
@@ -1,61 +1,479 @@
 package scanner
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/xml"
+	"fmt"
 	"io"
 	"net/http"
+	neturl "net/url"
+	"regexp"
+	"sort"
 	"strings"
 )
 
+const (
+	// maxRobotsBody is the parsing limit RFC 9309 asks crawlers to support
+	maxRobotsBody = 500 << 10
+	// maxSitemapBody caps a sitemap after decompression
+	maxSitemapBody = 10 << 20
+	// maxSitemaps caps how many sitemap files are fetched, indexes included
+	maxSitemaps = 50
+	// maxSitemapDepth is how many levels of sitemap indexes are followed
+	maxSitemapDepth = 3
+	// maxListedURLs is how many inventory URLs are listed in the result
+	maxListedURLs = 1000
+)
+
+// defaultSitemaps are tried when robots.txt declares none
+var defaultSitemaps = []string{"/sitemap.xml", "/sitemap_index.xml"}
+
+// interestingRobotsSegments are path segments of disallowed paths that hint at content
+// worth a look. A segment also matches when one of its -, _ or . separated words does,
+// e.g. wp-config.php or *.bak.
+var interestingRobotsSegments = map[string]bool{
+	"admin": true, "administrator": true, "backup": true, "backups": true, "bak": true,
+	"private": true, "secret": true, "secrets": true, "internal": true, "config": true,
+	".git": true, ".env": true, "debug": true, "staging": true, "dev": true, "test": true,
+	"old": true, "login": true, "console": true, "api": true,
+}
+
+// robotsRule is one Allow or Disallow line of a group
+type robotsRule struct {
+	allow   bool
+	pattern string
+	re      *regexp.Regexp
+}
+
+// robotsGroup holds the rules for the user agents named at its start
+type robotsGroup struct {
+	agents     []string
+	rules      []robotsRule
+	crawlDelay string
+}
+
+type robotsFile struct {
+	groups   []*robotsGroup
+	sitemaps []string
+}
+
 func init() {
-	RegisterCheck("robots_txt", "Extracts disallowed or hidden paths from robots.txt", checkRobotsPlugin)
+	RegisterCheck("robots_txt", "Parses robots.txt groups and rules and inventories the URLs in declared and default sitemaps", checkRobotsPlugin)
+}
+
+func checkRobotsPlugin(ctx context.Context, url string) interface{} {
+	baseURL := landingBase(ctx, url)
+	result := map[string]interface{}{}
+	var findings []Finding
+
+	robots := &robotsFile{}
+	if body, ok := fetchText(ctx, baseURL+"/robots.txt", maxRobotsBody); ok {
+		robots = parseRobots(body)
+		result["Groups"] = describeRobotsGroups(robots.groups)
+
+		var disallowed []string
+		seen := make(map[string]bool)
+		for _, g := range robots.groups {
+			for _, r := range g.rules {
+				if !r.allow && !seen[r.pattern] {
+					seen[r.pattern] = true
+					disallowed = append(disallowed, r.pattern)
+				}
+			}
+		}
+		sort.Strings(disallowed)
+		result["Disallowed"] = disallowed
+
+		var interesting []string
+		for _, p := range disallowed {
+			if interestingRobotsPath(p) {
+				interesting = append(interesting, p)
+			}
+		}
+		if len(interesting) > 0 {
+			findings = append(findings, Finding{
+				Severity:       SeverityInfo,
+				Title:          "robots.txt points at sensitive-looking paths",
+				Detail:         "Disallow rules are public and list: " + strings.Join(interesting, ", "),
+				Recommendation: "Protect private areas with authentication; robots.txt only asks crawlers to stay away.",
+			})
+		}
+	}
+
+	// Sitemaps on other sites (e.g. a CDN) are outside the target
+	site := registrableDomain(extractDomain(baseURL))
+	var starts []string
+	for _, s := range robots.sitemaps {
+		if u, err := neturl.Parse(s); err == nil && (u.Scheme == "http" || u.Scheme == "https") && registrableDomain(u.Hostname()) == site {
+			starts = append(starts, s)
+		}
+	}
+	if len(robots.sitemaps) > 0 {
+		result["Declared Sitemaps"] = robots.sitemaps
+	}
+	if len(starts) == 0 {
+		for _, p := range defaultSitemaps {
+			starts = append(starts, baseURL+p)
+		}
+	}
+
+	fetched, urls := crawlSitemaps(ctx, starts, site)
+	if len(fetched) > 0 {
+		result["Sitemaps"] = fetched
+		result["URL Count"] = len(urls)
+
+		sections := make(map[string]int)
+		blocked := 0
+		everyone := robots.group("*")
+		for _, raw := range urls {
+			u, err := neturl.Parse(raw)
+			if err != nil {
+				continue
+			}
+			section, _, _ := strings.Cut(strings.TrimPrefix(u.EscapedPath(), "/"), "/")
+			sections["/"+section]++
+			if everyone != nil && !everyone.allows(u.EscapedPath()) {
+				blocked++
+			}
+		}
+		result["URLs by Section"] = sections
+
+		// URL Count has the total; URLs is left out of scan diffs
+		listed := urls
+		if len(listed) > maxListedURLs {
+			listed = listed[:maxListedURLs]
+		}
+		result["URLs"] = listed
+
+		if blocked > 0 {
+			findings = append(findings, Finding{
+				Severity:       SeverityInfo,
+				Title:          "Sitemap lists URLs that robots.txt disallows",
+				Detail:         fmt.Sprintf("%d of %d sitemap URLs are disallowed for all crawlers.", blocked, len(urls)),
+				Recommendation: "Remove disallowed URLs from the sitemap or allow them in robots.txt.",
+			})
+		}
+	}
+
+	if len(findings) > 0 {
+		sortFindings(findings)
+		result["findings"] = findings
+	}
+	return result
 }
 
-func checkRobotsPlugin(ctx context.Context, baseURL string) interface{} {
-	target := baseURL + "/robots.txt"
+// interestingRobotsPath reports whether a segment of the rule path p, or a word of one,
+// is in interestingRobotsSegments, so /old matches but /holdings does not
+func interestingRobotsPath(p string) bool {
+	for _, segment := range strings.Split(strings.ToLower(p), "/") {
+		segment = strings.Trim(segment, "*$")
+		if interestingRobotsSegments[segment] {
+			return true
+		}
+		words := strings.FieldsFunc(segment, func(r rune) bool {
+			return r == '-' || r == '_' || r == '.' || r == '*'
+		})
+		for _, word := range words {
+			if interestingRobotsSegments[word] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fetchText GETs a small text resource, rejecting error statuses and HTML catch-all
+// pages
+func fetchText(ctx context.Context, target string, limit int64) ([]byte, bool) {
 	req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
 	if err != nil {
-		return []string{}
+		return nil, false
 	}
 	req.Header.Set("User-Agent", "URLHawkScanner-Scanner/1.0")
 
 	resp, err := pluginClient.Do(req)
 	if err != nil {
-		return []string{}
+		return nil, false
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, false
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit))
+	if err != nil || bytes.Contains(bytes.ToLower(body), []byte("<html")) {
+		return nil, false
+	}
+	return body, true
+}
+
+// parseRobots reads robots.txt as described in RFC 9309: consecutive user-agent lines
+// open a group, the rules that follow belong to it, and sitemap lines are global.
+// Paths keep their case; only field names are case-insensitive.
+func parseRobots(body []byte) *robotsFile {
+	robots := &robotsFile{}
+	var current *robotsGroup
+	inRules := false
+
+	lines := bufio.NewScanner(bytes.NewReader(body))
+	for lines.Scan() {
+		line, _, _ := strings.Cut(lines.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
 
-	if resp.StatusCode != 200 {
-		return []string{}
+		switch key {
+		case "user-agent":
+			if current == nil || inRules {
+				current = &robotsGroup{}
+				robots.groups = append(robots.groups, current)
+				inRules = false
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+		case "allow", "disallow":
+			if current == nil {
+				continue
+			}
+			inRules = true
+			// An empty Disallow allows everything and adds no rule
+			if value == "" {
+				continue
+			}
+			current.rules = append(current.rules, robotsRule{allow: key == "allow", pattern: value, re: robotsPattern(value)})
+		case "crawl-delay":
+			if current != nil {
+				inRules = true
+				current.crawlDelay = value
+			}
+		case "sitemap":
+			if value != "" {
+				robots.sitemaps = append(robots.sitemaps, value)
+			}
+		}
 	}
+	return robots
+}
 
-	body, err := io.ReadAll(io.LimitReader(resp.Body, 10240)) // limit to 10kb
-	if err != nil {
-		return []string{}
+// robotsPattern compiles a rule path: * matches any run of characters and a trailing
+// $ anchors the end; everything else is a prefix match
+func robotsPattern(pattern string) *regexp.Regexp {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	expr := "^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*")
+	if anchored {
+		expr += "$"
 	}
+	return regexp.MustCompile(expr)
+}
 
-	content := string(body)
-	if strings.Contains(strings.ToLower(content), "<html") {
-		return []string{} // False positive redirect to home page
+// group returns the group that applies to agent, merged when several groups name it
+func (r *robotsFile) group(agent string) *robotsGroup {
+	var merged *robotsGroup
+	for _, g := range r.groups {
+		for _, a := range g.agents {
+			if a != agent {
+				continue
+			}
+			if merged == nil {
+				merged = &robotsGroup{agents: []string{agent}, crawlDelay: g.crawlDelay}
+			}
+			merged.rules = append(merged.rules, g.rules...)
+			break
+		}
 	}
+	return merged
+}
 
-	var disallowed []string
-	lines := strings.Split(content, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(strings.ToLower(line), "disallow:") {
-			path := strings.TrimSpace(strings.TrimPrefix(strings.ToLower(line), "disallow:"))
-			if path != "" && path != "/" {
-				disallowed = append(disallowed, path)
+// allows applies the longest matching rule to path; Allow wins a tie
+func (g *robotsGroup) allows(path string) bool {
+	allowed, longest := true, -1
+	for _, r := range g.rules {
+		if !r.re.MatchString(path) {
+			continue
+		}
+		if len(r.pattern) > longest || (len(r.pattern) == longest && r.allow) {
+			allowed, longest = r.allow, len(r.pattern)
+		}
+	}
+	return allowed
+}
+
+// describeRobotsGroups reports every user agent's Allow, Disallow and Crawl-delay
+func describeRobotsGroups(groups []*robotsGroup) map[string]interface{} {
+	described := make(map[string]interface{})
+	for _, g := range groups {
+		var allow, disallow []string
+		for _, r := range g.rules {
+			if r.allow {
+				allow = append(allow, r.pattern)
+			} else {
+				disallow = append(disallow, r.pattern)
+			}
+		}
+		for _, agent := range g.agents {
+			entry, ok := described[agent].(map[string]interface{})
+			if !ok {
+				entry = make(map[string]interface{})
+				described[agent] = entry
+			}
+			if len(allow) > 0 {
+				entry["Allow"] = appendStrings(entry["Allow"], allow)
+			}
+			if len(disallow) > 0 {
+				entry["Disallow"] = appendStrings(entry["Disallow"], disallow)
+			}
+			if g.crawlDelay != "" {
+				entry["Crawl-delay"] = g.crawlDelay
 			}
 		}
 	}
+	return described
+}
+
+func appendStrings(existing interface{}, more []string) []string {
+	list, _ := existing.([]string)
+	return append(list, more...)
+}
+
+// crawlSitemaps fetches the sitemaps in starts and the sitemaps their indexes list,
+// returning the sitemaps that were read and the deduplicated page URLs in them
+func crawlSitemaps(ctx context.Context, starts []string, site string) ([]string, []string) {
+	var fetched, urls []string
+	seenSitemaps := make(map[string]bool)
+	seenURLs := make(map[string]bool)
+
+	level := starts
+	for depth := 0; depth < maxSitemapDepth && len(level) > 0; depth++ {
+		var batch []string
+		for _, s := range level {
+			if !seenSitemaps[s] && len(seenSitemaps) < maxSitemaps {
+				seenSitemaps[s] = true
+				batch = append(batch, s)
+			}
+		}
+
+		type parsed struct {
+			ok            bool
+			pages, nested []string
+		}
+		results := make([]parsed, len(batch))
+		probeEach(len(batch), func(i int) {
+			pages, nested, ok := fetchSitemap(ctx, batch[i])
+			results[i] = parsed{ok, pages, nested}
+		})
 
-	// Limit to top 15 so we don't blow up the UI if the file is massive
-	if len(disallowed) > 15 {
-		disallowed = disallowed[:15]
-		disallowed = append(disallowed, "... (more hidden paths found in file)")
+		level = nil
+		for i, r := range results {
+			if !r.ok {
+				continue
+			}
+			fetched = append(fetched, batch[i])
+			for _, page := range r.pages {
+				if !seenURLs[page] {
+					seenURLs[page] = true
+					urls = append(urls, page)
+				}
+			}
+			for _, s := range r.nested {
+				if u, err := neturl.Parse(s); err == nil && registrableDomain(u.Hostname()) == site {
+					level = append(level, s)
+				}
+			}
+		}
+	}
+	sort.Strings(urls)
+	return fetched, urls
+}
+
+// fetchSitemap reads one sitemap, gzip-compressed or not, returning the page URLs of
+// a urlset and the sitemap URLs of an index. Plain-text sitemaps (one URL per line)
+// are accepted too.
+func fetchSitemap(ctx context.Context, target string) ([]string, []string, bool) {
+	req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
+	if err != nil {
+		return nil, nil, false
+	}
+	req.Header.Set("User-Agent", "URLHawkScanner-Scanner/1.0")
+
+	resp, err := pluginClient.Do(req)
+	if err != nil {
+		return nil, nil, false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, false
+	}
+
+	body := bufio.NewReader(io.LimitReader(resp.Body, maxSitemapBody))
+	var r io.Reader = body
+	if magic, _ := body.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, nil, false
+		}
+		defer gz.Close()
+		r = gz
+	}
+	data, err := io.ReadAll(io.LimitReader(r, maxSitemapBody))
+	if err != nil && len(data) == 0 {
+		return nil, nil, false
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	if !bytes.HasPrefix(trimmed, []byte("<")) {
+		var pages []string
+		for _, line := range strings.Split(string(trimmed), "\n") {
+			if line = strings.TrimSpace(line); strings.HasPrefix(line, "http://") || strings.HasPrefix(line, "https://") {
+				pages = append(pages, line)
+			}
+		}
+		return pages, nil, len(pages) > 0
 	}
+	return parseSitemapXML(trimmed)
+}
+
+// parseSitemapXML collects <url><loc> and <sitemap><loc> entries, ignoring namespaces
+func parseSitemapXML(data []byte) ([]string, []string, bool) {
+	var pages, nested []string
+	var parent string
+	isSitemap := false
 
-	return disallowed
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "urlset", "sitemapindex":
+				isSitemap = true
+			case "url", "sitemap":
+				parent = t.Name.Local
+			case "loc":
+				var loc string
+				if decoder.DecodeElement(&loc, &t) != nil {
+					continue
+				}
+				loc = strings.TrimSpace(loc)
+				if parent == "sitemap" {
+					nested = append(nested, loc)
+				} else if parent == "url" {
+					pages = append(pages, loc)
+				}
+			}
+		case xml.EndElement:
+			if t.Name.Local == parent {
+				parent = ""
+			}
+		}
+	}
+	return pages, nested, isSitemap
 }
@@ -0,0 +1,246 @@
+package scanner
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+const testRobots = `# Comments and unknown fields are ignored
+User-agent: Googlebot
+User-agent: bingbot
+Disallow: /search
+Crawl-delay: 5
+
+user-agent: *
+disallow: /admin/
+Allow: /admin/public/
+Disallow: /*.bak$
+Disallow:   # allows everything, adds no rule
+Unknown: value
+
+Sitemap: https://example.com/sitemap.xml
+User-agent: Googlebot
+Disallow: /Private
+`
+
+func TestParseRobots(t *testing.T) {
+	robots := parseRobots([]byte(testRobots))
+
+	if len(robots.groups) != 3 {
+		t.Fatalf("%d groups, want 3", len(robots.groups))
+	}
+	if !slices.Equal(robots.groups[0].agents, []string{"googlebot", "bingbot"}) || robots.groups[0].crawlDelay != "5" {
+		t.Errorf("first group = %+v", robots.groups[0])
+	}
+	if !slices.Equal(robots.sitemaps, []string{"https://example.com/sitemap.xml"}) {
+		t.Errorf("sitemaps = %q", robots.sitemaps)
+	}
+
+	// Groups naming the same agent are merged; paths keep their case
+	google := robots.group("googlebot")
+	var patterns []string
+	for _, r := range google.rules {
+		patterns = append(patterns, r.pattern)
+	}
+	if !slices.Equal(patterns, []string{"/search", "/Private"}) {
+		t.Errorf("googlebot rules = %q", patterns)
+	}
+	if robots.group("duckduckbot") != nil {
+		t.Error("an agent without a group got one")
+	}
+
+	everyone := robots.group("*")
+	if len(everyone.rules) != 3 {
+		t.Errorf("* has %d rules, want 3", len(everyone.rules))
+	}
+
+	groups := describeRobotsGroups(robots.groups)
+	bing := groups["bingbot"].(map[string]interface{})
+	if !slices.Equal(bing["Disallow"].([]string), []string{"/search"}) || bing["Crawl-delay"] != "5" {
+		t.Errorf("bingbot = %v", bing)
+	}
+}
+
+func TestRobotsAllows(t *testing.T) {
+	robots := parseRobots([]byte(testRobots))
+	everyone := robots.group("*")
+	google := robots.group("googlebot")
+
+	tests := []struct {
+		group *robotsGroup
+		path  string
+		want  bool
+	}{
+		{everyone, "/", true},
+		{everyone, "/admin/", false},
+		{everyone, "/admin/users", false},
+		// The longer Allow rule wins
+		{everyone, "/admin/public/logo.png", true},
+		{everyone, "/db.bak", false},
+		{everyone, "/db.bak.txt", true},
+		{everyone, "/backups/2024/db.bak", false},
+		{google, "/search?q=x", false},
+		{google, "/private", true},
+		{google, "/Private/a", false},
+	}
+	for _, tt := range tests {
+		if got := tt.group.allows(tt.path); got != tt.want {
+			t.Errorf("allows(%q) = %t, want %t", tt.path, got, tt.want)
+		}
+	}
+
+	// Allow wins a tie between rules of the same length
+	tie := parseRobots([]byte("User-agent: *\nDisallow: /page\nAllow: /page\n")).group("*")
+	if !tie.allows("/page") {
+		t.Error("Disallow won a tie with Allow")
+	}
+}
+
+func TestRobotsPattern(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"/fish", "/fish.html", true},
+		{"/fish", "/Fish", false},
+		{"/fish*", "/fishheads/yummy", true},
+		{"/*.php", "/index.php?x=1", true},
+		{"/*.php$", "/index.php?x=1", false},
+		{"/*.php$", "/a/index.php", true},
+		{"/a.b", "/axb", false},
+	}
+	for _, tt := range tests {
+		if got := robotsPattern(tt.pattern).MatchString(tt.path); got != tt.want {
+			t.Errorf("%q matching %q = %t, want %t", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestInterestingRobotsPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/admin/", true},
+		{"/ADMIN", true},
+		{"/wp-config.php", true},
+		{"/*.bak$", true},
+		{"/.git/", true},
+		{"/site/old/", true},
+		{"/holdings/", false},
+		{"/administration/", false},
+		{"/testimonials", false},
+		{"/search", false},
+	}
+	for _, tt := range tests {
+		if got := interestingRobotsPath(tt.path); got != tt.want {
+			t.Errorf("interestingRobotsPath(%q) = %t, want %t", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestParseSitemapXML(t *testing.T) {
+	tests := []struct {
+		name          string
+		data          string
+		pages, nested []string
+		ok            bool
+	}{
+		{
+			name:  "urlset",
+			data:  `<?xml version="1.0" encoding="UTF-8"?><urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc> https://example.com/ </loc><lastmod>2024-01-01</lastmod></url><url><loc>https://example.com/a?x=1&amp;y=2</loc></url></urlset>`,
+			pages: []string{"https://example.com/", "https://example.com/a?x=1&y=2"},
+			ok:    true,
+		},
+		{
+			name:   "index",
+			data:   `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><sitemap><loc>https://example.com/posts.xml</loc></sitemap><sitemap><loc>https://example.com/pages.xml.gz</loc></sitemap></sitemapindex>`,
+			nested: []string{"https://example.com/posts.xml", "https://example.com/pages.xml.gz"},
+			ok:     true,
+		},
+		{
+			name:  "prefixed namespace",
+			data:  `<sm:urlset xmlns:sm="http://www.sitemaps.org/schemas/sitemap/0.9"><sm:url><sm:loc>https://example.com/gallery</sm:loc></sm:url></sm:urlset>`,
+			pages: []string{"https://example.com/gallery"},
+			ok:    true,
+		},
+		{
+			name: "not a sitemap",
+			data: `<rss><channel><item><link>https://example.com/</link></item></channel></rss>`,
+		},
+		{
+			name:  "truncated",
+			data:  `<urlset><url><loc>https://example.com/a</loc></url><url><loc>https://exa`,
+			pages: []string{"https://example.com/a"},
+			ok:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pages, nested, ok := parseSitemapXML([]byte(tt.data))
+			if !slices.Equal(pages, tt.pages) || !slices.Equal(nested, tt.nested) || ok != tt.ok {
+				t.Errorf("parseSitemapXML = %q, %q, %t, want %q, %q, %t", pages, nested, ok, tt.pages, tt.nested, tt.ok)
+			}
+		})
+	}
+}
+
+func TestRobotsPluginFollowsSitemaps(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			fmt.Fprintf(w, "User-agent: *\nDisallow: /backup/\n\nSitemap: %s/index.xml\nSitemap: https://cdn.example.net/sitemap.xml\n", srv.URL)
+		case "/index.xml":
+			fmt.Fprintf(w, "<sitemapindex><sitemap><loc>%s/pages.xml.gz</loc></sitemap><sitemap><loc>%s/list.txt</loc></sitemap></sitemapindex>", srv.URL, srv.URL)
+		case "/pages.xml.gz":
+			var buf bytes.Buffer
+			gz := gzip.NewWriter(&buf)
+			fmt.Fprintf(gz, "<urlset><url><loc>%s/blog/one</loc></url><url><loc>%s/backup/db.sql</loc></url></urlset>", srv.URL, srv.URL)
+			gz.Close()
+			w.Write(buf.Bytes())
+		case "/list.txt":
+			fmt.Fprintf(w, "%s/blog/two\n%s/blog/one\n", srv.URL, srv.URL)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	result, ok := checkRobotsPlugin(ctx, srv.URL).(map[string]interface{})
+	if !ok {
+		t.Fatalf("unexpected result %#v", result)
+	}
+
+	if !slices.Equal(result["Disallowed"].([]string), []string{"/backup/"}) {
+		t.Errorf("Disallowed = %v", result["Disallowed"])
+	}
+	wantSitemaps := []string{srv.URL + "/index.xml", srv.URL + "/pages.xml.gz", srv.URL + "/list.txt"}
+	if got := result["Sitemaps"].([]string); !slices.Equal(slices.Sorted(slices.Values(got)), slices.Sorted(slices.Values(wantSitemaps))) {
+		t.Errorf("Sitemaps = %q, want %q (the CDN sitemap is off site)", got, wantSitemaps)
+	}
+	if result["URL Count"] != 3 {
+		t.Errorf("URL Count = %v, want 3", result["URL Count"])
+	}
+	sections := result["URLs by Section"].(map[string]int)
+	if sections["/blog"] != 2 || sections["/backup"] != 1 {
+		t.Errorf("URLs by Section = %v", sections)
+	}
+
+	findings, _ := result["findings"].([]Finding)
+	titles := findingTitles(findings)
+	for _, want := range []string{"robots.txt points at sensitive-looking paths", "Sitemap lists URLs that robots.txt disallows"} {
+		if !slices.Contains(titles, want) {
+			t.Errorf("findings %q lack %q", titles, want)
+		}
+	}
+}
@@ -1,54 +1,265 @@
 package scanner
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"fmt"
 	"io"
+	"mime"
 	"net/http"
+	neturl "net/url"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
+// maxSecurityTxtBody caps how much of security.txt is read
+const maxSecurityTxtBody = 32 << 10
+
+// securityTxtPaths are tried in order; RFC 9116 places the file under /.well-known/
+// and allows the top-level path for legacy compatibility
+var securityTxtPaths = []string{"/.well-known/security.txt", "/security.txt"}
+
+// securityTxtFields are the RFC 9116 fields, with whether each may appear more than once
+var securityTxtFields = []struct {
+	name     string
+	multiple bool
+}{
+	{"Contact", true},
+	{"Expires", false},
+	{"Encryption", true},
+	{"Acknowledgments", true},
+	{"Preferred-Languages", false},
+	{"Canonical", true},
+	{"Policy", true},
+	{"Hiring", true},
+}
+
 func init() {
-	RegisterCheck("security_txt", "Checks for a standard security.txt policy file", checkSecurityTxtPlugin)
+	RegisterCheck("security_txt", "Parses and validates security.txt against RFC 9116, including PGP signatures", checkSecurityTxtPlugin)
+}
+
+func checkSecurityTxtPlugin(ctx context.Context, url string) interface{} {
+	baseURL := landingBase(ctx, url)
+
+	var body []byte
+	var requested, fetched, contentType string
+	for _, p := range securityTxtPaths {
+		var ok bool
+		if body, fetched, contentType, ok = fetchSecurityTxt(ctx, baseURL+p); ok {
+			requested = baseURL + p
+			break
+		}
+	}
+	if requested == "" {
+		return map[string]interface{}{
+			"Policy Found": "No",
+			"findings": []Finding{{
+				Severity:       SeverityInfo,
+				Title:          "No security.txt",
+				Detail:         "Neither /.well-known/security.txt nor /security.txt is served.",
+				Recommendation: "Publish /.well-known/security.txt (RFC 9116) with at least Contact and Expires so researchers know how to report vulnerabilities.",
+			}},
+		}
+	}
+
+	result := map[string]interface{}{
+		"Policy Found": "Yes",
+		"URL":          fetched,
+	}
+	var findings []Finding
+	add := func(severity, title, detail, recommendation string) {
+		findings = append(findings, Finding{Severity: severity, Title: title, Detail: detail, Recommendation: recommendation})
+	}
+
+	if !strings.HasSuffix(requested, securityTxtPaths[0]) {
+		add(SeverityInfo, "security.txt only at the legacy location", "The file is served at /security.txt but not at /.well-known/security.txt.",
+			"Serve the file at /.well-known/security.txt; the top-level path is only for legacy compatibility.")
+	}
+	if !strings.HasPrefix(fetched, "https://") {
+		add(SeverityLow, "security.txt is not served over HTTPS", fetched+" was fetched over plain HTTP.",
+			"RFC 9116 requires security.txt to be served over HTTPS.")
+	}
+	if mediaType, params, err := mime.ParseMediaType(contentType); err != nil || mediaType != "text/plain" {
+		add(SeverityInfo, "security.txt has the wrong Content-Type", fmt.Sprintf("Content-Type is %q.", contentType),
+			`Serve security.txt as "text/plain; charset=utf-8".`)
+	} else if charset := params["charset"]; charset != "" && !strings.EqualFold(charset, "utf-8") {
+		add(SeverityInfo, "security.txt has the wrong charset", "The charset parameter is "+charset+".",
+			`Serve security.txt as "text/plain; charset=utf-8".`)
+	}
+	if !utf8.Valid(body) {
+		add(SeverityLow, "security.txt is not valid UTF-8", "The file contains byte sequences that are not UTF-8.",
+			"Save the file as UTF-8.")
+	}
+
+	// Only the signed part of a signed file counts
+	content := bytes.TrimPrefix(body, []byte("\xef\xbb\xbf"))
+	result["Signed"] = "No"
+	if message, armored, ok := splitClearsigned(content); ok {
+		content = message
+		result["Signed"] = "Yes"
+		sig, err := parsePGPSignature(armored)
+		if err != nil {
+			result["Signature"] = map[string]interface{}{"error": err.Error()}
+			add(SeverityLow, "security.txt signature cannot be parsed", err.Error(),
+				"Sign the file with gpg --clearsign or an equivalent OpenPGP cleartext signature.")
+		} else {
+			result["Signature"] = sig.describe()
+			if sig.weakHash() {
+				add(SeverityLow, "security.txt is signed with a weak hash", "The signature uses "+sig.hashName()+".",
+					"Re-sign the file with SHA-256 or stronger.")
+			}
+		}
+	}
+
+	fields, unsupported := parseSecurityTxt(content)
+	for _, f := range securityTxtFields {
+		values := fields[f.name]
+		if len(values) == 0 {
+			continue
+		}
+		if f.multiple {
+			result[f.name] = values
+		} else {
+			result[f.name] = values[0]
+			if len(values) > 1 {
+				add(SeverityLow, "security.txt repeats "+f.name, fmt.Sprintf("%s appears %d times; it must appear at most once.", f.name, len(values)),
+					"Keep a single "+f.name+" line.")
+			}
+		}
+	}
+	if len(unsupported) > 0 {
+		result["Other Fields"] = unsupported
+	}
+
+	valid := true
+	contacts := fields["Contact"]
+	if len(contacts) == 0 {
+		valid = false
+		add(SeverityLow, "security.txt has no Contact", "The required Contact field is missing.",
+			"Add at least one Contact line with a mailto:, tel: or https: URI.")
+	}
+	for _, uri := range append(append([]string{}, contacts...), fields["Encryption"]...) {
+		if strings.HasPrefix(strings.ToLower(uri), "http://") {
+			add(SeverityLow, "security.txt links over plain HTTP", uri+" uses http://.",
+				"Use https:// URIs for Contact and Encryption so they cannot be tampered with in transit.")
+		}
+	}
+
+	if expires := fields["Expires"]; len(expires) == 0 {
+		valid = false
+		add(SeverityLow, "security.txt has no Expires", "The required Expires field is missing.",
+			"Add an Expires line in RFC 3339 format, less than a year ahead, e.g. Expires: "+time.Now().AddDate(0, 6, 0).UTC().Format(time.RFC3339)+".")
+	} else if t, err := time.Parse(time.RFC3339, expires[0]); err != nil {
+		valid = false
+		add(SeverityLow, "security.txt Expires is not a valid date", fmt.Sprintf("%q is not an RFC 3339 date and time.", expires[0]),
+			"Write Expires in RFC 3339 format, e.g. "+time.Now().AddDate(0, 6, 0).UTC().Format(time.RFC3339)+".")
+	} else {
+		days := int(time.Until(t).Hours() / 24)
+		result["Days Until Expiry"] = days
+		switch {
+		case time.Now().After(t):
+			valid = false
+			add(SeverityLow, "security.txt has expired", "The file expired on "+t.Format("2006-01-02")+" and should no longer be trusted.",
+				"Review the contents and move Expires into the future.")
+		case days > 365:
+			add(SeverityInfo, "security.txt expires more than a year ahead", fmt.Sprintf("Expires is %d days away.", days),
+				"Keep Expires less than a year in the future so the contents are reviewed regularly.")
+		}
+	}
+
+	if canonicals := fields["Canonical"]; len(canonicals) > 0 {
+		if !canonicalListed(canonicals, requested, fetched) {
+			valid = false
+			add(SeverityLow, "security.txt Canonical does not match its location", fetched+" is not listed in Canonical: "+strings.Join(canonicals, ", "),
+				"List every URL the file is served from in Canonical; otherwise its contents should not be trusted.")
+		}
+	} else if result["Signed"] == "Yes" {
+		add(SeverityInfo, "Signed security.txt has no Canonical", "Without Canonical the signed file can be replayed from another site.",
+			"Add a Canonical line with the file's URL before signing.")
+	}
+
+	result["Valid"] = fmt.Sprintf("%t", valid)
+	if len(findings) > 0 {
+		sortFindings(findings)
+		result["findings"] = findings
+	}
+	return result
 }
 
-func checkSecurityTxtPlugin(ctx context.Context, baseURL string) interface{} {
-	target := baseURL + "/.well-known/security.txt"
+// fetchSecurityTxt GETs target, returning the body, the URL it was finally served
+// from and its Content-Type; error statuses and HTML catch-all pages are rejected
+func fetchSecurityTxt(ctx context.Context, target string) ([]byte, string, string, bool) {
 	req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
 	if err != nil {
-		return map[string]string{"Policy Found": "No"}
+		return nil, "", "", false
 	}
 	req.Header.Set("User-Agent", "URLHawkScanner/1.0")
 
 	resp, err := pluginClient.Do(req)
 	if err != nil {
-		return map[string]string{"Policy Found": "Connection Failed"}
+		return nil, "", "", false
 	}
 	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return map[string]string{"Policy Found": "No (404/Denied)"}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", "", false
 	}
-
-	body, err := io.ReadAll(io.LimitReader(resp.Body, 2048))
-	if err != nil {
-		return map[string]string{"Policy Found": "Read Error"}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSecurityTxtBody))
+	if err != nil || bytes.Contains(bytes.ToLower(body), []byte("<html")) {
+		return nil, "", "", false
 	}
-	content := string(body)
+	return body, resp.Request.URL.String(), resp.Header.Get("Content-Type"), true
+}
 
-	if strings.Contains(strings.ToLower(content), "<html") {
-		return map[string]string{"Policy Found": "No (Redirected to HTML)"}
+// parseSecurityTxt collects field values by their RFC 9116 name. Field names are
+// case-insensitive; fields the RFC does not define are returned separately.
+func parseSecurityTxt(content []byte) (map[string][]string, []string) {
+	canonical := make(map[string]string, len(securityTxtFields))
+	for _, f := range securityTxtFields {
+		canonical[strings.ToLower(f.name)] = f.name
 	}
 
-	return map[string]string{
-		"Policy Found": "Yes",
-		"Path": "/.well-known/security.txt",
-		"Snippet": content[:min(len(content), 100)] + "...",
+	fields := make(map[string][]string)
+	var unsupported []string
+	lines := bufio.NewScanner(bytes.NewReader(content))
+	for lines.Scan() {
+		line := strings.TrimSpace(lines.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if known, ok := canonical[strings.ToLower(strings.TrimSpace(name))]; ok {
+			fields[known] = append(fields[known], value)
+		} else {
+			unsupported = append(unsupported, line)
+		}
 	}
+	return fields, unsupported
 }
 
-func min(a, b int) int {
-	if a < b {
-		return a
+// canonicalListed reports whether one of the Canonical URIs names one of urls,
+// ignoring case in the scheme and host
+func canonicalListed(canonicals []string, urls ...string) bool {
+	normalize := func(raw string) string {
+		u, err := neturl.Parse(strings.TrimSpace(raw))
+		if err != nil {
+			return raw
+		}
+		u.Scheme = strings.ToLower(u.Scheme)
+		u.Host = strings.ToLower(u.Host)
+		return u.String()
+	}
+	for _, c := range canonicals {
+		for _, u := range urls {
+			if normalize(c) == normalize(u) {
+				return true
+			}
+		}
 	}
-	return b
+	return false
 }
@@ -0,0 +1,139 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseSecurityTxt(t *testing.T) {
+	content := "# Our policy\ncontact: mailto:security@example.com\nContact:https://example.com/report\n\nEXPIRES: 2030-01-01T00:00:00Z\nPreferred-Languages: en, de\nX-Custom: value\nnot a field\n"
+	fields, unsupported := parseSecurityTxt([]byte(content))
+
+	if want := []string{"mailto:security@example.com", "https://example.com/report"}; !slices.Equal(fields["Contact"], want) {
+		t.Errorf("Contact = %q, want %q", fields["Contact"], want)
+	}
+	if !slices.Equal(fields["Expires"], []string{"2030-01-01T00:00:00Z"}) {
+		t.Errorf("Expires = %q", fields["Expires"])
+	}
+	if !slices.Equal(fields["Preferred-Languages"], []string{"en, de"}) {
+		t.Errorf("Preferred-Languages = %q", fields["Preferred-Languages"])
+	}
+	if !slices.Equal(unsupported, []string{"X-Custom: value"}) {
+		t.Errorf("unsupported = %q", unsupported)
+	}
+}
+
+func TestCanonicalListed(t *testing.T) {
+	served := "https://example.com/.well-known/security.txt"
+	tests := []struct {
+		canonicals []string
+		want       bool
+	}{
+		{[]string{served}, true},
+		{[]string{"HTTPS://Example.COM/.well-known/security.txt"}, true},
+		{[]string{"https://other.example/.well-known/security.txt", " " + served + " "}, true},
+		{[]string{"https://example.com/.well-known/SECURITY.txt"}, false},
+		{[]string{"https://example.com/security.txt"}, false},
+	}
+	for _, tt := range tests {
+		if got := canonicalListed(tt.canonicals, served); got != tt.want {
+			t.Errorf("canonicalListed(%q) = %t, want %t", tt.canonicals, got, tt.want)
+		}
+	}
+}
+
+func TestSecurityTxtPlugin(t *testing.T) {
+	expires := time.Now().AddDate(0, 3, 0).UTC().Format(time.RFC3339)
+	tests := []struct {
+		name        string
+		path        string
+		contentType string
+		body        func(base string) string
+		valid       string
+		titles      []string
+		// only means titles are all the findings
+		only bool
+	}{
+		{
+			name:        "valid",
+			path:        "/.well-known/security.txt",
+			contentType: "text/plain; charset=utf-8",
+			body: func(base string) string {
+				return "Contact: mailto:security@example.com\nExpires: " + expires + "\nCanonical: " + base + "/.well-known/security.txt\n"
+			},
+			valid:  "true",
+			titles: []string{"security.txt is not served over HTTPS"},
+			only:   true,
+		},
+		{
+			name:        "legacy location, expired, no contact",
+			path:        "/security.txt",
+			contentType: "text/plain",
+			body: func(string) string {
+				return "Expires: 2020-01-01T00:00:00Z\nExpires: 2021-01-01T00:00:00Z\n"
+			},
+			valid: "false",
+			titles: []string{
+				"security.txt has expired", "security.txt has no Contact", "security.txt is not served over HTTPS",
+				"security.txt repeats Expires", "security.txt only at the legacy location",
+			},
+		},
+		{
+			name:        "signed elsewhere",
+			path:        "/.well-known/security.txt",
+			contentType: "text/html",
+			body:        func(string) string { return gnupgClearsigned },
+			valid:       "false",
+			titles: []string{
+				"security.txt Canonical does not match its location", "security.txt is not served over HTTPS",
+				"security.txt has the wrong Content-Type",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var srv *httptest.Server
+			srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != tt.path {
+					http.NotFound(w, r)
+					return
+				}
+				w.Header().Set("Content-Type", tt.contentType)
+				fmt.Fprint(w, tt.body(srv.URL))
+			}))
+			defer srv.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			result, ok := checkSecurityTxtPlugin(ctx, srv.URL).(map[string]interface{})
+			if !ok {
+				t.Fatalf("unexpected result %#v", result)
+			}
+			if result["Valid"] != tt.valid {
+				t.Errorf("Valid = %v, want %s", result["Valid"], tt.valid)
+			}
+			findings, _ := result["findings"].([]Finding)
+			titles := findingTitles(findings)
+			if tt.only && len(titles) != len(tt.titles) {
+				t.Errorf("findings = %q, want only %q", titles, tt.titles)
+			}
+			for _, want := range tt.titles {
+				if !slices.Contains(titles, want) {
+					t.Errorf("findings %q lack %q", titles, want)
+				}
+			}
+		})
+	}
+
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	result := checkSecurityTxtPlugin(context.Background(), srv.URL).(map[string]interface{})
+	if result["Policy Found"] != "No" {
+		t.Errorf("Policy Found = %v without a file", result["Policy Found"])
+	}
+}
@@ -4,48 +4,177 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
-	"net"
+	"math"
+	"strings"
+	"sync"
 	"time"
 )
 
+// Titles of the findings certExpiryFindings raises about the expiry date
+const (
+	titleCertExpired         = "Certificate expired"
+	titleCertExpiresCritical = "Certificate expires within critical threshold"
+	titleCertExpiresWarning  = "Certificate expires within warning threshold"
+)
+
+var (
+	certThresholdsMu sync.RWMutex
+	certWarningDays  = 30
+	certCriticalDays = 7
+)
+
 func init() {
 	RegisterCheck("ssl_certificate", "Analyzes SSL/TLS certificate details", checkSSLPlugin)
 }
 
+// SetCertExpiryThresholds sets how many days before expiry the ssl_certificate check
+// raises a warning (medium) and a critical (high) finding. Expired certificates are
+// always critical.
+func SetCertExpiryThresholds(warningDays, criticalDays int) {
+	certThresholdsMu.Lock()
+	defer certThresholdsMu.Unlock()
+	certWarningDays = warningDays
+	certCriticalDays = criticalDays
+}
+
+func certExpiryThresholds() (int, int) {
+	certThresholdsMu.RLock()
+	defer certThresholdsMu.RUnlock()
+	return certWarningDays, certCriticalDays
+}
+
+// tlsHandshake is the memoized outcome of the default handshake with a target
+type tlsHandshake struct {
+	state tls.ConnectionState
+	err   error
+}
+
+// peerTLSState performs the default handshake with addr once per scan; the
+// certificate, revocation and transparency checks all inspect the same connection.
+func peerTLSState(ctx context.Context, addr string) (tls.ConnectionState, error) {
+	h := memoize(ctx, "tls_state:"+addr, func() interface{} {
+		conn, err := scanDialTLS(ctx, addr, &tls.Config{InsecureSkipVerify: true})
+		if err != nil {
+			return tlsHandshake{err: err}
+		}
+		defer conn.Close()
+		return tlsHandshake{state: conn.ConnectionState()}
+	}).(tlsHandshake)
+	return h.state, h.err
+}
+
 func checkSSLPlugin(ctx context.Context, url string) interface{} {
-	domain := extractDomain(url)
+	domain, addr := tlsAddress(url)
 	if domain == "" {
 		return map[string]string{"error": "Invalid domain"}
 	}
 
-	dialer := &net.Dialer{}
-
-	// Create a context-aware dial
-	deadline, ok := ctx.Deadline()
-	if ok {
-		dialer.Deadline = deadline
-	}
-
-	conn, err := tls.DialWithDialer(dialer, "tcp", domain+":443", &tls.Config{
-		InsecureSkipVerify: true,
-	})
+	state, err := peerTLSState(ctx, addr)
 	if err != nil {
-		return map[string]string{"error": "No SSL/TLS on port 443 (or timed out)"}
+		return map[string]string{"error": "No SSL/TLS on " + addr + " (or timed out)"}
 	}
-	defer conn.Close()
 
-	certs := conn.ConnectionState().PeerCertificates
+	certs := state.PeerCertificates
 	if len(certs) == 0 {
 		return map[string]string{"error": "No certificates found"}
 	}
 
 	cert := certs[0]
+	now := time.Now()
+	days := int(math.Floor(cert.NotAfter.Sub(now).Hours() / 24))
+
+	chain := make([]CertificateInfo, 0, len(certs))
+	for _, c := range certs {
+		chain = append(chain, describeCertificate(c))
+	}
+	trusted, hostnameOK, chainFindings := verifyChain(certs, domain, now)
+
+	result := map[string]interface{}{
+		"Subject":             cert.Subject.CommonName,
+		"Issuer":              cert.Issuer.CommonName,
+		"Expires":             cert.NotAfter.Format(time.RFC822),
+		"Days Until Expiry":   days,
+		"Valid Now":           fmt.Sprintf("%t", now.Before(cert.NotAfter) && now.After(cert.NotBefore)),
+		"Trusted":             fmt.Sprintf("%t", trusted),
+		"Hostname Match":      fmt.Sprintf("%t", hostnameOK),
+		"Algorithm":           cert.SignatureAlgorithm.String(),
+		"SANs":                strings.Join(cert.DNSNames, ", "),
+		"Fingerprint SHA-256": chain[0].FingerprintSHA256,
+		"Chain":               chain,
+	}
+
+	findings := append(certExpiryFindings(cert.NotBefore, cert.NotAfter, now), chainFindings...)
+	if len(findings) > 0 {
+		sortFindings(findings)
+		result["findings"] = findings
+	}
+	return result
+}
+
+// certExpiryFindings rates the validity window against the configured thresholds.
+// Titles stay the same from one day to the next so diffs and baselines only see a
+// certificate again when it crosses a threshold; the day count goes in the detail.
+func certExpiryFindings(notBefore, notAfter, now time.Time) []Finding {
+	warning, critical := certExpiryThresholds()
+	days := int(math.Floor(notAfter.Sub(now).Hours() / 24))
+	expires := notAfter.Format("2006-01-02")
+
+	var findings []Finding
+	switch {
+	case now.After(notAfter):
+		findings = append(findings, Finding{
+			Severity:       SeverityCritical,
+			Title:          titleCertExpired,
+			Detail:         fmt.Sprintf("The certificate expired on %s (%d days ago).", expires, -days),
+			Recommendation: "Renew and deploy a new certificate immediately.",
+		})
+	case days <= critical:
+		findings = append(findings, Finding{
+			Severity:       SeverityHigh,
+			Title:          titleCertExpiresCritical,
+			Detail:         fmt.Sprintf("The certificate expires on %s (in %d days), within the %d day critical threshold.", expires, days, critical),
+			Recommendation: "Renew the certificate now and check why automatic renewal has not happened.",
+		})
+	case days <= warning:
+		findings = append(findings, Finding{
+			Severity:       SeverityMedium,
+			Title:          titleCertExpiresWarning,
+			Detail:         fmt.Sprintf("The certificate expires on %s (in %d days), within the %d day warning threshold.", expires, days, warning),
+			Recommendation: "Schedule renewal, or confirm automatic renewal (e.g. ACME) is working.",
+		})
+	}
+
+	if now.Before(notBefore) {
+		findings = append(findings, Finding{
+			Severity:       SeverityHigh,
+			Title:          "Certificate not yet valid",
+			Detail:         fmt.Sprintf("The certificate only becomes valid on %s.", notBefore.Format("2006-01-02 15:04 MST")),
+			Recommendation: "Check the server clock and the certificate's validity period.",
+		})
+	}
+	return findings
+}
+
+func isCertExpiryTitle(title string) bool {
+	return title == titleCertExpired || title == titleCertExpiresCritical || title == titleCertExpiresWarning
+}
+
+// CertExpiryFinding returns the expiry finding the ssl_certificate check raised in
+// results, if the certificate is past the warning threshold
+func CertExpiryFinding(results map[string]interface{}) (Finding, bool) {
+	for _, f := range CollectFindings(results)["ssl_certificate"] {
+		if isCertExpiryTitle(f.Title) {
+			return f, true
+		}
+	}
+	return Finding{}, false
+}
 
-	return map[string]string{
-		"Subject": cert.Subject.CommonName,
-		"Issuer": cert.Issuer.CommonName,
-		"Expires": cert.NotAfter.Format(time.RFC822),
-		"Valid Now": fmt.Sprintf("%t", time.Now().Before(cert.NotAfter)),
-		"Algorithm": cert.SignatureAlgorithm.String(),
+// IsCertExpiryChange reports whether c adds or removes an ssl_certificate expiry finding
+func IsCertExpiryChange(c Change) bool {
+	if c.Check != "ssl_certificate" || !strings.HasPrefix(c.Item, "finding [") {
+		return false
 	}
+	_, title, ok := strings.Cut(c.Item, "] ")
+	return ok && isCertExpiryTitle(title)
 }
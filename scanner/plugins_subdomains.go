@@ -0,0 +1,328 @@
+package scanner
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// SubdomainSource is a single provider of candidate subdomains for a registrable domain.
+// Sources only need to return raw names; filtering, deduplication and resolution are
+// handled by the subdomains plugin.
+type SubdomainSource interface {
+	Name() string
+	Enumerate(ctx context.Context, domain string) ([]string, error)
+}
+
+// CertTransparencySource searches certificate transparency logs through a crt.sh compatible API.
+type CertTransparencySource struct {
+	Endpoint string
+}
+
+// WaybackHostsSource extracts host names from the Wayback Machine CDX index.
+type WaybackHostsSource struct {
+	Endpoint string
+}
+
+// DNSBruteForceSource resolves a wordlist of common labels under the domain.
+// Wildcard DNS zones are detected up front so catch-all answers are not reported.
+type DNSBruteForceSource struct {
+	Words    []string
+	Resolver *net.Resolver
+}
+
+// TLSSANSource harvests names from the Subject Alternative Names of the certificate
+// served on the domain's TLS port.
+type TLSSANSource struct {
+	Port string
+}
+
+var (
+	defaultSubdomainWords = []string{
+		"www", "mail", "webmail", "smtp", "pop", "imap", "mx", "ns1", "ns2", "dns",
+		"api", "dev", "staging", "stage", "test", "qa", "uat", "beta", "demo", "sandbox",
+		"admin", "portal", "dashboard", "app", "apps", "m", "mobile", "static", "assets", "cdn",
+		"img", "media", "files", "download", "docs", "help", "support", "status", "blog", "shop",
+		"store", "vpn", "remote", "git", "gitlab", "jenkins", "ci", "jira", "wiki", "internal",
+		"intranet", "auth", "sso", "login", "id", "accounts", "old", "new", "legacy", "backup",
+	}
+
+	subdomainMu      sync.RWMutex
+	subdomainSources = []SubdomainSource{
+		&CertTransparencySource{Endpoint: "https://crt.sh/"},
+		&WaybackHostsSource{Endpoint: "http://web.archive.org/cdx/search/cdx"},
+		&DNSBruteForceSource{Words: defaultSubdomainWords},
+		&TLSSANSource{Port: "443"},
+	}
+)
+
+func init() {
+	RegisterCheck("subdomains", "Enumerates subdomains via CT logs, archives, DNS brute force and TLS SANs", checkSubdomainsPlugin)
+}
+
+// SetSubdomainSources replaces the sources used by the subdomains plugin, e.g. to point
+// them at local stand-ins or to disable the slower passive APIs.
+func SetSubdomainSources(sources ...SubdomainSource) {
+	subdomainMu.Lock()
+	defer subdomainMu.Unlock()
+	subdomainSources = sources
+}
+
+func checkSubdomainsPlugin(ctx context.Context, url string) interface{} {
+	domain := registrableDomain(extractDomain(url))
+	if domain == "" {
+		return map[string]string{"error": "Invalid domain"}
+	}
+
+	subdomainMu.RLock()
+	sources := subdomainSources
+	subdomainMu.RUnlock()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	found := make(map[string]bool)
+
+	for _, source := range sources {
+		wg.Add(1)
+		go func(src SubdomainSource) {
+			defer wg.Done()
+			names, err := src.Enumerate(ctx, domain)
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			for _, name := range names {
+				if name = cleanSubdomain(name, domain); name != "" {
+					found[name] = true
+				}
+			}
+			mu.Unlock()
+		}(source)
+	}
+	wg.Wait()
+
+	results := make(map[string]interface{})
+	var resolver net.Resolver
+	sem := make(chan struct{}, 20)
+
+	for name := range found {
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			var ips []string
+			if addrs, err := resolver.LookupIPAddr(ctx, host); err == nil {
+				for _, addr := range addrs {
+					ips = append(ips, addr.String())
+				}
+			}
+			if len(ips) == 0 {
+				ips = []string{"unresolved"}
+			}
+			sort.Strings(ips)
+
+			mu.Lock()
+			results[host] = ips
+			mu.Unlock()
+		}(name)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// cleanSubdomain normalizes a candidate name and returns it only if it is a strict
+// subdomain of domain.
+func cleanSubdomain(name, domain string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = strings.TrimSuffix(name, ".")
+	name = strings.TrimPrefix(name, "*.")
+	if name == domain || !strings.HasSuffix(name, "."+domain) {
+		return ""
+	}
+	if strings.ContainsAny(name, " */@:") {
+		return ""
+	}
+	return name
+}
+
+// registrableDomain returns the eTLD+1 of host, falling back to the host itself
+// for IPs and names the public suffix list does not know about.
+func registrableDomain(host string) string {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "" || net.ParseIP(host) != nil {
+		return host
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return domain
+}
+
+func (s *CertTransparencySource) Name() string { return "crt.sh" }
+
+func (s *CertTransparencySource) Enumerate(ctx context.Context, domain string) ([]string, error) {
+	target := fmt.Sprintf("%s?q=%s&output=json", s.Endpoint, url.QueryEscape("%."+domain))
+	req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "URLHawkScanner-Scanner/1.0")
+
+	resp, err := osintClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("crt.sh returned %d", resp.StatusCode)
+	}
+
+	var entries []struct {
+		NameValue string `json:"name_value"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		// A single certificate entry lists all of its names separated by newlines
+		names = append(names, strings.Split(entry.NameValue, "\n")...)
+	}
+	return names, nil
+}
+
+func (s *WaybackHostsSource) Name() string { return "wayback" }
+
+func (s *WaybackHostsSource) Enumerate(ctx context.Context, domain string) ([]string, error) {
+	target := fmt.Sprintf("%s?url=*.%s&output=json&fl=original&collapse=urlkey&limit=5000", s.Endpoint, url.QueryEscape(domain))
+	req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "URLHawkScanner-Scanner/1.0")
+
+	resp, err := osintClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("wayback CDX returned %d", resp.StatusCode)
+	}
+
+	var rows [][]string
+	if err := json.NewDecoder(resp.Body).Decode(&rows); err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for i, row := range rows {
+		// The first row is the field header
+		if i == 0 || len(row) == 0 {
+			continue
+		}
+		raw := row[0]
+		if !strings.Contains(raw, "://") {
+			raw = "http://" + raw
+		}
+		if u, err := url.Parse(raw); err == nil && u.Hostname() != "" {
+			names = append(names, u.Hostname())
+		}
+	}
+	return names, nil
+}
+
+func (s *DNSBruteForceSource) Name() string { return "dns_bruteforce" }
+
+func (s *DNSBruteForceSource) Enumerate(ctx context.Context, domain string) ([]string, error) {
+	resolver := s.Resolver
+	if resolver == nil {
+		resolver = &net.Resolver{}
+	}
+
+	// Wildcard detection: a random label should never resolve on a sane zone
+	wildcard := make(map[string]bool)
+	if addrs, err := resolver.LookupIPAddr(ctx, randomLabel()+"."+domain); err == nil {
+		for _, addr := range addrs {
+			wildcard[addr.String()] = true
+		}
+	}
+
+	var names []string
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	sem := make(chan struct{}, 20)
+
+	for _, word := range s.Words {
+		wg.Add(1)
+		go func(w string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			host := w + "." + domain
+			addrs, err := resolver.LookupIPAddr(ctx, host)
+			if err != nil || len(addrs) == 0 {
+				return
+			}
+			if len(wildcard) > 0 {
+				matchesWildcard := true
+				for _, addr := range addrs {
+					if !wildcard[addr.String()] {
+						matchesWildcard = false
+						break
+					}
+				}
+				if matchesWildcard {
+					return
+				}
+			}
+			mu.Lock()
+			names = append(names, host)
+			mu.Unlock()
+		}(word)
+	}
+	wg.Wait()
+
+	return names, nil
+}
+
+func (s *TLSSANSource) Name() string { return "tls_san" }
+
+func (s *TLSSANSource) Enumerate(ctx context.Context, domain string) ([]string, error) {
+	conn, err := scanDialTLS(ctx, net.JoinHostPort(domain, s.Port), &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var names []string
+	for _, cert := range conn.ConnectionState().PeerCertificates {
+		names = append(names, cert.DNSNames...)
+	}
+	return names, nil
+}
+
+func randomLabel() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return "urlhawk-" + hex.EncodeToString(buf)
+}
@@ -0,0 +1,170 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestCleanSubdomain(t *testing.T) {
+	tests := []struct {
+		name, domain, want string
+	}{
+		{"www.example.com", "example.com", "www.example.com"},
+		{" API.Example.com. ", "example.com", "api.example.com"},
+		{"*.dev.example.com", "example.com", "dev.example.com"},
+		{"example.com", "example.com", ""},
+		{"notexample.com", "example.com", ""},
+		{"www.example.org", "example.com", ""},
+		{"user@mail.example.com", "example.com", ""},
+		{"a b.example.com", "example.com", ""},
+	}
+	for _, tt := range tests {
+		if got := cleanSubdomain(tt.name, tt.domain); got != tt.want {
+			t.Errorf("cleanSubdomain(%q, %q) = %q, want %q", tt.name, tt.domain, got, tt.want)
+		}
+	}
+}
+
+// crtServer stands in for crt.sh, answering with one entry per name list
+func crtServer(t *testing.T, entries ...string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("q"); got != "%.example.test" {
+			t.Errorf("crt.sh query q = %q, want %%.example.test", got)
+		}
+		var body []map[string]string
+		for _, e := range entries {
+			body = append(body, map[string]string{"name_value": e})
+		}
+		json.NewEncoder(w).Encode(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// cdxServer stands in for the Wayback CDX API, answering with a header row and urls
+func cdxServer(t *testing.T, urls ...string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("url"); got != "*.example.test" {
+			t.Errorf("CDX query url = %q, want *.example.test", got)
+		}
+		rows := [][]string{{"original"}}
+		for _, u := range urls {
+			rows = append(rows, []string{u})
+		}
+		json.NewEncoder(w).Encode(rows)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestCertTransparencySource(t *testing.T) {
+	srv := crtServer(t, "www.example.test\nmail.example.test", "*.example.test")
+	source := &CertTransparencySource{Endpoint: srv.URL + "/"}
+
+	names, err := source.Enumerate(context.Background(), "example.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"www.example.test", "mail.example.test", "*.example.test"}
+	if !slices.Equal(names, want) {
+		t.Errorf("names = %v, want %v", names, want)
+	}
+}
+
+func TestCertTransparencySourceError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "busy", http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	source := &CertTransparencySource{Endpoint: srv.URL + "/"}
+	if _, err := source.Enumerate(context.Background(), "example.test"); err == nil {
+		t.Error("expected an error for a 502 answer")
+	}
+}
+
+func TestWaybackHostsSource(t *testing.T) {
+	srv := cdxServer(t, "http://old.example.test/index.html", "shop.example.test:8080/cart", "https://www.example.test/")
+	source := &WaybackHostsSource{Endpoint: srv.URL}
+
+	names, err := source.Enumerate(context.Background(), "example.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"old.example.test", "shop.example.test", "www.example.test"}
+	if !slices.Equal(names, want) {
+		t.Errorf("names = %v, want %v", names, want)
+	}
+}
+
+// quietTLSServer is httptest.NewTLSServer without logging the handshakes probes abort
+func quietTLSServer(t *testing.T, handler http.Handler) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(handler)
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestTLSSANSource(t *testing.T) {
+	srv := quietTLSServer(t, http.NotFoundHandler())
+	u, _ := url.Parse(srv.URL)
+	host, port, _ := net.SplitHostPort(u.Host)
+
+	// The httptest certificate is issued for example.com
+	names, err := (&TLSSANSource{Port: port}).Enumerate(context.Background(), host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(names, "example.com") {
+		t.Errorf("names = %v, want example.com among them", names)
+	}
+}
+
+func TestSubdomainsPluginMergesSources(t *testing.T) {
+	crt := crtServer(t, "www.example.test\n*.api.example.test", "example.test", "other.invalid")
+	cdx := cdxServer(t, "http://WWW.example.test/", "http://blog.example.test/post")
+
+	SetSubdomainSources(
+		&CertTransparencySource{Endpoint: crt.URL + "/"},
+		&WaybackHostsSource{Endpoint: cdx.URL},
+	)
+	t.Cleanup(func() {
+		SetSubdomainSources(
+			&CertTransparencySource{Endpoint: "https://crt.sh/"},
+			&WaybackHostsSource{Endpoint: "http://web.archive.org/cdx/search/cdx"},
+			&DNSBruteForceSource{Words: defaultSubdomainWords},
+			&TLSSANSource{Port: "443"},
+		)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result, ok := checkSubdomainsPlugin(ctx, "https://example.test").(map[string]interface{})
+	if !ok {
+		t.Fatalf("unexpected result %#v", result)
+	}
+
+	var got []string
+	for name := range result {
+		got = append(got, name)
+	}
+	sort.Strings(got)
+	want := []string{"api.example.test", "blog.example.test", "www.example.test"}
+	if !slices.Equal(got, want) {
+		t.Errorf("subdomains = %v, want %v", got, want)
+	}
+}
@@ -38,6 +38,11 @@ func checkTechPlugin(ctx context.Context, url string) interface{} {
 		stack["ASP.NET"] = asp
 	}
 
+	// Session cookie names give away the framework, including cookies set on redirects
+	for name, tech := range sessionCookieTech(redirectChain(ctx, url)) {
+		stack["Cookie "+name] = tech
+	}
+
 	// Fingerprint basic body HTML tags (first 2kb is enough)
 	body, _ := io.ReadAll(io.LimitReader(resp.Body, 2048))
 	content := strings.ToLower(string(body))
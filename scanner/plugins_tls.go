@@ -0,0 +1,343 @@
+package scanner
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	neturl "net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	tlsProbeTimeout     = 3 * time.Second
+	tlsProbeConcurrency = 8
+)
+
+// tlsVersions are probed oldest first. SSLv3 is not implemented by crypto/tls and
+// cannot be tested.
+var tlsVersions = []uint16{tls.VersionTLS10, tls.VersionTLS11, tls.VersionTLS12, tls.VersionTLS13}
+
+var tlsCurves = []tls.CurveID{tls.X25519MLKEM768, tls.X25519, tls.CurveP256, tls.CurveP384, tls.CurveP521}
+
+var alpnProtocols = []string{"h2", "http/1.1"}
+
+func init() {
+	RegisterCheck("tls_cipher_suites", "Enumerates TLS versions, cipher suites, curves and ALPN and grades the configuration", checkTLSConfigPlugin)
+}
+
+// tlsAddress is the host:port TLS checks connect to: the explicit port of an https
+// URL, and 443 otherwise. The port of a plain http URL serves no TLS.
+func tlsAddress(url string) (string, string) {
+	host := extractDomain(url)
+	port := "443"
+	if u, err := neturl.Parse(normalizeURL(url)); err == nil && u.Scheme == "https" && u.Port() != "" {
+		port = u.Port()
+	}
+	return host, net.JoinHostPort(host, port)
+}
+
+// tlsProbe performs one handshake with config and returns the negotiated state
+func tlsProbe(ctx context.Context, addr string, config *tls.Config) (tls.ConnectionState, error) {
+	probeCtx, cancel := context.WithTimeout(ctx, tlsProbeTimeout)
+	defer cancel()
+
+	config.InsecureSkipVerify = true
+	conn, err := scanDialTLS(probeCtx, addr, config)
+	if err != nil {
+		return tls.ConnectionState{}, err
+	}
+	defer conn.Close()
+	return conn.ConnectionState(), nil
+}
+
+// probeEach runs fn for every index in [0, n) with bounded concurrency
+func probeEach(n int, fn func(i int)) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, tlsProbeConcurrency)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+func checkTLSConfigPlugin(ctx context.Context, url string) interface{} {
+	host, addr := tlsAddress(url)
+	if host == "" {
+		return map[string]string{"error": "Invalid domain"}
+	}
+
+	// Protocol versions
+	accepted := make([]bool, len(tlsVersions))
+	probeEach(len(tlsVersions), func(i int) {
+		v := tlsVersions[i]
+		// Offer every suite crypto/tls implements, so servers that only speak legacy
+		// suites are still detected
+		_, err := tlsProbe(ctx, addr, &tls.Config{MinVersion: v, MaxVersion: v, CipherSuites: allCipherSuiteIDs()})
+		accepted[i] = err == nil
+	})
+
+	protocols := make(map[string]string)
+	var highestLegacy uint16
+	supports13 := false
+	for i, v := range tlsVersions {
+		state := "disabled"
+		if accepted[i] {
+			state = "enabled"
+			if v == tls.VersionTLS13 {
+				supports13 = true
+			} else {
+				highestLegacy = v
+			}
+		}
+		protocols[tls.VersionName(v)] = state
+	}
+	if highestLegacy == 0 && !supports13 {
+		return map[string]string{"error": "No TLS handshake succeeded on " + addr}
+	}
+
+	result := map[string]interface{}{
+		"Address":   addr,
+		"Protocols": protocols,
+	}
+
+	// Cipher suites of the highest pre-1.3 version, in the order the server picks them
+	var suites []*tls.CipherSuite
+	if highestLegacy != 0 {
+		suites = enumerateCipherSuites(ctx, addr, highestLegacy)
+		names := make([]string, 0, len(suites))
+		for _, s := range suites {
+			names = append(names, s.Name)
+		}
+		result["Cipher Suites"] = names
+	}
+
+	// TLS 1.3 suites are not configurable in crypto/tls; report the negotiated one
+	maxVersion := highestLegacy
+	if supports13 {
+		maxVersion = tls.VersionTLS13
+		if state, err := tlsProbe(ctx, addr, &tls.Config{MinVersion: tls.VersionTLS13}); err == nil {
+			result["TLS 1.3 Cipher Suite"] = tls.CipherSuiteName(state.CipherSuite)
+		}
+	}
+
+	curves := make([]bool, len(tlsCurves))
+	probeEach(len(tlsCurves), func(i int) {
+		_, err := tlsProbe(ctx, addr, &tls.Config{
+			MinVersion:       tls.VersionTLS10,
+			MaxVersion:       maxVersion,
+			CurvePreferences: []tls.CurveID{tlsCurves[i]},
+		})
+		curves[i] = err == nil
+	})
+	var curveNames []string
+	for i, ok := range curves {
+		if ok {
+			curveNames = append(curveNames, tlsCurves[i].String())
+		}
+	}
+	result["Curves"] = curveNames
+
+	alpn := make([]string, len(alpnProtocols))
+	probeEach(len(alpnProtocols), func(i int) {
+		state, err := tlsProbe(ctx, addr, &tls.Config{
+			MinVersion: tls.VersionTLS10,
+			MaxVersion: maxVersion,
+			NextProtos: []string{alpnProtocols[i]},
+		})
+		if err == nil {
+			alpn[i] = state.NegotiatedProtocol
+		}
+	})
+	var alpnNames []string
+	for _, proto := range alpn {
+		if proto != "" {
+			alpnNames = append(alpnNames, proto)
+		}
+	}
+	result["ALPN"] = alpnNames
+
+	grade, findings := gradeTLS(protocols, suites, supports13)
+	result["Grade"] = grade
+	if len(findings) > 0 {
+		sortFindings(findings)
+		result["findings"] = findings
+	}
+	return result
+}
+
+// enumerateCipherSuites finds every suite crypto/tls can offer that the server
+// accepts at version, then orders them by repeatedly offering all remaining suites
+// and removing the one the server picked. crypto/tls ignores the client's suite
+// order, so the result reflects the server's preference when it enforces one.
+func enumerateCipherSuites(ctx context.Context, addr string, version uint16) []*tls.CipherSuite {
+	var candidates []*tls.CipherSuite
+	for _, s := range allCipherSuites() {
+		for _, v := range s.SupportedVersions {
+			if v == version {
+				candidates = append(candidates, s)
+				break
+			}
+		}
+	}
+
+	ok := make([]bool, len(candidates))
+	probeEach(len(candidates), func(i int) {
+		_, err := tlsProbe(ctx, addr, &tls.Config{
+			MinVersion:   version,
+			MaxVersion:   version,
+			CipherSuites: []uint16{candidates[i].ID},
+		})
+		ok[i] = err == nil
+	})
+
+	remaining := make(map[uint16]*tls.CipherSuite)
+	for i, s := range candidates {
+		if ok[i] {
+			remaining[s.ID] = s
+		}
+	}
+
+	var ordered []*tls.CipherSuite
+	for len(remaining) > 0 {
+		ids := make([]uint16, 0, len(remaining))
+		for id := range remaining {
+			ids = append(ids, id)
+		}
+		state, err := tlsProbe(ctx, addr, &tls.Config{MinVersion: version, MaxVersion: version, CipherSuites: ids})
+		chosen, found := remaining[state.CipherSuite]
+		if err != nil || !found {
+			// Keep whatever is left in the order crypto/tls lists it
+			for _, s := range candidates {
+				if remaining[s.ID] != nil {
+					ordered = append(ordered, s)
+				}
+			}
+			break
+		}
+		ordered = append(ordered, chosen)
+		delete(remaining, chosen.ID)
+	}
+	return ordered
+}
+
+func allCipherSuites() []*tls.CipherSuite {
+	return append(tls.CipherSuites(), tls.InsecureCipherSuites()...)
+}
+
+func allCipherSuiteIDs() []uint16 {
+	var ids []uint16
+	for _, s := range allCipherSuites() {
+		ids = append(ids, s.ID)
+	}
+	return ids
+}
+
+// gradeTLS rates the configuration from A to F. Each problem caps the grade:
+// no TLS 1.2 or newer F, RC4 D, 3DES C, TLS 1.0/1.1 or suites without forward
+// secrecy B. CBC suites and missing TLS 1.3 only produce findings.
+func gradeTLS(protocols map[string]string, suites []*tls.CipherSuite, supports13 bool) (string, []Finding) {
+	grade := 'A'
+	capGrade := func(g rune) {
+		if g > grade {
+			grade = g
+		}
+	}
+
+	var findings []Finding
+	if protocols["TLS 1.2"] != "enabled" && !supports13 {
+		capGrade('F')
+		findings = append(findings, Finding{
+			Severity:       SeverityCritical,
+			Title:          "No modern TLS version supported",
+			Detail:         "The server only accepts TLS 1.0/1.1, which are deprecated (RFC 8996) and rejected by current browsers.",
+			Recommendation: "Enable TLS 1.2 and TLS 1.3.",
+		})
+	}
+
+	var legacy []string
+	for _, v := range []string{"TLS 1.0", "TLS 1.1"} {
+		if protocols[v] == "enabled" {
+			legacy = append(legacy, v)
+		}
+	}
+	if len(legacy) > 0 {
+		capGrade('B')
+		findings = append(findings, Finding{
+			Severity:       SeverityMedium,
+			Title:          "Deprecated TLS versions enabled",
+			Detail:         fmt.Sprintf("The server accepts %s.", strings.Join(legacy, " and ")),
+			Recommendation: "Disable TLS 1.0 and 1.1; allow only TLS 1.2 and 1.3.",
+		})
+	}
+
+	if !supports13 && protocols["TLS 1.2"] == "enabled" {
+		findings = append(findings, Finding{
+			Severity:       SeverityLow,
+			Title:          "TLS 1.3 not supported",
+			Recommendation: "Enable TLS 1.3 for faster handshakes and only forward-secret AEAD suites.",
+		})
+	}
+
+	var rc4, tripleDES, noPFS, cbc []string
+	for _, s := range suites {
+		switch {
+		case strings.Contains(s.Name, "RC4"):
+			rc4 = append(rc4, s.Name)
+		case strings.Contains(s.Name, "3DES"):
+			tripleDES = append(tripleDES, s.Name)
+		}
+		if !strings.HasPrefix(s.Name, "TLS_ECDHE_") {
+			noPFS = append(noPFS, s.Name)
+		}
+		if strings.Contains(s.Name, "_CBC_") {
+			cbc = append(cbc, s.Name)
+		}
+	}
+
+	if len(rc4) > 0 {
+		capGrade('D')
+		findings = append(findings, Finding{
+			Severity:       SeverityHigh,
+			Title:          "RC4 cipher suites accepted",
+			Detail:         strings.Join(rc4, ", "),
+			Recommendation: "Remove RC4 suites; RC4 is broken (RFC 7465).",
+		})
+	}
+	if len(tripleDES) > 0 {
+		capGrade('C')
+		findings = append(findings, Finding{
+			Severity:       SeverityMedium,
+			Title:          "3DES cipher suites accepted",
+			Detail:         strings.Join(tripleDES, ", ") + " (vulnerable to SWEET32).",
+			Recommendation: "Remove 3DES suites.",
+		})
+	}
+	if len(noPFS) > 0 {
+		capGrade('B')
+		findings = append(findings, Finding{
+			Severity:       SeverityMedium,
+			Title:          "Cipher suites without forward secrecy",
+			Detail:         strings.Join(noPFS, ", "),
+			Recommendation: "Offer only ECDHE key exchange so past traffic stays safe if the key leaks.",
+		})
+	}
+	if len(cbc) > 0 {
+		findings = append(findings, Finding{
+			Severity:       SeverityLow,
+			Title:          "CBC mode cipher suites accepted",
+			Detail:         strings.Join(cbc, ", "),
+			Recommendation: "Prefer AES-GCM and ChaCha20-Poly1305 suites.",
+		})
+	}
+
+	return string(grade), findings
+}
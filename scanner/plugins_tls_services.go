@@ -0,0 +1,144 @@
+package scanner
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// plaintextPorts are open ports from the port scan that never speak TLS directly and
+// have no STARTTLS support here, so no handshake is attempted on them
+var plaintextPorts = map[int]bool{22: true, 23: true, 53: true, 80: true, 445: true, 3306: true, 6379: true, 8080: true}
+
+func init() {
+	RegisterCheck("tls_services", "Inventories TLS certificates on every open port, including STARTTLS mail, FTP and PostgreSQL", checkTLSServicesPlugin)
+}
+
+func checkTLSServicesPlugin(ctx context.Context, url string) interface{} {
+	host, urlAddr := tlsAddress(url)
+	if host == "" {
+		return map[string]string{"error": "Invalid domain"}
+	}
+	_, rawPort, _ := net.SplitHostPort(urlAddr)
+	urlPort, _ := strconv.Atoi(rawPort)
+
+	candidates := map[int]bool{urlPort: true}
+	// Reuse the port scan of this run; if the scope excludes it, only the URL port is checked
+	if scope := currentScope(); scope == nil || scope.AllowsCheck("open_ports") {
+		for _, p := range openPorts(ctx, host) {
+			if !plaintextPorts[p] {
+				candidates[p] = true
+			}
+		}
+	}
+
+	ports := make([]int, 0, len(candidates))
+	for p := range candidates {
+		ports = append(ports, p)
+	}
+	sort.Ints(ports)
+
+	entries := make([]map[string]interface{}, len(ports))
+	portFindings := make([][]Finding, len(ports))
+	probeEach(len(ports), func(i int) {
+		entries[i], portFindings[i] = inspectTLSService(ctx, host, ports[i], ports[i] == urlPort)
+	})
+
+	result := make(map[string]interface{})
+	var findings []Finding
+	for i, p := range ports {
+		if entries[i] == nil {
+			continue
+		}
+		result[strconv.Itoa(p)] = entries[i]
+		// ssl_certificate already reports on the URL's own port
+		if p != urlPort {
+			findings = append(findings, portFindings[i]...)
+		}
+	}
+	if len(findings) > 0 {
+		sortFindings(findings)
+		result["findings"] = findings
+	}
+	return result
+}
+
+// inspectTLSService handshakes with one port, directly or via STARTTLS, and
+// describes the certificate it presents. A nil entry means the port does not speak
+// TLS and is not worth reporting.
+func inspectTLSService(ctx context.Context, host string, port int, explicit bool) (map[string]interface{}, []Finding) {
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	probeCtx, cancel := context.WithTimeout(ctx, tlsProbeTimeout)
+	defer cancel()
+
+	config := &tls.Config{InsecureSkipVerify: true, MinVersion: tls.VersionTLS10}
+	mode := "TLS"
+	protocol, starttls := starttlsPorts[port]
+
+	var conn *tls.Conn
+	var err error
+	if starttls {
+		mode = "STARTTLS (" + strings.ToUpper(protocol) + ")"
+		conn, err = dialSTARTTLS(probeCtx, addr, protocol, config)
+	} else {
+		conn, err = scanDialTLS(probeCtx, addr, config)
+	}
+
+	if err != nil {
+		if errors.Is(err, ErrSTARTTLSNotOffered) {
+			return map[string]interface{}{"Mode": mode, "Status": "not offered"}, []Finding{{
+				Severity:       SeverityMedium,
+				Title:          fmt.Sprintf("Port %d (%s) does not offer STARTTLS", port, strings.ToUpper(protocol)),
+				Detail:         "Credentials and data on this service travel in plaintext.",
+				Recommendation: "Enable STARTTLS, or implicit TLS on the service's TLS port.",
+			}}
+		}
+		if starttls || explicit {
+			return map[string]interface{}{"Mode": mode, "Status": "handshake failed"}, nil
+		}
+		return nil, nil
+	}
+	defer conn.Close()
+
+	state := conn.ConnectionState()
+	if len(state.PeerCertificates) == 0 {
+		return map[string]interface{}{"Mode": mode, "Status": "no certificate"}, nil
+	}
+
+	cert := state.PeerCertificates[0]
+	now := time.Now()
+	trusted, hostnameOK, chainFindings := verifyChain(state.PeerCertificates, host, now)
+	entry := map[string]interface{}{
+		"Mode":                mode,
+		"Protocol":            tls.VersionName(state.Version),
+		"Cipher Suite":        tls.CipherSuiteName(state.CipherSuite),
+		"Subject":             cert.Subject.CommonName,
+		"Issuer":              cert.Issuer.CommonName,
+		"Expires":             cert.NotAfter.Format(time.RFC822),
+		"Days Until Expiry":   int(math.Floor(cert.NotAfter.Sub(now).Hours() / 24)),
+		"Trusted":             fmt.Sprintf("%t", trusted),
+		"Hostname Match":      fmt.Sprintf("%t", hostnameOK),
+		"Fingerprint SHA-256": describeCertificate(cert).FingerprintSHA256,
+	}
+
+	findings := append(certExpiryFindings(cert.NotBefore, cert.NotAfter, now), chainFindings...)
+	if state.Version < tls.VersionTLS12 {
+		findings = append(findings, Finding{
+			Severity:       SeverityMedium,
+			Title:          "Deprecated TLS version negotiated",
+			Detail:         fmt.Sprintf("The best protocol the service offers is %s.", tls.VersionName(state.Version)),
+			Recommendation: "Enable TLS 1.2 and 1.3 on this service.",
+		})
+	}
+	for i := range findings {
+		findings[i].Title = fmt.Sprintf("Port %d: %s", port, findings[i].Title)
+	}
+	return entry, findings
+}
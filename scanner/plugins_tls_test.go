@@ -0,0 +1,181 @@
+package scanner
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+// pinnedTLSServer serves HTTPS with the httptest certificate and config's versions
+// and suites
+func pinnedTLSServer(t *testing.T, config *tls.Config) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(http.NotFoundHandler())
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.TLS = config
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func suitesNamed(t *testing.T, names ...string) []*tls.CipherSuite {
+	t.Helper()
+	var suites []*tls.CipherSuite
+	for _, name := range names {
+		i := slices.IndexFunc(allCipherSuites(), func(s *tls.CipherSuite) bool { return s.Name == name })
+		if i < 0 {
+			t.Fatalf("unknown cipher suite %s", name)
+		}
+		suites = append(suites, allCipherSuites()[i])
+	}
+	return suites
+}
+
+func findingTitles(findings []Finding) []string {
+	var titles []string
+	for _, f := range findings {
+		titles = append(titles, f.Title)
+	}
+	return titles
+}
+
+func TestGradeTLS(t *testing.T) {
+	modern := map[string]string{"TLS 1.0": "disabled", "TLS 1.1": "disabled", "TLS 1.2": "enabled", "TLS 1.3": "enabled"}
+	legacy := map[string]string{"TLS 1.0": "enabled", "TLS 1.1": "enabled", "TLS 1.2": "enabled", "TLS 1.3": "disabled"}
+	oldOnly := map[string]string{"TLS 1.0": "enabled", "TLS 1.1": "disabled", "TLS 1.2": "disabled", "TLS 1.3": "disabled"}
+	gcm := "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"
+
+	tests := []struct {
+		name       string
+		protocols  map[string]string
+		suites     []string
+		supports13 bool
+		grade      string
+		titles     []string
+	}{
+		{"modern", modern, []string{gcm}, true, "A", nil},
+		{"no TLS 1.3", map[string]string{"TLS 1.2": "enabled"}, []string{gcm}, false, "A", []string{"TLS 1.3 not supported"}},
+		{"legacy versions", legacy, []string{gcm}, false, "B", []string{"Deprecated TLS versions enabled", "TLS 1.3 not supported"}},
+		{"no forward secrecy", modern, []string{gcm, "TLS_RSA_WITH_AES_128_GCM_SHA256"}, true, "B", []string{"Cipher suites without forward secrecy"}},
+		{"3DES", modern, []string{gcm, "TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA"}, true, "C", []string{"3DES cipher suites accepted"}},
+		{"RC4", modern, []string{"TLS_ECDHE_RSA_WITH_RC4_128_SHA"}, true, "D", []string{"RC4 cipher suites accepted"}},
+		{"only TLS 1.0", oldOnly, []string{gcm}, false, "F", []string{"No modern TLS version supported", "Deprecated TLS versions enabled"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			grade, findings := gradeTLS(tt.protocols, suitesNamed(t, tt.suites...), tt.supports13)
+			if grade != tt.grade {
+				t.Errorf("grade = %s, want %s (findings %q)", grade, tt.grade, findingTitles(findings))
+			}
+			titles := findingTitles(findings)
+			for _, want := range tt.titles {
+				if !slices.Contains(titles, want) {
+					t.Errorf("findings %q lack %q", titles, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTLSConfigPluginAgainstLocalServers(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    *tls.Config
+		protocols map[string]string
+		suites    []string
+		grade     string
+	}{
+		{
+			name:      "TLS 1.3 only",
+			config:    &tls.Config{MinVersion: tls.VersionTLS13},
+			protocols: map[string]string{"TLS 1.0": "disabled", "TLS 1.1": "disabled", "TLS 1.2": "disabled", "TLS 1.3": "enabled"},
+			grade:     "A",
+		},
+		{
+			name: "TLS 1.2 with forward secrecy",
+			config: &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				MaxVersion:   tls.VersionTLS12,
+				CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384, tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+			},
+			protocols: map[string]string{"TLS 1.0": "disabled", "TLS 1.1": "disabled", "TLS 1.2": "enabled", "TLS 1.3": "disabled"},
+			suites:    []string{"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384", "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"},
+			grade:     "A",
+		},
+		{
+			name: "TLS 1.0 to 1.2 with RSA key exchange",
+			config: &tls.Config{
+				MinVersion:   tls.VersionTLS10,
+				MaxVersion:   tls.VersionTLS12,
+				CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_RSA_WITH_AES_128_CBC_SHA},
+			},
+			protocols: map[string]string{"TLS 1.0": "enabled", "TLS 1.1": "enabled", "TLS 1.2": "enabled", "TLS 1.3": "disabled"},
+			suites:    []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", "TLS_RSA_WITH_AES_128_CBC_SHA"},
+			grade:     "B",
+		},
+		{
+			name: "3DES",
+			config: &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				MaxVersion:   tls.VersionTLS12,
+				CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA},
+			},
+			protocols: map[string]string{"TLS 1.0": "disabled", "TLS 1.1": "disabled", "TLS 1.2": "enabled", "TLS 1.3": "disabled"},
+			suites:    []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", "TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA"},
+			grade:     "C",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := pinnedTLSServer(t, tt.config)
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			result, ok := checkTLSConfigPlugin(ctx, srv.URL).(map[string]interface{})
+			if !ok {
+				t.Fatalf("unexpected result %#v", checkTLSConfigPlugin(ctx, srv.URL))
+			}
+			protocols := result["Protocols"].(map[string]string)
+			for version, want := range tt.protocols {
+				if protocols[version] != want {
+					t.Errorf("%s = %s, want %s", version, protocols[version], want)
+				}
+			}
+			// crypto/tls servers pick suites in their own order, so compare them as sets
+			if tt.suites != nil {
+				got, _ := result["Cipher Suites"].([]string)
+				got = slices.Sorted(slices.Values(got))
+				if !slices.Equal(got, slices.Sorted(slices.Values(tt.suites))) {
+					t.Errorf("Cipher Suites = %v, want %v", got, tt.suites)
+				}
+			}
+			if result["Grade"] != tt.grade {
+				t.Errorf("Grade = %v, want %s", result["Grade"], tt.grade)
+			}
+		})
+	}
+}
+
+func TestTLSAddress(t *testing.T) {
+	tests := []struct {
+		url, host, addr string
+	}{
+		{"https://example.com", "example.com", "example.com:443"},
+		{"https://example.com:8443/path", "example.com", "example.com:8443"},
+		{"http://example.com", "example.com", "example.com:443"},
+		{"http://example.com:8080/", "example.com", "example.com:443"},
+		{"example.com:8080", "example.com", "example.com:443"},
+	}
+	for _, tt := range tests {
+		host, addr := tlsAddress(tt.url)
+		if host != tt.host || addr != tt.addr {
+			t.Errorf("tlsAddress(%q) = %q, %q, want %q, %q", tt.url, host, addr, tt.host, tt.addr)
+		}
+	}
+}
@@ -23,7 +23,7 @@ func checkWaybackPlugin(ctx context.Context, url string) interface{} {
 		return map[string]string{"error": "Request failed"}
 	}
 
-	resp, err := pluginClient.Do(req)
+	resp, err := osintClient.Do(req)
 	if err != nil {
 		return map[string]string{"error": "Archive API unreachable"}
 	}
@@ -0,0 +1,121 @@
+package scanner
+
+import (
+	"net/http"
+	neturl "net/url"
+	"sort"
+	"strings"
+)
+
+// recurseScope decides which discovered hosts may be fed back into the scan queue
+type recurseScope struct {
+	roots map[string]bool
+	allow []string
+}
+
+var redirectProbeClient = &http.Client{
+	Transport: pluginClient.Transport,
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+func newRecurseScope(urls []string, allow []string) *recurseScope {
+	scope := &recurseScope{roots: make(map[string]bool)}
+	for _, u := range urls {
+		if domain := registrableDomain(extractDomain(normalizeURL(u))); domain != "" {
+			scope.roots[domain] = true
+		}
+	}
+	for _, entry := range allow {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		entry = strings.TrimPrefix(entry, "*.")
+		entry = strings.TrimPrefix(entry, ".")
+		if entry != "" {
+			scope.allow = append(scope.allow, entry)
+		}
+	}
+	return scope
+}
+
+// allows reports whether host shares a registrable domain with one of the original
+// targets or falls under an explicitly allowlisted domain.
+func (s *recurseScope) allows(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "" {
+		return false
+	}
+	if s.roots[registrableDomain(host)] {
+		return true
+	}
+	for _, entry := range s.allow {
+		if host == entry || strings.HasSuffix(host, "."+entry) {
+			return true
+		}
+	}
+	return false
+}
+
+// discoverAssets extracts host names from plugin results that are worth scanning on
+// their own: enumerated subdomains, TLS SAN names, MX/NS hosts and redirect targets.
+func discoverAssets(target string, results map[string]interface{}) []string {
+	self := strings.ToLower(extractDomain(target))
+	seen := map[string]bool{self: true}
+	var hosts []string
+
+	add := func(name string) {
+		name = strings.ToLower(strings.TrimSpace(name))
+		name = strings.TrimSuffix(name, ".")
+		name = strings.TrimPrefix(name, "*.")
+		if name == "" || seen[name] || strings.ContainsAny(name, " /@") {
+			return
+		}
+		seen[name] = true
+		hosts = append(hosts, name)
+	}
+
+	for name := range toAnyMap(results["subdomains"]) {
+		if name != "error" {
+			add(name)
+		}
+	}
+
+	if ssl := toStringMap(results["ssl_certificate"]); ssl != nil {
+		for _, name := range strings.Split(ssl["SANs"], ",") {
+			add(name)
+		}
+	}
+
+	dns := toAnyMap(results["dns_records"])
+	for _, key := range []string{"MX", "NS"} {
+		for _, name := range toStringList(dns[key]) {
+			add(name)
+		}
+	}
+
+	for _, next := range redirectHosts(results) {
+		add(next)
+	}
+
+	return hosts
+}
+
+// redirectHosts returns the hosts the redirect chains recorded by the redirects check
+// point at, so recursion does not send requests of its own outside the scan
+func redirectHosts(results map[string]interface{}) []string {
+	var hosts []string
+	redirects := toAnyMap(results["redirects"])
+	for _, described := range toAnyMap(redirects["Chains"]) {
+		for _, line := range toStringList(toAnyMap(described)["Hops"]) {
+			_, location, ok := strings.Cut(line, " -> ")
+			if !ok {
+				continue
+			}
+			if loc, err := neturl.Parse(location); err == nil && loc.Hostname() != "" {
+				hosts = append(hosts, loc.Hostname())
+			}
+		}
+	}
+	sort.Strings(hosts)
+	return hosts
+}
@@ -0,0 +1,70 @@
+package scanner
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRecurseScopeAllows(t *testing.T) {
+	scope := newRecurseScope([]string{"https://www.example.com/app", "shop.example.co.uk"}, []string{"*.partner.test", " .CDN.example.net "})
+
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"example.com", true},
+		{"api.example.com", true},
+		{"API.Example.com.", true},
+		{"other.example.co.uk", true},
+		// co.uk is a public suffix, not the targets' domain
+		{"evil.co.uk", false},
+		{"partner.test", true},
+		{"login.partner.test", true},
+		{"notpartner.test", false},
+		{"img.cdn.example.net", true},
+		{"example.net", false},
+		{"example.org", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := scope.allows(tt.host); got != tt.want {
+			t.Errorf("allows(%q) = %t, want %t", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestDiscoverAssets(t *testing.T) {
+	results := map[string]interface{}{
+		"subdomains": map[string]interface{}{
+			"api.example.com": []string{"192.0.2.1"},
+			"www.example.com": []string{"192.0.2.2"},
+			"error":           "partial",
+		},
+		"ssl_certificate": map[string]interface{}{"SANs": []string{"*.example.com", "Mail.Example.com.", "www.example.com"}},
+		"dns_records": map[string]interface{}{
+			"MX": []string{"mx1.example.net."},
+			"NS": []string{"ns1.example.com.", "bad name"},
+		},
+		"redirects": map[string]interface{}{
+			"Final URL": "https://login.example.org/",
+			"Chains": map[string]interface{}{
+				"http://www.example.com": map[string]interface{}{
+					"Hops": []string{
+						"301 http://www.example.com -> https://www.example.com/",
+						"302 https://www.example.com/ -> https://login.example.org/",
+						"200 https://login.example.org/",
+					},
+				},
+			},
+		},
+	}
+	want := []string{"api.example.com", "example.com", "mail.example.com", "mx1.example.net", "ns1.example.com", "login.example.org"}
+
+	// The target itself is never rediscovered; results may also come back from JSON
+	for _, res := range []map[string]interface{}{results, roundTrip(t, results)} {
+		got := discoverAssets("http://www.example.com", res)
+		if !slices.Equal(slices.Sorted(slices.Values(got)), slices.Sorted(slices.Values(want))) {
+			t.Errorf("discoverAssets = %q, want %q", got, want)
+		}
+	}
+}
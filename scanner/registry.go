@@ -16,12 +16,23 @@ type CheckFunc func(ctx context.Context, url string) interface{}
 type CheckDefinition struct {
 	Name        string
 	Description string
-	Execute     CheckFunc
+	// Intrusive checks send large numbers of requests; they only run once enabled
+	// with SetIntrusiveChecks
+	Intrusive bool
+	Execute   CheckFunc
 }
 
 // registry holds all the registered plugins
 var registry = make(map[string]CheckDefinition)
 
+var (
+	runSettingsMu sync.RWMutex
+	// The Vercel Free Tier hard limits at 10 seconds, so scans default to 8 seconds to
+	// allow JSON serialization time
+	scanTimeout      = 8 * time.Second
+	intrusiveEnabled bool
+)
+
 // RegisterCheck allows a plugin to register itself in its init() func
 func RegisterCheck(name, description string, check CheckFunc) {
 	registry[name] = CheckDefinition{
@@ -31,34 +42,88 @@ func RegisterCheck(name, description string, check CheckFunc) {
 	}
 }
 
+// RegisterIntrusiveCheck registers a check that is skipped unless intrusive checks
+// are enabled
+func RegisterIntrusiveCheck(name, description string, check CheckFunc) {
+	RegisterCheck(name, description, check)
+	def := registry[name]
+	def.Intrusive = true
+	registry[name] = def
+}
+
+// SetScanTimeout sets how long all checks of one target may run in total. Values of
+// zero or less restore the default.
+func SetScanTimeout(timeout time.Duration) {
+	runSettingsMu.Lock()
+	defer runSettingsMu.Unlock()
+	if timeout <= 0 {
+		timeout = 8 * time.Second
+	}
+	scanTimeout = timeout
+}
+
+// SetIntrusiveChecks enables or disables checks registered as intrusive
+func SetIntrusiveChecks(enabled bool) {
+	runSettingsMu.Lock()
+	defer runSettingsMu.Unlock()
+	intrusiveEnabled = enabled
+}
+
+func runSettings() (time.Duration, bool) {
+	runSettingsMu.RLock()
+	defer runSettingsMu.RUnlock()
+	return scanTimeout, intrusiveEnabled
+}
+
+// RunCheck runs a single registered check against url with the same timeout, scope
+// and intrusive-check rules as a full scan. It returns false if no check of that name
+// exists or it may not run.
+func RunCheck(name, url string) (interface{}, bool) {
+	check, ok := registry[name]
+	if !ok {
+		return nil, false
+	}
+	if scope := currentScope(); scope != nil && !scope.AllowsCheck(name) {
+		return nil, false
+	}
+	timeout, intrusive := runSettings()
+	if check.Intrusive && !intrusive {
+		return nil, false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return executeCheck(ctx, name, url, check), true
+}
+
 // RunAllChecks executes all registered plugins concurrently with Vercel safety guarantees
 func RunAllChecks(url string) map[string]interface{} {
 	results := make(map[string]interface{})
 	var mu sync.Mutex
 	var wg sync.WaitGroup
 
-	// Vercel Serverless Safety Guarantee 1: Strict Global Timeout
-	// The Vercel Free Tier hard limits at 10 seconds. We use 8 seconds to allow JSON serialization time.
-	ctx, cancel := context.WithTimeout(context.Background(), 8*time.Second)
+	// Vercel Serverless Safety Guarantee 1: Strict Global Timeout (see scanTimeout)
+	timeout, intrusive := runSettings()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
+	// Blocked connection attempts are collected per scan and reported alongside the results
+	ctx, violations := withViolationLog(ctx)
+	ctx = withScanMemo(ctx)
+	scope := currentScope()
+
 	for key, check := range registry {
+		if scope != nil && !scope.AllowsCheck(key) {
+			continue
+		}
+		if check.Intrusive && !intrusive {
+			continue
+		}
+
 		wg.Add(1)
 		go func(k string, chk CheckDefinition) {
 			defer wg.Done()
-
-			// Vercel Serverless Safety Guarantee 2: Graceful Panic Recovery
-			defer func() {
-				if r := recover(); r != nil {
-					color.Red("[-] Plugin Panic (%s): %v", k, r)
-					mu.Lock()
-					results[k] = map[string]string{"error": fmt.Sprintf("Plugin execution crashed: %v", r)}
-					mu.Unlock()
-				}
-			}()
-
-			// Execute the check, passing the context down so plugins can abort network calls if time runs out
-			res := chk.Execute(ctx, url)
+			res := executeCheck(ctx, k, url, chk)
 
 			mu.Lock()
 			results[k] = res
@@ -69,5 +134,23 @@ func RunAllChecks(url string) map[string]interface{} {
 	// Wait for all plugins to finish OR the global timeout context to expire inside the plugins
 	wg.Wait()
 
+	if blocked := violations.list(); len(blocked) > 0 {
+		results["scope_violations"] = blocked
+	}
+
 	return results
 }
+
+// executeCheck runs one plugin, turning a panic into an error result
+func executeCheck(ctx context.Context, name, url string, check CheckDefinition) (res interface{}) {
+	// Vercel Serverless Safety Guarantee 2: Graceful Panic Recovery
+	defer func() {
+		if r := recover(); r != nil {
+			color.Red("[-] Plugin Panic (%s): %v", name, r)
+			res = map[string]string{"error": fmt.Sprintf("Plugin execution crashed: %v", r)}
+		}
+	}()
+
+	// Execute the check, passing the context down so plugins can abort network calls if time runs out
+	return check.Execute(context.WithValue(ctx, checkNameKey, name), url)
+}
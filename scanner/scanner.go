@@ -1,7 +1,9 @@
 package scanner
 
 import (
+	"context"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 
@@ -12,22 +14,73 @@ var (
 	printMu sync.Mutex
 )
 
+// ScanOptions controls how the CLI scanning engine schedules targets
+type ScanOptions struct {
+	Threads int
+
+	// Recurse feeds in-scope hosts discovered by the plugins (subdomains, TLS SANs,
+	// MX/NS records, redirect targets) back into the worker queue, up to MaxDepth hops
+	// away from the original targets.
+	Recurse  bool
+	MaxDepth int
+
+	// RecurseAllow lists extra domains that recursion may expand into, on top of the
+	// registrable domains of the original targets. Entries match the domain itself
+	// and all of its subdomains.
+	RecurseAllow []string
+
+	// OnResult, when set, is called from the workers with each finished scan, e.g. to
+	// persist it. It must be safe for concurrent use.
+	OnResult func(url string, results map[string]interface{})
+
+	// BaselineFor, when set, returns a previous scan of url. Targets with a baseline
+	// only print findings that are new compared to it instead of the full results.
+	BaselineFor func(url string) map[string]interface{}
+}
+
+type scanJob struct {
+	url   string
+	depth int
+}
+
+// scanQueue is the worker queue shared by the CLI workers. It deduplicates targets
+// and keeps track of outstanding jobs so it can be closed once recursion settles.
+type scanQueue struct {
+	mu      sync.Mutex
+	seen    map[string]bool
+	hosts   map[string]bool
+	pending sync.WaitGroup
+	jobs    chan scanJob
+}
+
 // RunScan is the entry point for the scanning engine
-func RunScan(urls []string, threads int) {
+func RunScan(urls []string, opts ScanOptions) {
 	color.Cyan("[*] Engine initialized. Scanners warming up...")
 
-	urlChan := make(chan string, len(urls))
-	var wg sync.WaitGroup
+	if opts.Threads < 1 {
+		opts.Threads = 1
+	}
 
-	for i := 0; i < threads; i++ {
+	queue := &scanQueue{
+		seen:  make(map[string]bool),
+		hosts: make(map[string]bool),
+		jobs:  make(chan scanJob, len(urls)),
+	}
+	scope := newRecurseScope(urls, opts.RecurseAllow)
+
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Threads; i++ {
 		wg.Add(1)
-		go worker(&wg, urlChan)
+		go worker(&wg, queue, scope, opts)
 	}
 
 	for _, url := range urls {
-		urlChan <- url
+		queue.add(url, 0, false)
 	}
-	close(urlChan)
+
+	// Recursion may keep adding jobs, so only close the queue once nothing is in flight
+	queue.pending.Wait()
+	close(queue.jobs)
 
 	wg.Wait()
 
@@ -35,44 +88,150 @@ func RunScan(urls []string, threads int) {
 	color.Green("[+] Scan complete. Hawk is returning to nest.")
 }
 
-func worker(wg *sync.WaitGroup, urlChan <-chan string) {
+// add enqueues a target unless it was already scanned. Discovered targets are also
+// skipped when their host has been scanned under any URL.
+func (q *scanQueue) add(unparsedURL string, depth int, discovered bool) bool {
+	url := normalizeURL(unparsedURL)
+	host := strings.ToLower(extractDomain(url))
+
+	q.mu.Lock()
+	if q.seen[url] || (discovered && q.hosts[host]) {
+		q.mu.Unlock()
+		return false
+	}
+	q.seen[url] = true
+	q.hosts[host] = true
+	q.mu.Unlock()
+
+	q.pending.Add(1)
+	go func() { q.jobs <- scanJob{url: url, depth: depth} }()
+	return true
+}
+
+func worker(wg *sync.WaitGroup, queue *scanQueue, scope *recurseScope, opts ScanOptions) {
 	defer wg.Done()
-	for unparsedURL := range urlChan {
-		url := normalizeURL(unparsedURL)
+	for job := range queue.jobs {
+		url := job.url
+
+		if !TargetInScope(context.Background(), url) {
+			printMu.Lock()
+			color.Red("\n[!] Skipping %s: out of scope", url)
+			printMu.Unlock()
+			queue.pending.Done()
+			continue
+		}
 
 		printMu.Lock()
-		color.Blue("\n[~] Scanning %s", url)
+		if job.depth > 0 {
+			color.Blue("\n[~] Scanning %s (discovered, depth %d)", url, job.depth)
+		} else {
+			color.Blue("\n[~] Scanning %s", url)
+		}
 		printMu.Unlock()
 
 		// Run all dynamic plugins
 		results := RunAllChecks(url)
 
+		var baseline map[string]interface{}
+		if opts.BaselineFor != nil {
+			baseline = opts.BaselineFor(url)
+		}
+
 		printMu.Lock()
-		for key, checkFunc := range registry {
-			res := results[key]
-
-			// Generic print logic based on data type returned
-			switch v := res.(type) {
-			case []string:
-				if len(v) > 0 {
-					color.Yellow("    [!] %s: %s", checkFunc.Name, strings.Join(v, ", "))
-				}
-			case string:
-				if v != "" {
-					color.Cyan("    [i] %s: %s", checkFunc.Name, v)
-				}
-			case map[string]string:
-				if len(v) > 0 {
-					color.Red("    [x] %s: Data Blocked or Errored", checkFunc.Name)
-				}
-			default:
-				// other types ignored in simple CLI printout for now, or print generic
-				if v != nil {
-					color.White("    [-] %s: Data Found", checkFunc.Name)
+		if baseline != nil {
+			printNewFindings(baseline, results)
+		} else {
+			printResults(results)
+		}
+		printMu.Unlock()
+
+		if opts.OnResult != nil {
+			opts.OnResult(url, results)
+		}
+
+		if opts.Recurse && job.depth < opts.MaxDepth {
+			var queued []string
+			for _, host := range discoverAssets(url, results) {
+				if scope.allows(host) && TargetInScope(context.Background(), host) && queue.add(host, job.depth+1, true) {
+					queued = append(queued, host)
 				}
 			}
+			if len(queued) > 0 {
+				printMu.Lock()
+				color.Green("    [+] Queued %d discovered in-scope hosts: %s", len(queued), strings.Join(queued, ", "))
+				printMu.Unlock()
+			}
 		}
-		printMu.Unlock()
+
+		queue.pending.Done()
+	}
+}
+
+// printResults is the default CLI printout of a finished scan. Callers hold printMu.
+func printResults(results map[string]interface{}) {
+	for key, checkFunc := range registry {
+		res := results[key]
+
+		// Generic print logic based on data type returned
+		switch v := res.(type) {
+		case []string:
+			if len(v) > 0 {
+				color.Yellow("    [!] %s: %s", checkFunc.Name, strings.Join(v, ", "))
+			}
+		case string:
+			if v != "" {
+				color.Cyan("    [i] %s: %s", checkFunc.Name, v)
+			}
+		case map[string]string:
+			if len(v) > 0 {
+				color.Red("    [x] %s: Data Blocked or Errored", checkFunc.Name)
+			}
+		default:
+			// other types ignored in simple CLI printout for now, or print generic
+			if v != nil {
+				color.White("    [-] %s: Data Found", checkFunc.Name)
+			}
+		}
+	}
+	if blocked, ok := results["scope_violations"].([]string); ok {
+		color.Red("    [!] Scope violations blocked: %s", strings.Join(blocked, ", "))
+	}
+	printFindings(CollectFindings(results))
+}
+
+// printFindings lists each check's findings, most severe first. Callers hold printMu.
+func printFindings(findings map[string][]Finding) {
+	checks := make([]string, 0, len(findings))
+	for check := range findings {
+		checks = append(checks, check)
+	}
+	sort.Strings(checks)
+
+	for _, check := range checks {
+		list := findings[check]
+		sortFindings(list)
+		for _, f := range list {
+			printer := color.Cyan
+			switch f.Severity {
+			case SeverityCritical, SeverityHigh:
+				printer = color.Red
+			case SeverityMedium:
+				printer = color.Yellow
+			}
+			printer("    [%s] %s: %s", strings.ToUpper(f.Severity), check, f.Title)
+		}
+	}
+}
+
+// printNewFindings prints only what appeared since the baseline scan. Callers hold printMu.
+func printNewFindings(baseline, results map[string]interface{}) {
+	findings := NewFindings(DiffResults(baseline, results))
+	if len(findings) == 0 {
+		color.Green("    [+] No new findings compared to baseline")
+		return
+	}
+	for _, f := range findings {
+		color.Yellow("    [!] NEW %s", f)
 	}
 }
 
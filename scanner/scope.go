@@ -0,0 +1,227 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrOutOfScope is returned by the shared HTTP/dial layer when a plugin tries to
+// reach a host or path that the active scope does not allow.
+var ErrOutOfScope = errors.New("target is out of scope")
+
+// Scope is a bug-bounty style program scope. Hosts are in scope when they match one
+// of the domain rules or resolve only into the listed networks, and are not excluded.
+//
+// Scope files are line based; blank lines and # comments are ignored:
+//
+//	example.com              exact host
+//	*.example.com            any subdomain of example.com
+//	203.0.113.0/24           network (single IPs are accepted too)
+//	!admin.example.com       exclusion, wins over every in-scope rule
+//	exclude-path /logout     path prefix that must never be requested
+//	exclude-check open_ports check that must never run
+type Scope struct {
+	Domains        []string
+	Networks       []*net.IPNet
+	Excluded       []string
+	ExcludedNets   []*net.IPNet
+	ExcludedPaths  []string
+	ExcludedChecks map[string]bool
+}
+
+var (
+	scopeMu     sync.RWMutex
+	activeScope *Scope
+)
+
+// scopeResolveTimeout bounds the DNS lookup TargetInScope needs for network rules
+const scopeResolveTimeout = 5 * time.Second
+
+// LoadScope parses a scope file
+func LoadScope(path string) (*Scope, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scope := &Scope{ExcludedChecks: make(map[string]bool)}
+	lineScanner := bufio.NewScanner(file)
+	lineNo := 0
+	for lineScanner.Scan() {
+		lineNo++
+		line := lineScanner.Text()
+		if idx := strings.Index(line, "#"); idx > -1 {
+			line = line[:idx]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		switch strings.ToLower(fields[0]) {
+		case "exclude-path":
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("%s:%d: exclude-path expects one path", path, lineNo)
+			}
+			scope.ExcludedPaths = append(scope.ExcludedPaths, strings.TrimSuffix(fields[1], "*"))
+			continue
+		case "exclude-check":
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("%s:%d: exclude-check expects one check name", path, lineNo)
+			}
+			scope.ExcludedChecks[fields[1]] = true
+			continue
+		}
+		if len(fields) != 1 {
+			return nil, fmt.Errorf("%s:%d: unrecognized scope rule %q", path, lineNo, line)
+		}
+
+		rule := strings.ToLower(fields[0])
+		excluded := strings.HasPrefix(rule, "!")
+		rule = strings.TrimPrefix(rule, "!")
+
+		if network := parseNetwork(rule); network != nil {
+			if excluded {
+				scope.ExcludedNets = append(scope.ExcludedNets, network)
+			} else {
+				scope.Networks = append(scope.Networks, network)
+			}
+			continue
+		}
+
+		rule = strings.TrimSuffix(rule, ".")
+		if excluded {
+			scope.Excluded = append(scope.Excluded, rule)
+		} else {
+			scope.Domains = append(scope.Domains, rule)
+		}
+	}
+	if err := lineScanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(scope.Domains) == 0 && len(scope.Networks) == 0 {
+		return nil, fmt.Errorf("%s: scope contains no in-scope hosts or networks", path)
+	}
+	return scope, nil
+}
+
+// SetScope activates scope enforcement for every scan in this process. Passing nil
+// disables it.
+func SetScope(scope *Scope) {
+	scopeMu.Lock()
+	defer scopeMu.Unlock()
+	activeScope = scope
+}
+
+func currentScope() *Scope {
+	scopeMu.RLock()
+	defer scopeMu.RUnlock()
+	return activeScope
+}
+
+// TargetInScope reports whether the host of url is allowed by the active scope.
+// It always returns true when no scope is loaded. Resolving the host gives up when
+// ctx ends or after scopeResolveTimeout, leaving only the domain rules to match.
+func TargetInScope(ctx context.Context, url string) bool {
+	scope := currentScope()
+	if scope == nil {
+		return true
+	}
+	ctx, cancel := context.WithTimeout(ctx, scopeResolveTimeout)
+	defer cancel()
+
+	host := extractDomain(normalizeURL(url))
+	ips, _ := resolveHost(ctx, host)
+	return scope.AllowsHost(host, ips)
+}
+
+// AllowsHost reports whether host, resolved to ips, may be contacted
+func (s *Scope) AllowsHost(host string, ips []net.IP) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	for _, rule := range s.Excluded {
+		if matchDomainRule(rule, host) {
+			return false
+		}
+	}
+	for _, ip := range ips {
+		if containsIP(s.ExcludedNets, ip) {
+			return false
+		}
+	}
+
+	for _, rule := range s.Domains {
+		if matchDomainRule(rule, host) {
+			return true
+		}
+	}
+
+	if len(ips) == 0 || len(s.Networks) == 0 {
+		return false
+	}
+	for _, ip := range ips {
+		if !containsIP(s.Networks, ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// AllowsPath reports whether a request path avoids every excluded path prefix
+func (s *Scope) AllowsPath(path string) bool {
+	if path == "" {
+		path = "/"
+	}
+	for _, prefix := range s.ExcludedPaths {
+		if strings.HasPrefix(path, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// AllowsCheck reports whether the named check may run
+func (s *Scope) AllowsCheck(name string) bool {
+	return !s.ExcludedChecks[name]
+}
+
+func matchDomainRule(rule, host string) bool {
+	if strings.HasPrefix(rule, "*.") {
+		return strings.HasSuffix(host, rule[1:])
+	}
+	return host == rule
+}
+
+func parseNetwork(rule string) *net.IPNet {
+	if _, network, err := net.ParseCIDR(rule); err == nil {
+		return network
+	}
+	if ip := net.ParseIP(rule); ip != nil {
+		bits := 128
+		if ip.To4() != nil {
+			ip = ip.To4()
+			bits = 32
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+	}
+	return nil
+}
+
+func containsIP(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package scanner
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testScope = `# Bug bounty program
+example.com
+*.example.com
+203.0.113.0/24
+2001:db8::1
+
+!admin.example.com   # staff only
+!203.0.113.99
+exclude-path /logout*
+exclude-check open_ports
+`
+
+func writeScope(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "scope.txt")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadScope(t *testing.T) {
+	scope, err := LoadScope(writeScope(t, testScope))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(scope.Domains, " ") != "example.com *.example.com" || strings.Join(scope.Excluded, " ") != "admin.example.com" {
+		t.Errorf("domains = %q, excluded = %q", scope.Domains, scope.Excluded)
+	}
+	if len(scope.Networks) != 2 || scope.Networks[1].String() != "2001:db8::1/128" {
+		t.Errorf("networks = %v", scope.Networks)
+	}
+	if len(scope.ExcludedNets) != 1 || scope.ExcludedNets[0].String() != "203.0.113.99/32" {
+		t.Errorf("excluded networks = %v", scope.ExcludedNets)
+	}
+
+	paths := []struct {
+		path string
+		want bool
+	}{
+		{"/", true},
+		{"", true},
+		{"/account", true},
+		{"/logout", false},
+		{"/logout/all", false},
+	}
+	for _, tt := range paths {
+		if got := scope.AllowsPath(tt.path); got != tt.want {
+			t.Errorf("AllowsPath(%q) = %t, want %t", tt.path, got, tt.want)
+		}
+	}
+	if scope.AllowsCheck("open_ports") || !scope.AllowsCheck("dns_records") {
+		t.Error("exclude-check open_ports was not applied")
+	}
+}
+
+func TestLoadScopeErrors(t *testing.T) {
+	tests := []string{
+		"# nothing in scope\n!admin.example.com\n",
+		"exclude-path\n",
+		"example.com\nexclude-check a b\n",
+		"example.com other.com\n",
+	}
+	for _, content := range tests {
+		if _, err := LoadScope(writeScope(t, content)); err == nil {
+			t.Errorf("LoadScope(%q) accepted an invalid scope", content)
+		}
+	}
+	if _, err := LoadScope(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("LoadScope accepted a missing file")
+	}
+}
+
+func TestScopeAllowsHost(t *testing.T) {
+	scope, err := LoadScope(writeScope(t, testScope))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ips := func(addrs ...string) []net.IP {
+		var list []net.IP
+		for _, a := range addrs {
+			list = append(list, net.ParseIP(a))
+		}
+		return list
+	}
+	tests := []struct {
+		host string
+		ips  []net.IP
+		want bool
+	}{
+		{"example.com", nil, true},
+		{"Shop.Example.com.", nil, true},
+		{"deep.api.example.com", nil, true},
+		{"admin.example.com", nil, false},
+		{"notexample.com", nil, false},
+		// Domain rules cannot override excluded networks
+		{"www.example.com", ips("203.0.113.99"), false},
+		{"cdn.partner.test", ips("203.0.113.10", "203.0.113.11"), true},
+		{"cdn.partner.test", ips("203.0.113.10", "198.51.100.1"), false},
+		{"cdn.partner.test", nil, false},
+		{"v6.partner.test", ips("2001:db8::1"), true},
+		{"203.0.113.5", ips("203.0.113.5"), true},
+	}
+	for _, tt := range tests {
+		if got := scope.AllowsHost(tt.host, tt.ips); got != tt.want {
+			t.Errorf("AllowsHost(%q, %v) = %t, want %t", tt.host, tt.ips, got, tt.want)
+		}
+	}
+}
+
+func TestTargetInScopeHonoursContext(t *testing.T) {
+	scope, err := LoadScope(writeScope(t, testScope))
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetScope(scope)
+	t.Cleanup(func() { SetScope(nil) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	if !TargetInScope(ctx, "https://www.example.com/login") {
+		t.Error("a host matching a domain rule needs no lookup")
+	}
+	// Without a lookup a host can only be in scope through a domain rule
+	if TargetInScope(ctx, "https://unresolved.invalid") {
+		t.Error("an unresolved host was put in scope")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("cancelled lookups took %v", elapsed)
+	}
+	if !TargetInScope(ctx, "203.0.113.7") || TargetInScope(ctx, "203.0.113.99") {
+		t.Error("IP targets were not matched against the networks")
+	}
+}
@@ -0,0 +1,241 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const (
+	// maxSensitiveBody is how much of a candidate file is read for signature matching
+	maxSensitiveBody = 64 << 10
+	// sensitiveProbeConcurrency bounds the parallel requests of exposed_files
+	sensitiveProbeConcurrency = 20
+)
+
+//go:embed data/sensitive_paths.txt
+var builtinSensitivePaths []byte
+
+// sensitivePath is a path probed by exposed_files together with the signature its
+// content must match: a magic byte prefix for binary files, a regexp otherwise
+type sensitivePath struct {
+	path    string
+	magic   []byte
+	pattern *regexp.Regexp
+}
+
+func (s sensitivePath) matches(body []byte) bool {
+	if s.magic != nil {
+		return bytes.HasPrefix(body, s.magic)
+	}
+	return s.pattern.Match(body)
+}
+
+var (
+	sensitivePathsMu sync.RWMutex
+	sensitivePaths   = mustParseSensitivePaths()
+)
+
+func mustParseSensitivePaths() []sensitivePath {
+	paths, err := parseSensitivePaths("data/sensitive_paths.txt", bytes.NewReader(builtinSensitivePaths))
+	if err != nil {
+		panic(err)
+	}
+	return paths
+}
+
+// SetSensitivePaths replaces the built-in exposed_files list with the entries in
+// file, which uses the same "path signature" format. An empty name restores the
+// built-in list.
+func SetSensitivePaths(file string) error {
+	paths := mustParseSensitivePaths()
+	if file != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if paths, err = parseSensitivePaths(file, f); err != nil {
+			return err
+		}
+		if len(paths) == 0 {
+			return fmt.Errorf("%s contains no paths", file)
+		}
+	}
+
+	sensitivePathsMu.Lock()
+	defer sensitivePathsMu.Unlock()
+	sensitivePaths = paths
+	return nil
+}
+
+func currentSensitivePaths() []sensitivePath {
+	sensitivePathsMu.RLock()
+	defer sensitivePathsMu.RUnlock()
+	return sensitivePaths
+}
+
+func parseSensitivePaths(name string, r io.Reader) ([]sensitivePath, error) {
+	var paths []sensitivePath
+	lineScanner := bufio.NewScanner(r)
+	lineNo := 0
+	for lineScanner.Scan() {
+		lineNo++
+		// Only whole-line comments: signatures may contain #
+		line := strings.TrimSpace(lineScanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		p, signature, _ := strings.Cut(line, " ")
+		signature = strings.TrimSpace(signature)
+		if !strings.HasPrefix(p, "/") || signature == "" {
+			return nil, fmt.Errorf("%s:%d: expected a path starting with / and a signature", name, lineNo)
+		}
+
+		entry := sensitivePath{path: p}
+		if magic, ok := strings.CutPrefix(signature, "hex:"); ok {
+			b, err := hex.DecodeString(magic)
+			if err != nil || len(b) == 0 {
+				return nil, fmt.Errorf("%s:%d: invalid hex signature %q", name, lineNo, magic)
+			}
+			entry.magic = b
+		} else {
+			re, err := regexp.Compile(signature)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %v", name, lineNo, err)
+			}
+			entry.pattern = re
+		}
+		paths = append(paths, entry)
+	}
+	return paths, lineScanner.Err()
+}
+
+// probeResponse is the part of a response exposed_files and content_discovery
+// compare. sum and size are taken with the requested path removed, since error pages
+// and redirects often echo it.
+type probeResponse struct {
+	status   int
+	body     []byte
+	length   int64
+	location string
+	sum      [32]byte
+	size     int
+}
+
+func fetchProbe(ctx context.Context, client *http.Client, baseURL, p string) (*probeResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL+p, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "URLHawkScanner-Scanner/1.0")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSensitiveBody))
+	if err != nil {
+		return nil, err
+	}
+	probe := &probeResponse{status: resp.StatusCode, body: body, length: resp.ContentLength}
+	if probe.length < 0 {
+		probe.length = int64(len(body))
+	}
+	if loc, err := resp.Location(); err == nil {
+		probe.location = loc.String()
+	}
+
+	echoed := []byte(p)
+	stripped := bytes.ReplaceAll(body, echoed, nil)
+	probe.size = len(stripped)
+	h := sha256.New()
+	h.Write(stripped)
+	h.Write(bytes.ReplaceAll([]byte(probe.location), echoed, nil))
+	h.Sum(probe.sum[:0])
+	return probe, nil
+}
+
+// notFoundBaseline fetches a random path with extension ext from baseURL, once per
+// scan, to learn what the server answers for files that do not exist. It returns nil
+// when the server answers with a real error status.
+func notFoundBaseline(ctx context.Context, baseURL, ext string) *probeResponse {
+	return memoize(ctx, "soft404:"+baseURL+"|"+ext, func() interface{} {
+		var baseline *probeResponse
+		token := make([]byte, 8)
+		if _, err := rand.Read(token); err != nil {
+			return baseline
+		}
+		resp, err := fetchProbe(ctx, pluginClient, baseURL, "/urlhawk-"+hex.EncodeToString(token)+ext)
+		if err != nil || resp.status != http.StatusOK {
+			return baseline
+		}
+		return resp
+	}).(*probeResponse)
+}
+
+// softNotFound reports whether resp looks like the catch-all page in baseline: the
+// same content, or a size within 5% for pages with timestamps or tokens
+func softNotFound(resp, baseline *probeResponse) bool {
+	if baseline == nil {
+		return false
+	}
+	if resp.sum == baseline.sum {
+		return true
+	}
+	diff := resp.size - baseline.size
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= baseline.size/20
+}
+
+// probeSensitivePaths returns the paths under baseURL whose content matches their
+// signature and differs from the server's not-found page, in list order
+func probeSensitivePaths(ctx context.Context, baseURL, host string) []string {
+	paths := currentSensitivePaths()
+	found := make([]bool, len(paths))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, sensitiveProbeConcurrency)
+	for i := range paths {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			p := strings.ReplaceAll(paths[i].path, "{host}", host)
+			resp, err := fetchProbe(ctx, pluginClient, baseURL, p)
+			if err != nil || resp.status != http.StatusOK || !paths[i].matches(resp.body) {
+				return
+			}
+			ext := path.Ext(strings.TrimSuffix(p, "/"))
+			found[i] = !softNotFound(resp, notFoundBaseline(ctx, baseURL, ext))
+		}(i)
+	}
+	wg.Wait()
+
+	var exposed []string
+	for i, ok := range found {
+		if ok {
+			exposed = append(exposed, strings.ReplaceAll(paths[i].path, "{host}", host))
+		}
+	}
+	return exposed
+}
@@ -0,0 +1,170 @@
+package scanner
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseSensitivePaths(t *testing.T) {
+	list := "# comment\n\n/.env ^[A-Z_]+=\n/backup.zip hex:504b0304\n/config.php <\\?php.*#\n"
+	paths, err := parseSensitivePaths("list.txt", strings.NewReader(list))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 3 {
+		t.Fatalf("%d paths, want 3", len(paths))
+	}
+
+	tests := []struct {
+		entry int
+		body  string
+		match bool
+	}{
+		{0, "DB_PASSWORD=secret", true},
+		{0, "<html>Not found</html>", false},
+		{1, "PK\x03\x04rest", true},
+		{1, "<html>PK\x03\x04</html>", false},
+		{2, "<?php // # settings", true},
+		{2, "<?php echo 1;", false},
+	}
+	for _, tt := range tests {
+		if got := paths[tt.entry].matches([]byte(tt.body)); got != tt.match {
+			t.Errorf("%s matches(%q) = %t, want %t", paths[tt.entry].path, tt.body, got, tt.match)
+		}
+	}
+
+	for _, bad := range []string{"/.env", ".env ^A=", "/a.zip hex:zz", "/a.zip hex:", "/a [unclosed"} {
+		if _, err := parseSensitivePaths("list.txt", strings.NewReader(bad)); err == nil {
+			t.Errorf("parseSensitivePaths(%q) accepted an invalid line", bad)
+		}
+	}
+}
+
+// Start of real files of every binary format in the built-in list
+func magicSamples(t *testing.T) map[string][]byte {
+	t.Helper()
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write([]byte("CREATE TABLE users (id int);"))
+	w.Close()
+
+	var zipped bytes.Buffer
+	zw := zip.NewWriter(&zipped)
+	f, _ := zw.Create("dump.sql")
+	f.Write([]byte("CREATE TABLE users (id int);"))
+	zw.Close()
+
+	return map[string][]byte{
+		"gzip":           gz.Bytes(),
+		"zip":            zipped.Bytes(),
+		"rar":            []byte("Rar!\x1a\x07\x01\x00\x33\x92\xb5\xe5\x0a\x01\x05\x06\x00"),
+		"7z":             []byte("7z\xbc\xaf\x27\x1c\x00\x04\x8d\x9b\xd5\x0f"),
+		"sqlite":         []byte("SQLite format 3\x00\x10\x00\x01\x01\x00\x40\x20\x20"),
+		"vim swap":       []byte("b0VIM 9.0\x00\x00\x10\x00\x00root\x00"),
+		"git index":      []byte("DIRC\x00\x00\x00\x02\x00\x00\x00\x2a"),
+		"ds_store":       []byte("\x00\x00\x00\x01Bud1\x00\x00\x10\x00"),
+		"ole2":           []byte("\xd0\xcf\x11\xe0\xa1\xb1\x1a\xe1\x00\x00\x00\x00"),
+		"java heap dump": []byte("JAVA PROFILE 1.0.2\x00\x00\x00\x00\x08"),
+	}
+}
+
+func TestBuiltinMagicSignatures(t *testing.T) {
+	samples := magicSamples(t)
+	matched := make(map[string]bool)
+	for _, entry := range mustParseSensitivePaths() {
+		if entry.magic == nil {
+			continue
+		}
+		format := ""
+		for name, sample := range samples {
+			if entry.matches(sample) {
+				format = name
+			}
+		}
+		if format == "" {
+			t.Errorf("%s: signature %s matches none of the sample files", entry.path, hex.EncodeToString(entry.magic))
+			continue
+		}
+		matched[format] = true
+		if entry.matches([]byte("<html><body>Not found</body></html>")) {
+			t.Errorf("%s: signature matches an HTML page", entry.path)
+		}
+	}
+	for name := range samples {
+		if !matched[name] {
+			t.Errorf("no built-in path uses the %s signature", name)
+		}
+	}
+}
+
+func TestSoftNotFound(t *testing.T) {
+	page := func(body string) *probeResponse {
+		return &probeResponse{status: http.StatusOK, sum: sha256.Sum256([]byte(body)), size: len(body)}
+	}
+	catchAll := strings.Repeat("x", 1000)
+
+	tests := []struct {
+		name     string
+		resp     *probeResponse
+		baseline *probeResponse
+		soft     bool
+	}{
+		{"no baseline", page(catchAll), nil, false},
+		{"identical", page(catchAll), page(catchAll), true},
+		{"within 5%", page(catchAll + strings.Repeat("y", 50)), page(catchAll), true},
+		{"over 5%", page(catchAll + strings.Repeat("y", 51)), page(catchAll), false},
+		{"smaller", page(catchAll[:940]), page(catchAll), false},
+		{"empty baseline", page("DB_PASSWORD=secret"), page(""), false},
+	}
+	for _, tt := range tests {
+		if got := softNotFound(tt.resp, tt.baseline); got != tt.soft {
+			t.Errorf("%s: softNotFound = %t, want %t", tt.name, got, tt.soft)
+		}
+	}
+}
+
+func TestFetchProbeStripsEchoedPath(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/moved" {
+			http.Redirect(w, r, "/login?next="+r.URL.Path, http.StatusFound)
+			return
+		}
+		fmt.Fprintf(w, "<html>Nothing at %s</html>", r.URL.Path)
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	short, err := fetchProbe(ctx, srv.Client(), srv.URL, "/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	long, err := fetchProbe(ctx, srv.Client(), srv.URL, "/a-much-longer-path.php")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if short.sum != long.sum || short.size != long.size {
+		t.Errorf("pages echoing the path differ: %d and %d bytes", short.size, long.size)
+	}
+	if short.length != int64(len(short.body)) {
+		t.Errorf("length = %d, want %d", short.length, len(short.body))
+	}
+
+	client := *srv.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	moved, err := fetchProbe(ctx, &client, srv.URL, "/moved")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved.status != http.StatusFound || moved.location != srv.URL+"/login?next=/moved" {
+		t.Errorf("redirect = %d %q", moved.status, moved.location)
+	}
+}
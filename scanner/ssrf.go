@@ -0,0 +1,188 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ErrBlockedAddress is returned when a target resolves to an address denied by the
+// active AddressPolicy.
+var ErrBlockedAddress = errors.New("target resolves to a blocked address")
+
+// AddressPolicy is the SSRF deny policy enforced by scanDial on every resolved address.
+// Because the check happens after DNS resolution and the vetted IP is dialed directly,
+// DNS rebinding and redirects into internal ranges are caught as well.
+type AddressPolicy struct {
+	DenyLoopback  bool
+	DenyPrivate   bool
+	DenyLinkLocal bool
+	DenyMetadata  bool
+	Denied        []*net.IPNet
+}
+
+var (
+	policyMu      sync.RWMutex
+	addressPolicy *AddressPolicy
+
+	privateRanges = mustParseCIDRs(
+		"10.0.0.0/8",
+		"172.16.0.0/12",
+		"192.168.0.0/16",
+		"100.64.0.0/10", // Carrier-grade NAT
+		"fc00::/7",
+	)
+
+	// Cloud instance metadata endpoints. Most live in link-local space but are listed
+	// explicitly so they stay blocked even when link-local is allowed.
+	metadataRanges = mustParseCIDRs(
+		"169.254.169.254/32", // AWS, GCP, Azure, OpenStack
+		"169.254.170.2/32",   // AWS ECS task metadata
+		"100.100.100.200/32", // Alibaba Cloud
+		"fd00:ec2::254/128",  // AWS IPv6
+	)
+
+	// "This network"; Linux routes 0.x.y.z to the local host
+	thisNetwork = mustParseCIDRs("0.0.0.0/8")
+
+	// NAT64 well-known prefix (RFC 6052); the last four bytes are the IPv4 address
+	nat64Prefix = mustParseCIDRs("64:ff9b::/96")
+)
+
+// PublicAddressPolicy denies every internal range, which is the right default for
+// internet-facing deployments.
+func PublicAddressPolicy() AddressPolicy {
+	return AddressPolicy{
+		DenyLoopback:  true,
+		DenyPrivate:   true,
+		DenyLinkLocal: true,
+		DenyMetadata:  true,
+	}
+}
+
+// AddressPolicyFromEnv builds the policy for the web server and serverless handler.
+// Internal ranges are denied unless URLHAWK_ALLOW_INTERNAL=true, and URLHAWK_DENY_RANGES
+// adds extra comma-separated CIDRs to the deny list either way.
+func AddressPolicyFromEnv() (*AddressPolicy, error) {
+	policy := &AddressPolicy{}
+	if os.Getenv("URLHAWK_ALLOW_INTERNAL") != "true" {
+		*policy = PublicAddressPolicy()
+	}
+
+	for _, raw := range strings.Split(os.Getenv("URLHAWK_DENY_RANGES"), ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		network := parseNetwork(raw)
+		if network == nil {
+			return nil, fmt.Errorf("invalid URLHAWK_DENY_RANGES entry %q", raw)
+		}
+		policy.Denied = append(policy.Denied, network)
+	}
+	return policy, nil
+}
+
+// SetAddressPolicy activates the SSRF deny policy for every scan in this process.
+// Passing nil allows all addresses, which is the CLI default.
+func SetAddressPolicy(policy *AddressPolicy) {
+	policyMu.Lock()
+	defer policyMu.Unlock()
+	addressPolicy = policy
+}
+
+func currentAddressPolicy() *AddressPolicy {
+	policyMu.RLock()
+	defer policyMu.RUnlock()
+	return addressPolicy
+}
+
+// Blocks reports whether ip is denied and, if so, which category denied it.
+// IPv4-mapped and NAT64 addresses are checked as the IPv4 address they reach.
+func (p *AddressPolicy) Blocks(ip net.IP) (string, bool) {
+	// Denied ranges may be written for either form of the address
+	v4 := embeddedIPv4(ip)
+	if containsIP(p.Denied, ip) || containsIP(p.Denied, v4) {
+		return "denied range", true
+	}
+	ip = v4
+	switch {
+	case p.DenyMetadata && containsIP(metadataRanges, ip):
+		return "cloud metadata", true
+	case p.DenyLoopback && (ip.IsLoopback() || ip.IsUnspecified() || containsIP(thisNetwork, ip)):
+		return "loopback", true
+	case p.DenyLinkLocal && (ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast()):
+		return "link-local", true
+	case p.DenyPrivate && containsIP(privateRanges, ip):
+		return "private", true
+	}
+	return "", false
+}
+
+// embeddedIPv4 unwraps ::ffff:0:0/96 and 64:ff9b::/96 addresses to the IPv4 address
+// they tunnel to; other addresses are returned unchanged
+func embeddedIPv4(ip net.IP) net.IP {
+	if v4 := ip.To4(); v4 != nil {
+		return v4
+	}
+	if len(ip) == net.IPv6len && containsIP(nat64Prefix, ip) {
+		return net.IPv4(ip[12], ip[13], ip[14], ip[15]).To4()
+	}
+	return ip
+}
+
+// CheckTargetAddress resolves the host of url and returns ErrBlockedAddress if any
+// of its addresses is denied. It lets API handlers refuse a scan up front; the same
+// policy is enforced again on every connection at dial time.
+func CheckTargetAddress(ctx context.Context, url string) error {
+	policy := currentAddressPolicy()
+	if policy == nil {
+		return nil
+	}
+
+	host := extractDomain(normalizeURL(url))
+	ips, err := resolveHost(ctx, host)
+	if err != nil {
+		// Unresolvable hosts cannot be reached either, let the plugins report it
+		return nil
+	}
+	for _, ip := range ips {
+		if reason, blocked := policy.Blocks(ip); blocked {
+			return fmt.Errorf("%w (%s, %s)", ErrBlockedAddress, ip, reason)
+		}
+	}
+	return nil
+}
+
+func resolveHost(ctx context.Context, host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+
+	var resolver net.Resolver
+	addrs, err := resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, 0, len(addrs))
+	for _, a := range addrs {
+		ips = append(ips, a.IP)
+	}
+	return ips, nil
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	var networks []*net.IPNet
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
@@ -0,0 +1,95 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestAddressPolicyBlocks(t *testing.T) {
+	public := PublicAddressPolicy()
+	custom := AddressPolicy{Denied: mustParseCIDRs("198.51.100.0/24", "2001:db8:bad::/48")}
+
+	tests := []struct {
+		name   string
+		policy AddressPolicy
+		ip     string
+		reason string
+	}{
+		{"loopback", public, "127.0.0.1", "loopback"},
+		{"loopback range", public, "127.8.9.10", "loopback"},
+		{"IPv6 loopback", public, "::1", "loopback"},
+		{"unspecified", public, "0.0.0.0", "loopback"},
+		{"this network", public, "0.1.2.3", "loopback"},
+		{"IPv6 unspecified", public, "::", "loopback"},
+		{"metadata", public, "169.254.169.254", "cloud metadata"},
+		{"IPv4-mapped metadata", public, "::ffff:169.254.169.254", "cloud metadata"},
+		{"AWS IPv6 metadata", public, "fd00:ec2::254", "cloud metadata"},
+		{"ECS metadata", public, "169.254.170.2", "cloud metadata"},
+		{"link-local", public, "169.254.1.1", "link-local"},
+		{"IPv6 link-local", public, "fe80::1", "link-local"},
+		{"10/8", public, "10.1.2.3", "private"},
+		{"172.16/12", public, "172.31.255.254", "private"},
+		{"192.168/16", public, "192.168.0.1", "private"},
+		{"CGNAT", public, "100.64.0.1", "private"},
+		{"unique local", public, "fd12:3456::1", "private"},
+		{"NAT64 private", public, "64:ff9b::a00:1", "private"},
+		{"NAT64 loopback", public, "64:ff9b::7f00:1", "loopback"},
+		{"IPv4-mapped loopback", public, "::ffff:127.0.0.1", "loopback"},
+		{"public", public, "93.184.216.34", ""},
+		{"172.32 is public", public, "172.32.0.1", ""},
+		{"public IPv6", public, "2606:4700::1111", ""},
+		{"NAT64 public", public, "64:ff9b::5db8:d822", ""},
+		{"no policy flags", AddressPolicy{}, "127.0.0.1", ""},
+		{"denied range", custom, "198.51.100.7", "denied range"},
+		{"denied range via NAT64", custom, "64:ff9b::c633:6407", "denied range"},
+		{"denied IPv6 range", custom, "2001:db8:bad::1", "denied range"},
+		{"outside denied ranges", custom, "10.0.0.1", ""},
+	}
+	for _, tt := range tests {
+		ip := net.ParseIP(tt.ip)
+		reason, blocked := tt.policy.Blocks(ip)
+		if reason != tt.reason || blocked != (tt.reason != "") {
+			t.Errorf("%s: Blocks(%s) = %q, %t, want %q", tt.name, tt.ip, reason, blocked, tt.reason)
+		}
+	}
+}
+
+func TestAddressPolicyFromEnv(t *testing.T) {
+	t.Setenv("URLHAWK_ALLOW_INTERNAL", "")
+	t.Setenv("URLHAWK_DENY_RANGES", " 198.51.100.0/24 , 2001:db8::1,")
+	policy, err := AddressPolicyFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !policy.DenyLoopback || !policy.DenyPrivate || len(policy.Denied) != 2 {
+		t.Errorf("policy = %+v", policy)
+	}
+
+	t.Setenv("URLHAWK_ALLOW_INTERNAL", "true")
+	if policy, err = AddressPolicyFromEnv(); err != nil || policy.DenyLoopback || len(policy.Denied) != 2 {
+		t.Errorf("URLHAWK_ALLOW_INTERNAL=true gave %+v, %v", policy, err)
+	}
+
+	t.Setenv("URLHAWK_DENY_RANGES", "10.0.0.0/33")
+	if _, err := AddressPolicyFromEnv(); err == nil {
+		t.Error("an invalid URLHAWK_DENY_RANGES entry was accepted")
+	}
+}
+
+func TestCheckTargetAddress(t *testing.T) {
+	policy := PublicAddressPolicy()
+	SetAddressPolicy(&policy)
+	t.Cleanup(func() { SetAddressPolicy(nil) })
+
+	ctx := context.Background()
+	for _, target := range []string{"http://127.0.0.1:8080/", "https://[::1]/", "169.254.169.254/latest/meta-data", "http://10.0.0.5"} {
+		if err := CheckTargetAddress(ctx, target); !errors.Is(err, ErrBlockedAddress) {
+			t.Errorf("CheckTargetAddress(%q) = %v, want ErrBlockedAddress", target, err)
+		}
+	}
+	if err := CheckTargetAddress(ctx, "https://93.184.216.34/"); err != nil {
+		t.Errorf("a public address was blocked: %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+)
+
+// ErrSTARTTLSNotOffered is returned when a plaintext service does not support upgrading
+// the connection to TLS
+var ErrSTARTTLSNotOffered = errors.New("STARTTLS not offered")
+
+// starttlsPorts maps well-known plaintext ports to the protocol used to upgrade them
+var starttlsPorts = map[int]string{
+	21:   "ftp",
+	25:   "smtp",
+	110:  "pop3",
+	143:  "imap",
+	587:  "smtp",
+	5432: "postgres",
+}
+
+// dialSTARTTLS connects to addr, negotiates the protocol's STARTTLS upgrade and then
+// performs the TLS handshake with config.
+func dialSTARTTLS(ctx context.Context, addr, protocol string, config *tls.Config) (*tls.Conn, error) {
+	raw, err := scanDial(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		raw.SetDeadline(deadline)
+	}
+
+	if err := negotiateSTARTTLS(raw, protocol); err != nil {
+		raw.Close()
+		return nil, err
+	}
+
+	config = config.Clone()
+	if config.ServerName == "" {
+		host, _, _ := net.SplitHostPort(addr)
+		if net.ParseIP(host) == nil {
+			config.ServerName = host
+		}
+	}
+	conn := tls.Client(raw, config)
+	if err := conn.HandshakeContext(ctx); err != nil {
+		raw.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func negotiateSTARTTLS(conn net.Conn, protocol string) error {
+	r := bufio.NewReader(conn)
+	switch protocol {
+	case "smtp":
+		if _, err := readReply(r, "220"); err != nil {
+			return err
+		}
+		fmt.Fprintf(conn, "EHLO urlhawkscanner\r\n")
+		caps, err := readReply(r, "250")
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(strings.ToUpper(caps), "STARTTLS") {
+			return ErrSTARTTLSNotOffered
+		}
+		fmt.Fprintf(conn, "STARTTLS\r\n")
+		_, err = readReply(r, "220")
+		return err
+	case "ftp":
+		if _, err := readReply(r, "220"); err != nil {
+			return err
+		}
+		fmt.Fprintf(conn, "AUTH TLS\r\n")
+		if _, err := readReply(r, "234"); err != nil {
+			return ErrSTARTTLSNotOffered
+		}
+		return nil
+	case "imap":
+		if line, err := r.ReadString('\n'); err != nil || !strings.HasPrefix(line, "* OK") {
+			return fmt.Errorf("unexpected IMAP greeting %q", strings.TrimSpace(line))
+		}
+		fmt.Fprintf(conn, "a1 STARTTLS\r\n")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return err
+			}
+			if strings.HasPrefix(line, "a1 ") {
+				if strings.HasPrefix(line, "a1 OK") {
+					return nil
+				}
+				return ErrSTARTTLSNotOffered
+			}
+		}
+	case "pop3":
+		if line, err := r.ReadString('\n'); err != nil || !strings.HasPrefix(line, "+OK") {
+			return fmt.Errorf("unexpected POP3 greeting %q", strings.TrimSpace(line))
+		}
+		fmt.Fprintf(conn, "STLS\r\n")
+		if line, err := r.ReadString('\n'); err != nil || !strings.HasPrefix(line, "+OK") {
+			return ErrSTARTTLSNotOffered
+		}
+		return nil
+	case "postgres":
+		// SSLRequest: length 8, then the magic request code 80877103
+		request := make([]byte, 8)
+		binary.BigEndian.PutUint32(request[0:4], 8)
+		binary.BigEndian.PutUint32(request[4:8], 80877103)
+		if _, err := conn.Write(request); err != nil {
+			return err
+		}
+		answer := make([]byte, 1)
+		if _, err := io.ReadFull(conn, answer); err != nil {
+			return err
+		}
+		if answer[0] != 'S' {
+			return ErrSTARTTLSNotOffered
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported STARTTLS protocol %q", protocol)
+}
+
+// readReply reads a (possibly multi-line) SMTP/FTP style reply and checks its code.
+// Continuation lines look like "250-PIPELINING", the last line like "250 OK".
+func readReply(r *bufio.Reader, code string) (string, error) {
+	var reply strings.Builder
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return reply.String(), err
+		}
+		reply.WriteString(line)
+		if len(line) < 4 || line[:3] != code {
+			return reply.String(), fmt.Errorf("unexpected reply %q", strings.TrimSpace(line))
+		}
+		if line[3] == ' ' {
+			return reply.String(), nil
+		}
+	}
+}
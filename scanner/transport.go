@@ -0,0 +1,280 @@
+package scanner
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// All traffic towards a scan target goes through scanDial and guardedTransport, so
+// scope rules and the SSRF address policy apply to every plugin, including redirects
+// followed by pluginClient.
+// Third-party OSINT APIs (archive.org, crt.sh, ip-api.com) use osintClient instead,
+// since they are not part of the target's attack surface.
+var (
+	pluginClient = &http.Client{
+		Transport: &guardedTransport{
+			base: &http.Transport{
+				DialContext:       scanDial,
+				TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+				DisableKeepAlives: true,
+			},
+		},
+		// Timeouts are handled by the Context passed to each plugin
+	}
+
+	osintClient = &http.Client{
+		Transport: &http.Transport{
+			DisableKeepAlives: true,
+		},
+	}
+
+	// caClient fetches URLs taken from the target's certificate (OCSP responders, CRL
+	// distribution points). Those hosts are outside the scope by nature, but the URLs
+	// are attacker-controlled, so the SSRF address policy still applies.
+	caClient = &http.Client{
+		Transport: &http.Transport{
+			DialContext:       policyDial,
+			DisableKeepAlives: true,
+		},
+	}
+)
+
+// rateLimiter spaces out requests to each target host across all checks and targets
+var rateLimiter = &hostLimiter{next: make(map[string]time.Time)}
+
+type ctxKey int
+
+const (
+	checkNameKey ctxKey = iota
+	violationLogKey
+	scanMemoKey
+)
+
+// guardedTransport refuses requests whose URL falls outside the active scope before
+// they are handed to the underlying transport.
+type guardedTransport struct {
+	base http.RoundTripper
+}
+
+func (t *guardedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if scope := currentScope(); scope != nil {
+		if !scope.AllowsPath(req.URL.Path) {
+			return nil, scopeViolation(req.Context(), "path "+req.URL.Path+" on "+req.URL.Host)
+		}
+	}
+	if err := rateLimiter.wait(req.Context(), req.URL.Host); err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(req)
+}
+
+// hostLimiter hands out request slots per host at a fixed interval, so no host sees
+// more than the configured rate however many checks run at once. A slot is only
+// taken when the request goes out: a waiter whose context ends holds nothing back
+// from the requests behind it.
+type hostLimiter struct {
+	mu        sync.Mutex
+	interval  time.Duration
+	next      map[string]time.Time
+	lastSweep time.Time
+}
+
+// limiterSweepEvery is how often hosts whose slot has passed are forgotten, so a
+// long-running server does not keep one entry per host it ever scanned
+const limiterSweepEvery = time.Minute
+
+// SetRateLimit caps the HTTP requests sent to each target host per second, shared by
+// all checks and targets. Zero or less removes the limit.
+func SetRateLimit(perSecond float64) {
+	rateLimiter.mu.Lock()
+	defer rateLimiter.mu.Unlock()
+	rateLimiter.interval = 0
+	if perSecond > 0 {
+		rateLimiter.interval = time.Duration(float64(time.Second) / perSecond)
+	}
+}
+
+// wait blocks until host may receive another request or ctx ends. It gives up at
+// once when the next free slot is already past ctx's deadline.
+func (l *hostLimiter) wait(ctx context.Context, host string) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		delay, err := l.take(ctx, host)
+		if delay <= 0 || err != nil {
+			return err
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// take claims the host's slot if it is free, or returns how long until it is
+func (l *hostLimiter) take(ctx context.Context, host string) (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.interval <= 0 {
+		return 0, nil
+	}
+	now := time.Now()
+	if now.Sub(l.lastSweep) >= limiterSweepEvery {
+		for h, t := range l.next {
+			if t.Before(now) {
+				delete(l.next, h)
+			}
+		}
+		l.lastSweep = now
+	}
+	slot := l.next[host]
+	if slot.After(now) {
+		if deadline, ok := ctx.Deadline(); ok && slot.After(deadline) {
+			return 0, context.DeadlineExceeded
+		}
+		return slot.Sub(now), nil
+	}
+	l.next[host] = now.Add(l.interval)
+	return 0, nil
+}
+
+// scanDial resolves addr, checks every resolved address against the scan policy and
+// then connects to the vetted IP directly, so the address that was checked is the
+// address that is used.
+func scanDial(ctx context.Context, network, addr string) (net.Conn, error) {
+	return dialVetted(ctx, network, addr, true)
+}
+
+// policyDial is scanDial without the scope check, for third-party hosts named by the
+// target itself
+func policyDial(ctx context.Context, network, addr string) (net.Conn, error) {
+	return dialVetted(ctx, network, addr, false)
+}
+
+func dialVetted(ctx context.Context, network, addr string, enforceScope bool) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	ips, err := resolveHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	if scope := currentScope(); enforceScope && scope != nil && !scope.AllowsHost(host, ips) {
+		return nil, scopeViolation(ctx, host)
+	}
+
+	// A single denied address blocks the host, so mixed public/internal answers
+	// cannot be used to slip past the policy
+	if policy := currentAddressPolicy(); policy != nil {
+		for _, ip := range ips {
+			if reason, blocked := policy.Blocks(ip); blocked {
+				blockedEntry := fmt.Sprintf("%s (%s, %s address)", host, ip, reason)
+				recordBlocked(ctx, blockedEntry)
+				return nil, fmt.Errorf("%w: %s", ErrBlockedAddress, blockedEntry)
+			}
+		}
+	}
+
+	var dialer net.Dialer
+	var lastErr error
+	for _, ip := range ips {
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no addresses for %s", host)
+	}
+	return nil, lastErr
+}
+
+// scanDialTLS performs a TLS handshake on top of scanDial. The ServerName defaults to
+// the host part of addr.
+func scanDialTLS(ctx context.Context, addr string, config *tls.Config) (*tls.Conn, error) {
+	raw, err := scanDial(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	config = config.Clone()
+	if config.ServerName == "" {
+		host, _, _ := net.SplitHostPort(addr)
+		if net.ParseIP(host) == nil {
+			config.ServerName = host
+		}
+	}
+
+	conn := tls.Client(raw, config)
+	if err := conn.HandshakeContext(ctx); err != nil {
+		raw.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// violationLog collects scope violations for a single target scan
+type violationLog struct {
+	mu      sync.Mutex
+	entries []string
+	seen    map[string]bool
+}
+
+func withViolationLog(ctx context.Context) (context.Context, *violationLog) {
+	log := &violationLog{seen: make(map[string]bool)}
+	return context.WithValue(ctx, violationLogKey, log), log
+}
+
+func (l *violationLog) list() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.entries...)
+}
+
+// scopeViolation logs and records an out-of-scope connection attempt and returns the
+// error handed back to the plugin.
+func scopeViolation(ctx context.Context, target string) error {
+	recordBlocked(ctx, target)
+	return fmt.Errorf("%w: %s", ErrOutOfScope, target)
+}
+
+// recordBlocked adds a blocked connection to the scan's violation log and prints it
+// the first time it is seen.
+func recordBlocked(ctx context.Context, target string) {
+	entry := target
+	if check, ok := ctx.Value(checkNameKey).(string); ok {
+		entry = check + " -> " + target
+	}
+
+	fresh := true
+	if log, ok := ctx.Value(violationLogKey).(*violationLog); ok {
+		log.mu.Lock()
+		fresh = !log.seen[entry]
+		if fresh {
+			log.seen[entry] = true
+			log.entries = append(log.entries, entry)
+		}
+		log.mu.Unlock()
+	}
+
+	if fresh {
+		printMu.Lock()
+		color.Red("    [!] Blocked connection: %s", entry)
+		printMu.Unlock()
+	}
+}
@@ -0,0 +1,110 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHostLimiterForgetsIdleHosts(t *testing.T) {
+	l := &hostLimiter{interval: time.Millisecond, next: make(map[string]time.Time)}
+	ctx := context.Background()
+	for _, host := range []string{"a.example", "b.example", "c.example"} {
+		if err := l.wait(ctx, host); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(l.next) != 3 {
+		t.Fatalf("%d hosts tracked, want 3", len(l.next))
+	}
+
+	// Once the sweep is due, hosts whose slot has passed are dropped
+	time.Sleep(5 * time.Millisecond)
+	l.lastSweep = time.Now().Add(-limiterSweepEvery)
+	if err := l.wait(ctx, "d.example"); err != nil {
+		t.Fatal(err)
+	}
+	if len(l.next) != 1 {
+		t.Errorf("hosts tracked after sweep = %v, want only d.example", l.next)
+	}
+}
+
+func TestHostLimiterSpacesRequests(t *testing.T) {
+	l := &hostLimiter{interval: 20 * time.Millisecond, next: make(map[string]time.Time)}
+	ctx := context.Background()
+	start := time.Now()
+	for range 3 {
+		if err := l.wait(ctx, "a.example"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("three requests took %v, want at least 40ms", elapsed)
+	}
+
+	// A cancelled context stops the wait instead of sleeping out the slot
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := l.wait(cancelled, "a.example"); err == nil {
+		t.Error("wait ignored a cancelled context")
+	}
+}
+
+func TestHostLimiterCancelledWaitsFreeTheirSlots(t *testing.T) {
+	l := &hostLimiter{interval: 100 * time.Millisecond, next: make(map[string]time.Time)}
+
+	// 100 waits at 10 requests per second would queue ten seconds of slots
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	var wg sync.WaitGroup
+	var granted atomic.Int32
+	for range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if l.wait(ctx, "a.example") == nil {
+				granted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if granted.Load() != 1 {
+		t.Errorf("%d waits were granted within 50ms, want 1", granted.Load())
+	}
+
+	// The abandoned waits left nothing behind, so the next request goes out on the
+	// following slot rather than behind them
+	ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	start := time.Now()
+	if err := l.wait(ctx, "a.example"); err != nil {
+		t.Fatalf("wait after cancelled waits = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("wait took %v after the cancelled waits", elapsed)
+	}
+}
+
+func TestHostLimiterRefusesSlotsPastDeadline(t *testing.T) {
+	l := &hostLimiter{interval: time.Hour, next: make(map[string]time.Time)}
+	if err := l.wait(context.Background(), "a.example"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	start := time.Now()
+	if err := l.wait(ctx, "a.example"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("wait = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("wait slept %v before refusing", elapsed)
+	}
+	// Other hosts are unaffected
+	if err := l.wait(ctx, "b.example"); err != nil {
+		t.Errorf("wait for another host = %v", err)
+	}
+}
@@ -0,0 +1,223 @@
+package web
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APIKey is a single client credential loaded from the keys file
+type APIKey struct {
+	Key  string `json:"key"`
+	Name string `json:"name"`
+
+	// RatePerMinute caps requests to any API endpoint. Zero uses the default.
+	RatePerMinute int `json:"rate_per_minute"`
+
+	// DailyQuota caps scans per UTC day. Zero means unlimited.
+	DailyQuota int `json:"daily_quota"`
+}
+
+const defaultRatePerMinute = 30
+
+// keyState tracks the token bucket and daily quota usage of one API key
+type keyState struct {
+	key       APIKey
+	tokens    float64
+	lastFill  time.Time
+	quotaDay  string
+	quotaUsed int
+}
+
+// authenticator guards every /api/ route. With no keys loaded it lets all requests
+// through, so the server behaves exactly as before unless -api-keys is given.
+type authenticator struct {
+	mu   sync.Mutex
+	keys []*keyState
+}
+
+// clientKey is the request context key under which middleware stores the caller's
+// key, so scan handlers can charge its quota once the scan is accepted
+type clientKey struct{}
+
+type client struct {
+	auth  *authenticator
+	state *keyState
+}
+
+type apiError struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+func loadAPIKeys(path string) (*authenticator, error) {
+	auth := &authenticator{}
+	if path == "" {
+		return auth, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []APIKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%s contains no API keys", path)
+	}
+
+	now := time.Now()
+	for i, key := range keys {
+		if strings.TrimSpace(key.Key) == "" {
+			return nil, fmt.Errorf("%s: key #%d is empty", path, i+1)
+		}
+		if key.Name == "" {
+			key.Name = fmt.Sprintf("key-%d", i+1)
+		}
+		if key.RatePerMinute <= 0 {
+			key.RatePerMinute = defaultRatePerMinute
+		}
+		auth.keys = append(auth.keys, &keyState{
+			key:      key,
+			tokens:   float64(key.RatePerMinute),
+			lastFill: now,
+		})
+	}
+	return auth, nil
+}
+
+func (a *authenticator) enabled() bool {
+	return len(a.keys) > 0
+}
+
+// middleware authenticates the request and applies the key's rate limit; scan
+// handlers charge the daily quota through chargeScan. CORS preflights carry no
+// credentials, so they are answered here and never reach the API handlers.
+func (a *authenticator) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isPreflight(r) {
+			handlePreflight(w, r)
+			return
+		}
+		if !a.enabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		state := a.lookup(requestKey(r))
+		if state == nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="urlhawkscanner"`)
+			writeAPIError(w, http.StatusUnauthorized, "unauthorized", "Missing or invalid API key")
+			return
+		}
+
+		if retry, ok := a.allow(state); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retry.Seconds()))))
+			writeAPIError(w, http.StatusTooManyRequests, "rate_limited", "Rate limit exceeded, slow down")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), clientKey{}, client{auth: a, state: state})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// chargeScan takes one scan from the caller's daily quota. Handlers call it after
+// the target was accepted, so refused scans cost nothing. When the quota is used up it
+// writes the 429 response and returns false.
+func chargeScan(w http.ResponseWriter, r *http.Request) bool {
+	c, ok := r.Context().Value(clientKey{}).(client)
+	if !ok {
+		return true
+	}
+	if retry, ok := c.auth.useQuota(c.state); !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retry.Seconds()))))
+		writeAPIError(w, http.StatusTooManyRequests, "quota_exceeded", "Daily scan quota exhausted")
+		return false
+	}
+	return true
+}
+
+// requestKey reads the API key from the X-API-Key header or a bearer token
+func requestKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return strings.TrimSpace(key)
+	}
+	auth := r.Header.Get("Authorization")
+	if len(auth) > 7 && strings.EqualFold(auth[:7], "bearer ") {
+		return strings.TrimSpace(auth[7:])
+	}
+	return ""
+}
+
+func (a *authenticator) lookup(key string) *keyState {
+	if key == "" {
+		return nil
+	}
+	var match *keyState
+	// Compare against every key so the response time does not leak which prefix matched
+	for _, state := range a.keys {
+		if subtle.ConstantTimeCompare([]byte(state.key.Key), []byte(key)) == 1 {
+			match = state
+		}
+	}
+	return match
+}
+
+// allow consumes one request token. When none is left it returns false and how long
+// the client should wait.
+func (a *authenticator) allow(state *keyState) (time.Duration, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	rate := float64(state.key.RatePerMinute) / 60
+	state.tokens = math.Min(float64(state.key.RatePerMinute), state.tokens+now.Sub(state.lastFill).Seconds()*rate)
+	state.lastFill = now
+
+	if state.tokens < 1 {
+		return time.Duration((1 - state.tokens) / rate * float64(time.Second)), false
+	}
+	state.tokens--
+	return 0, true
+}
+
+// useQuota counts one scan against the key's daily quota. When it is used up it
+// returns false and the time until the next UTC day.
+func (a *authenticator) useQuota(state *keyState) (time.Duration, bool) {
+	if state.key.DailyQuota <= 0 {
+		return 0, true
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	day := now.UTC().Format("2006-01-02")
+	if state.quotaDay != day {
+		state.quotaDay = day
+		state.quotaUsed = 0
+	}
+	if state.quotaUsed >= state.key.DailyQuota {
+		tomorrow := now.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+		return tomorrow.Sub(now), false
+	}
+	state.quotaUsed++
+	return 0, true
+}
+
+func writeAPIError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(apiError{Error: message, Code: code})
+}
@@ -0,0 +1,210 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/DhanushNehru/urlhawkscanner/scanner"
+)
+
+func testAuthenticator(t *testing.T, keys string) *authenticator {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "keys.json")
+	if err := os.WriteFile(path, []byte(keys), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	auth, err := loadAPIKeys(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return auth
+}
+
+// scanStub stands in for a scan endpoint that has accepted its target
+func scanStub(w http.ResponseWriter, r *http.Request) {
+	if chargeScan(w, r) {
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func serve(handler http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+	return rec
+}
+
+func errorCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body apiError
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("error body: %v", err)
+	}
+	return body.Code
+}
+
+func TestLoadAPIKeys(t *testing.T) {
+	auth := testAuthenticator(t, `[{"key": "k1"}, {"key": "k2", "name": "ci", "rate_per_minute": 5}]`)
+	if auth.keys[0].key.Name != "key-1" || auth.keys[0].key.RatePerMinute != defaultRatePerMinute || auth.keys[1].key.RatePerMinute != 5 {
+		t.Errorf("keys = %+v, %+v", auth.keys[0].key, auth.keys[1].key)
+	}
+
+	for _, bad := range []string{`[]`, `[{"key": " "}]`, `{"key": "k"}`} {
+		path := filepath.Join(t.TempDir(), "keys.json")
+		os.WriteFile(path, []byte(bad), 0o600)
+		if _, err := loadAPIKeys(path); err == nil {
+			t.Errorf("loadAPIKeys(%s) accepted an invalid file", bad)
+		}
+	}
+	if auth, err := loadAPIKeys(""); err != nil || auth.enabled() {
+		t.Errorf("no keys file should leave the API open, got %v", err)
+	}
+}
+
+func TestMiddlewareAuthentication(t *testing.T) {
+	auth := testAuthenticator(t, `[{"key": "secret-key", "name": "ci"}]`)
+	handler := auth.middleware(http.HandlerFunc(scanStub))
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		status int
+	}{
+		{"missing key", "", "", http.StatusUnauthorized},
+		{"bad key", "X-API-Key", "wrong-key", http.StatusUnauthorized},
+		{"bad bearer", "Authorization", "Bearer wrong-key", http.StatusUnauthorized},
+		{"basic auth", "Authorization", "Basic c2VjcmV0LWtleQ==", http.StatusUnauthorized},
+		{"X-API-Key", "X-API-Key", "secret-key", http.StatusOK},
+		{"bearer", "Authorization", "Bearer secret-key", http.StatusOK},
+		{"lowercase bearer", "Authorization", "bearer  secret-key ", http.StatusOK},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/api/scan?url=example.com", nil)
+		if tt.header != "" {
+			r.Header.Set(tt.header, tt.value)
+		}
+		rec := serve(handler, r)
+		if rec.Code != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.name, rec.Code, tt.status)
+			continue
+		}
+		if tt.status == http.StatusUnauthorized {
+			if rec.Header().Get("WWW-Authenticate") == "" || errorCode(t, rec) != "unauthorized" {
+				t.Errorf("%s: 401 without a challenge or the unauthorized code", tt.name)
+			}
+		}
+	}
+
+	// X-API-Key wins over a bearer token
+	r := httptest.NewRequest("GET", "/api/scan", nil)
+	r.Header.Set("X-API-Key", "secret-key")
+	r.Header.Set("Authorization", "Bearer wrong-key")
+	if got := requestKey(r); got != "secret-key" {
+		t.Errorf("requestKey = %q, want the X-API-Key value", got)
+	}
+}
+
+func TestMiddlewareRateLimitAndQuota(t *testing.T) {
+	auth := testAuthenticator(t, `[
+		{"key": "slow", "rate_per_minute": 2},
+		{"key": "daily", "rate_per_minute": 100, "daily_quota": 1}
+	]`)
+	handler := auth.middleware(http.HandlerFunc(scanStub))
+	request := func(key string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/api/scan?url=example.com", nil)
+		r.Header.Set("X-API-Key", key)
+		return serve(handler, r)
+	}
+
+	for i := 0; i < 2; i++ {
+		if rec := request("slow"); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: status %d", i+1, rec.Code)
+		}
+	}
+	rec := request("slow")
+	if rec.Code != http.StatusTooManyRequests || errorCode(t, rec) != "rate_limited" {
+		t.Errorf("third request: status %d, want 429 rate_limited", rec.Code)
+	}
+	if retry, _ := strconv.Atoi(rec.Header().Get("Retry-After")); retry < 1 || retry > 30 {
+		t.Errorf("rate limit Retry-After = %q", rec.Header().Get("Retry-After"))
+	}
+
+	if rec := request("daily"); rec.Code != http.StatusOK {
+		t.Fatalf("first scan: status %d", rec.Code)
+	}
+	rec = request("daily")
+	if rec.Code != http.StatusTooManyRequests || errorCode(t, rec) != "quota_exceeded" {
+		t.Errorf("second scan: status %d, want 429 quota_exceeded", rec.Code)
+	}
+	if retry, _ := strconv.Atoi(rec.Header().Get("Retry-After")); retry < 1 || retry > 24*60*60 {
+		t.Errorf("quota Retry-After = %q", rec.Header().Get("Retry-After"))
+	}
+}
+
+func TestRefusedScansCostNoQuota(t *testing.T) {
+	policy := scanner.PublicAddressPolicy()
+	scanner.SetAddressPolicy(&policy)
+	t.Cleanup(func() { scanner.SetAddressPolicy(nil) })
+
+	auth := testAuthenticator(t, `[{"key": "daily", "daily_quota": 1}]`)
+	handler := auth.middleware(http.HandlerFunc(handleScan))
+	for i := 0; i < 3; i++ {
+		r := httptest.NewRequest("GET", "/api/scan?url=http://127.0.0.1:9", nil)
+		r.Header.Set("X-API-Key", "daily")
+		if rec := serve(handler, r); rec.Code != http.StatusForbidden {
+			t.Fatalf("internal target: status %d, want 403", rec.Code)
+		}
+	}
+	if used := auth.keys[0].quotaUsed; used != 0 {
+		t.Errorf("refused scans used %d of the quota", used)
+	}
+}
+
+func TestPreflight(t *testing.T) {
+	t.Cleanup(func() { corsOrigins = nil })
+	corsOrigins = []string{"https://app.example.com"}
+
+	auth := testAuthenticator(t, `[{"key": "secret-key"}]`)
+	reached := false
+	handler := auth.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	}))
+	options := func(origin, method string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("OPTIONS", "/api/scan", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		if method != "" {
+			r.Header.Set("Access-Control-Request-Method", method)
+		}
+		return serve(handler, r)
+	}
+
+	rec := options("https://app.example.com", "GET")
+	if rec.Code != http.StatusNoContent || reached {
+		t.Errorf("preflight: status %d, reached handler %t", rec.Code, reached)
+	}
+	if rec.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" ||
+		rec.Header().Get("Access-Control-Allow-Headers") != "X-API-Key, Authorization" ||
+		rec.Header().Get("Access-Control-Allow-Methods") != "GET" ||
+		rec.Header().Get("Access-Control-Max-Age") == "" {
+		t.Errorf("preflight headers = %v", rec.Header())
+	}
+
+	rec = options("https://evil.example", "GET")
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "" || rec.Header().Get("Access-Control-Allow-Headers") != "" {
+		t.Errorf("preflight from another origin: status %d, headers %v", rec.Code, rec.Header())
+	}
+
+	// Without the preflight headers OPTIONS is an ordinary request and needs a key
+	for _, tt := range []struct{ origin, method string }{{"", ""}, {"https://app.example.com", ""}, {"", "GET"}} {
+		if rec := options(tt.origin, tt.method); rec.Code != http.StatusUnauthorized || reached {
+			t.Errorf("OPTIONS with Origin %q and method %q: status %d, reached handler %t", tt.origin, tt.method, rec.Code, reached)
+		}
+	}
+}
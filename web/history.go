@@ -0,0 +1,125 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/DhanushNehru/urlhawkscanner/history"
+	"github.com/DhanushNehru/urlhawkscanner/scanner"
+)
+
+const defaultHistoryLimit = 50
+
+// handleHistory lists stored scans, newest first: GET /api/history?target=example.com&limit=20
+func handleHistory(w http.ResponseWriter, r *http.Request) {
+	if historyStore == nil {
+		writeAPIError(w, http.StatusServiceUnavailable, "history_disabled", "Scan history is disabled on this server")
+		return
+	}
+
+	target := r.URL.Query().Get("target")
+	limit := defaultHistoryLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			writeAPIError(w, http.StatusBadRequest, "bad_request", "Invalid 'limit' parameter")
+			return
+		}
+		limit = n
+	}
+
+	scans, err := historyStore.List(target, limit)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "internal_error", "Failed to read scan history")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"target": target,
+		"scans":  scans,
+	})
+}
+
+// handleGetScan returns one stored scan with its full results: GET /api/scans/{id}
+func handleGetScan(w http.ResponseWriter, r *http.Request) {
+	if historyStore == nil {
+		writeAPIError(w, http.StatusServiceUnavailable, "history_disabled", "Scan history is disabled on this server")
+		return
+	}
+
+	record, err := historyStore.Get(r.PathValue("id"))
+	if errors.Is(err, history.ErrNotFound) {
+		writeAPIError(w, http.StatusNotFound, "not_found", "Scan not found")
+		return
+	}
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "internal_error", "Failed to read scan")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(record)
+}
+
+// handleDiff compares two stored scans: GET /api/diff?from={id}&to={id}, or
+// GET /api/diff?target=example.com for the two most recent scans of a target.
+func handleDiff(w http.ResponseWriter, r *http.Request) {
+	if historyStore == nil {
+		writeAPIError(w, http.StatusServiceUnavailable, "history_disabled", "Scan history is disabled on this server")
+		return
+	}
+
+	query := r.URL.Query()
+	fromID, toID := query.Get("from"), query.Get("to")
+	if target := query.Get("target"); target != "" && fromID == "" && toID == "" {
+		scans, err := historyStore.List(target, 2)
+		if err != nil {
+			writeAPIError(w, http.StatusInternalServerError, "internal_error", "Failed to read scan history")
+			return
+		}
+		if len(scans) < 2 {
+			writeAPIError(w, http.StatusNotFound, "not_found", "Need at least two stored scans of this target")
+			return
+		}
+		fromID, toID = scans[1].ID, scans[0].ID
+	}
+	if fromID == "" || toID == "" {
+		writeAPIError(w, http.StatusBadRequest, "bad_request", "Provide 'from' and 'to' scan IDs, or a 'target'")
+		return
+	}
+
+	var records [2]*history.Record
+	for i, id := range []string{fromID, toID} {
+		record, err := historyStore.Get(id)
+		if errors.Is(err, history.ErrNotFound) {
+			writeAPIError(w, http.StatusNotFound, "not_found", "Scan not found: "+id)
+			return
+		}
+		if err != nil {
+			writeAPIError(w, http.StatusInternalServerError, "internal_error", "Failed to read scan")
+			return
+		}
+		records[i] = record
+	}
+
+	changes := scanner.DiffResults(records[0].Results, records[1].Results)
+	if changes == nil {
+		changes = []scanner.Change{}
+	}
+
+	summaries := make([]string, 0, len(changes))
+	for _, c := range changes {
+		summaries = append(summaries, c.String())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"from":    history.Summary{ID: records[0].ID, Target: records[0].Target, Time: records[0].Time},
+		"to":      history.Summary{ID: records[1].ID, Target: records[1].Target, Time: records[1].Time},
+		"changes": changes,
+		"summary": summaries,
+	})
+}
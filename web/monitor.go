@@ -0,0 +1,25 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/DhanushNehru/urlhawkscanner/monitor"
+)
+
+var scanMonitor *monitor.Monitor
+
+// handleMonitor reports the monitoring schedule and recent alerts: GET /api/monitor
+func handleMonitor(w http.ResponseWriter, r *http.Request) {
+	if scanMonitor == nil {
+		writeAPIError(w, http.StatusServiceUnavailable, "monitor_disabled", "Monitoring is not enabled on this server")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"every":   scanMonitor.Interval().String(),
+		"targets": scanMonitor.Status(),
+		"alerts":  scanMonitor.Alerts(),
+	})
+}
@@ -14,9 +14,21 @@ document.addEventListener('DOMContentLoaded', () => {
     const contentTitle = document.getElementById('content-title');
     const contentBody = document.getElementById('content-body');
 
+    // History Elements
+    const historyPanel = document.getElementById('history');
+    const historyList = document.getElementById('history-list');
+
     // Store current scan data globally for fast tab switching
     let currentScanData = {};
 
+    // API key for servers started with -api-keys, remembered across visits
+    const API_KEY_STORAGE = 'urlhawkApiKey';
+
+    function apiHeaders() {
+        const key = localStorage.getItem(API_KEY_STORAGE);
+        return key ? { 'X-API-Key': key } : {};
+    }
+
     scanBtn.addEventListener('click', initiateScan);
     urlInput.addEventListener('keypress', (e) => {
         if (e.key === 'Enter') initiateScan();
@@ -46,7 +58,23 @@ document.addEventListener('DOMContentLoaded', () => {
         loadingState.classList.remove('hidden');
 
         try {
-            const response = await fetch(`/api/scan?url=${encodeURIComponent(url)}`);
+            const response = await fetch(`/api/scan?url=${encodeURIComponent(url)}`, { headers: apiHeaders() });
+
+            if (response.status === 401) {
+                const key = prompt('This scanner requires an API key:');
+                if (key) {
+                    localStorage.setItem(API_KEY_STORAGE, key.trim());
+                    return initiateScan();
+                }
+                localStorage.removeItem(API_KEY_STORAGE);
+                return;
+            }
+
+            if (response.status === 429) {
+                const err = await response.json();
+                alert(`${err.error}. Retry in ${response.headers.get('Retry-After') || 'a few'} seconds.`);
+                return;
+            }
 
             if (!response.ok) {
                 throw new Error('Network response was not ok');
@@ -55,6 +83,7 @@ document.addEventListener('DOMContentLoaded', () => {
             const data = await response.json();
             currentScanData = data;
             buildHUD(data);
+            loadHistory(data.url);
 
         } catch (error) {
             console.error('Error during scan:', error);
@@ -66,6 +95,55 @@ document.addEventListener('DOMContentLoaded', () => {
         }
     }
 
+    // Past scans of the same target, served from the scanner's local history database
+    async function loadHistory(target) {
+        try {
+            const response = await fetch(`/api/history?target=${encodeURIComponent(target)}&limit=10`, { headers: apiHeaders() });
+            if (!response.ok) {
+                // History disabled or not available on this deployment
+                historyPanel.classList.add('hidden');
+                return;
+            }
+
+            const data = await response.json();
+            historyList.innerHTML = '';
+            data.scans.forEach(scan => {
+                const li = document.createElement('li');
+                li.className = 'list-item history-item';
+                li.innerHTML = `
+                    <i data-lucide="history"></i>
+                    <span>${new Date(scan.time).toLocaleString()}</span>
+                    <span class="history-id">${scan.id}</span>
+                `;
+                li.addEventListener('click', () => showStoredScan(scan.id));
+                historyList.appendChild(li);
+            });
+
+            historyPanel.classList.toggle('hidden', data.scans.length === 0);
+            lucide.createIcons();
+        } catch (error) {
+            console.error('Error loading history:', error);
+        }
+    }
+
+    async function showStoredScan(id) {
+        try {
+            const response = await fetch(`/api/scans/${encodeURIComponent(id)}`, { headers: apiHeaders() });
+            if (!response.ok) {
+                throw new Error('Scan not found');
+            }
+
+            const record = await response.json();
+            navList.innerHTML = '';
+            resetContentPane();
+            currentScanData = record.results;
+            buildHUD(record.results);
+        } catch (error) {
+            console.error('Error loading stored scan:', error);
+            alert("Failed to load the stored scan.");
+        }
+    }
+
     function resetContentPane() {
         contentIcon.setAttribute('data-lucide', 'globe');
         contentIcon.className = 'card-icon blue';
@@ -84,18 +162,22 @@ document.addEventListener('DOMContentLoaded', () => {
         const rules = {
             'url': { icon: 'globe', color: 'blue', title: 'Target Overview' },
             'missing_headers': { icon: 'shield-alert', color: 'yellow', title: 'Security Headers' },
+            'security_headers': { icon: 'clipboard-check', color: 'yellow', title: 'Security Header Scorecard' },
             'exposed_files': { icon: 'file-warning', color: 'red', title: 'Sensitive Files' },
+            'git_exposure': { icon: 'git-commit', color: 'red', title: 'Exposed Git Repository' },
+            'content_discovery': { icon: 'folder-search', color: 'purple', title: 'Content Discovery' },
             'dns_records': { icon: 'network', color: 'blue', title: 'DNS Records' },
             'whois_info': { icon: 'book', color: 'yellow', title: 'WHOIS Registration' },
             'open_ports': { icon: 'radio-tower', color: 'red', title: 'Open Ports' },
             'tech_stack': { icon: 'cpu', color: 'blue', title: 'Technology Stack' },
-            'robots_txt': { icon: 'bot', color: 'yellow', title: 'Robots.txt Paths' },
+            'robots_txt': { icon: 'bot', color: 'yellow', title: 'Robots.txt & Sitemaps' },
             'geolocation': { icon: 'map-pin', color: 'blue', title: 'Geolocation' },
             'ssl_certificate': { icon: 'lock', color: 'green', title: 'SSL Certificate' },
             'security_txt': { icon: 'file-lock', color: 'yellow', title: 'Security.txt Policy' },
             'http_methods': { icon: 'arrow-right-left', color: 'blue', title: 'HTTP Methods' },
             'wayback_machine': { icon: 'history', color: 'blue', title: 'Wayback Archive' },
-            'social_links': { icon: 'share-2', color: 'blue', title: 'Social Links' }
+            'social_links': { icon: 'share-2', color: 'blue', title: 'Social Links' },
+            'subdomains': { icon: 'git-branch', color: 'purple', title: 'Subdomains' }
             ,
         // Additional OSINT & Reconnaissance Features
         'subdomain_enum': { icon: 'git-branch', color: 'purple', title: 'Subdomain Discovery' },
@@ -106,6 +188,9 @@ document.addEventListener('DOMContentLoaded', () => {
         'cors_policy': { icon: 'shield-question', color: 'yellow', title: 'CORS Policy Analysis' },
         'cookie_security': { icon: 'cookie', color: 'yellow', title: 'Cookie Security' },
         'tls_cipher_suites': { icon: 'key', color: 'green', title: 'TLS Cipher Suites' },
+        'tls_services': { icon: 'lock-keyhole', color: 'green', title: 'TLS Services' },
+        'certificate_revocation': { icon: 'shield-off', color: 'green', title: 'Certificate Revocation' },
+        'certificate_transparency': { icon: 'scroll-text', color: 'green', title: 'Certificate Transparency' },
         'content_security': { icon: 'shield-check', color: 'green', title: 'Content Security Policy' },
         // Technology Detection
         'cms_plugins': { icon: 'plug', color: 'blue', title: 'CMS & Plugins' },
@@ -121,7 +206,8 @@ document.addEventListener('DOMContentLoaded', () => {
         'traceroute': { icon: 'route', color: 'orange', title: 'Network Traceroute' },
         'server_status': { icon: 'activity', color: 'cyan', title: 'Server Status & Uptime' },
         'carbon_footprint': { icon: 'leaf', color: 'green', title: 'Carbon Footprint Analysis' },
-        'redirects': { icon: 'corner-down-right', color: 'purple', title: 'HTTP Redirects' }
+        'redirects': { icon: 'corner-down-right', color: 'purple', title: 'HTTP Redirects' },
+        'hsts_preload': { icon: 'lock', color: 'green', title: 'HSTS Preload Eligibility' }
         };
         return rules[key] || { icon: 'server', color: 'pink', title: formatKeyAsTitle(key) };
     }
@@ -137,6 +223,12 @@ document.addEventListener('DOMContentLoaded', () => {
 
         if (val.error) return 'yellow'; // Warning/Timeout
 
+        // Checks that raise findings are rated by their most severe one
+        if (Array.isArray(val.findings) && val.findings.length > 0) {
+            if (val.findings.some(f => f.severity === 'critical' || f.severity === 'high')) return 'red';
+            if (val.findings.some(f => f.severity === 'medium')) return 'yellow';
+        }
+
         // Domain-specific threat logic
         if (key === 'exposed_files' || key === 'open_ports') return 'red';
         if (key === 'missing_headers') return 'yellow';
@@ -202,6 +294,31 @@ document.addEventListener('DOMContentLoaded', () => {
         lucide.createIcons();
     }
 
+    // Scan results echo what the target sent (titles, headers, paths), so every value
+    // is escaped before it goes into innerHTML
+    function escapeHTML(value) {
+        return String(value).replace(/[&<>"']/g, c => ({
+            '&': '&amp;', '<': '&lt;', '>': '&gt;', '"': '&quot;', "'": '&#39;'
+        })[c]);
+    }
+
+    function renderFindings(findings) {
+        let html = `<ul class="item-list findings">`;
+        findings.forEach(f => {
+            let liClass = 'list-item finding';
+            let icon = 'info';
+            if (f.severity === 'critical' || f.severity === 'high') { liClass += ' critical'; icon = 'flame'; }
+            else if (f.severity === 'medium') { liClass += ' warning'; icon = 'alert-circle'; }
+
+            html += `<li class="${liClass}"><i data-lucide="${icon}"></i><div>
+                <span class="severity severity-${escapeHTML(f.severity)}">${escapeHTML(f.severity)}</span><strong>${escapeHTML(f.title)}</strong>
+                ${f.detail ? `<p class="finding-detail">${escapeHTML(f.detail)}</p>` : ''}
+                ${f.recommendation ? `<p class="finding-fix">${escapeHTML(f.recommendation)}</p>` : ''}
+            </div></li>`;
+        });
+        return html + `</ul>`;
+    }
+
     function renderContentPane(key, data, meta, statusColor) {
         // Clear immediately to prevent old content from flashing
         contentBody.innerHTML = '';
@@ -223,7 +340,7 @@ document.addEventListener('DOMContentLoaded', () => {
             bodyHTML = `
                 <div class="badge warning">PLUGIN ERROR / TIMEOUT</div>
                 <ul class="item-list">
-                    <li class="list-item warning"><i data-lucide="alert-triangle"></i> ${data.error}</li>
+                    <li class="list-item warning"><i data-lucide="alert-triangle"></i> ${escapeHTML(data.error)}</li>
                 </ul>
             `;
         } else if (statusColor === 'green' && (Array.isArray(data) && data.length === 0 || Object.keys(data).length === 0)) {
@@ -240,7 +357,7 @@ document.addEventListener('DOMContentLoaded', () => {
             `;
         } else {
             if (key === 'url') {
-                bodyHTML = `<p class="data-label">URL Scanned:</p><p class="data-value highlight">${data}</p>`;
+                bodyHTML = `<p class="data-label">URL Scanned:</p><p class="data-value highlight">${escapeHTML(data)}</p>`;
             } else if (Array.isArray(data)) {
                 // Arrays
                 bodyHTML += `<ul class="item-list">`;
@@ -251,21 +368,34 @@ document.addEventListener('DOMContentLoaded', () => {
                     if (statusColor === 'red') { liClass += ' critical'; listIcon = 'flame'; }
                     else if (statusColor === 'yellow') { liClass += ' warning'; listIcon = 'alert-circle'; }
 
-                    bodyHTML += `<li class="${liClass}"><i data-lucide="${listIcon}"></i>${item}</li>`;
+                    bodyHTML += `<li class="${liClass}"><i data-lucide="${listIcon}"></i>${escapeHTML(item)}</li>`;
                 });
                 bodyHTML += `</ul>`;
             } else if (typeof data === 'object') {
-                // Objects
+                // Objects, with any findings listed above the raw fields
+                if (Array.isArray(data.findings) && data.findings.length > 0) {
+                    bodyHTML += renderFindings(data.findings);
+                }
                 bodyHTML += `<ul class="item-list">`;
                 for (const [subKey, subVal] of Object.entries(data)) {
-                    let fmtVal = subVal;
-                    if (Array.isArray(subVal)) fmtVal = subVal.join(', ');
-                    bodyHTML += `<li class="list-item"><strong>${subKey}:</strong><span style="margin-left:auto; text-align:right;">${fmtVal}</span></li>`;
+                    if (subKey === 'findings') continue;
+                    let fmtVal = escapeHTML(subVal);
+                    if (Array.isArray(subVal) && subVal.length > 0 && typeof subVal[0] === 'object') {
+                        // Lists of records, e.g. a certificate chain: one block per entry
+                        fmtVal = subVal.map(entry => Object.entries(entry)
+                            .map(([k, v]) => `${escapeHTML(k)}: ${escapeHTML(Array.isArray(v) ? v.join(', ') : v)}`)
+                            .join('<br>')).join('<hr>');
+                    } else if (Array.isArray(subVal)) fmtVal = escapeHTML(subVal.join(', '));
+                    else if (subVal && typeof subVal === 'object') {
+                        // Nested records, e.g. one per port
+                        fmtVal = Object.entries(subVal).map(([k, v]) => `${escapeHTML(k)}: ${escapeHTML(v)}`).join('<br>');
+                    }
+                    bodyHTML += `<li class="list-item"><strong>${escapeHTML(subKey)}:</strong><span style="margin-left:auto; text-align:right;">${fmtVal}</span></li>`;
                 }
                 bodyHTML += `</ul>`;
             } else {
                 // Strings
-                bodyHTML = `<p class="data-value">${data}</p>`;
+                bodyHTML = `<p class="data-value">${escapeHTML(data)}</p>`;
             }
         }
 
@@ -1,13 +1,18 @@
 package web
 
 import (
+	"context"
 	"embed"
 	"encoding/json"
 	"fmt"
 	"io/fs"
 	"net/http"
+	"slices"
 	"time"
 
+	"github.com/DhanushNehru/urlhawkscanner/history"
+	"github.com/DhanushNehru/urlhawkscanner/monitor"
+	"github.com/DhanushNehru/urlhawkscanner/notify"
 	"github.com/DhanushNehru/urlhawkscanner/scanner"
 	"github.com/fatih/color"
 )
@@ -15,7 +20,51 @@ import (
 //go:embed public/*
 var staticFiles embed.FS
 
-func StartServer(port int) {
+// Options configures the web server
+type Options struct {
+	// AllowInternal disables the SSRF address policy so the server can scan
+	// loopback, private and link-local targets. Only use it for internal deployments.
+	AllowInternal bool
+
+	// APIKeysFile is a JSON list of API keys with per-key rate limits and daily scan
+	// quotas. When empty, the API is open to anyone who can reach the server.
+	APIKeysFile string
+
+	// History persists every API scan and backs the history endpoints. When nil the
+	// history endpoints report that history is disabled.
+	History *history.Store
+
+	// Monitor, when set, rescans its targets in the background for as long as the
+	// server runs and exposes its schedule and alerts on /api/monitor.
+	Monitor *monitor.Monitor
+
+	// Notifier, when set, is told about every completed API scan
+	Notifier *notify.Dispatcher
+
+	// CORSOrigins lists the origins a browser may call the scan API from. Empty keeps
+	// Access-Control-Allow-Origin: *, which is fine as long as the API needs no cookies.
+	CORSOrigins []string
+}
+
+var (
+	historyStore *history.Store
+	notifier     *notify.Dispatcher
+	corsOrigins  []string
+)
+
+func StartServer(port int, opts Options) {
+	if opts.AllowInternal {
+		color.Yellow("[!] Internal address scanning enabled: SSRF protection is off")
+		scanner.SetAddressPolicy(nil)
+	} else {
+		policy, err := scanner.AddressPolicyFromEnv()
+		if err != nil {
+			color.Red("[-] Error loading address policy: %v", err)
+			return
+		}
+		scanner.SetAddressPolicy(policy)
+	}
+
 	// Serve static files from the embedded filesystem
 	staticFS, err := fs.Sub(staticFiles, "public")
 	if err != nil {
@@ -23,10 +72,35 @@ func StartServer(port int) {
 		return
 	}
 
+	historyStore = opts.History
+	scanMonitor = opts.Monitor
+	notifier = opts.Notifier
+	corsOrigins = opts.CORSOrigins
+
+	auth, err := loadAPIKeys(opts.APIKeysFile)
+	if err != nil {
+		color.Red("[-] Error loading API keys: %v", err)
+		return
+	}
+
+	// The static UI stays public; everything under /api/ goes through authentication
+	// and rate limiting, including endpoints registered on apiMux later on.
 	http.Handle("/", http.FileServer(http.FS(staticFS)))
 
+	apiMux := http.NewServeMux()
+
 	// API Endpoint for scanning
-	http.HandleFunc("/api/scan", handleScan)
+	apiMux.HandleFunc("GET /api/scan", handleScan)
+
+	// Scan history lookups
+	apiMux.HandleFunc("GET /api/history", handleHistory)
+	apiMux.HandleFunc("GET /api/scans/{id}", handleGetScan)
+	apiMux.HandleFunc("GET /api/diff", handleDiff)
+
+	// Scheduled monitoring status
+	apiMux.HandleFunc("GET /api/monitor", handleMonitor)
+
+	http.Handle("/api/", auth.middleware(apiMux))
 
 	addr := fmt.Sprintf(":%d", port)
 	color.Cyan(`
@@ -38,6 +112,13 @@ func StartServer(port int) {
 
 	`)
 	color.Green("[+] URLHawkScanner Web UI Started")
+	if auth.enabled() {
+		color.Green("[+] API key authentication enabled (%d keys)", len(auth.keys))
+	}
+	if scanMonitor != nil {
+		color.Green("[+] Monitoring %d targets every %s", len(scanMonitor.Status()), scanMonitor.Interval())
+		go scanMonitor.Run(context.Background())
+	}
 	color.Green("[+] Open your browser to http://localhost%s", addr)
 	color.Yellow("[!] Press Ctrl+C to stop")
 
@@ -49,7 +130,7 @@ func StartServer(port int) {
 
 func handleScan(w http.ResponseWriter, r *http.Request) {
 	// Enable CORS for potential separate frontend development
-	w.Header().Set("Access-Control-Allow-Origin", "*")
+	setCORSHeaders(w, r)
 	w.Header().Set("Content-Type", "application/json")
 
 	urlParam := r.URL.Query().Get("url")
@@ -61,10 +142,70 @@ func handleScan(w http.ResponseWriter, r *http.Request) {
 
 	color.Blue("[~] API Request received for: %s", urlParam)
 
+	if !scanner.TargetInScope(r.Context(), urlParam) {
+		color.Red("[!] Refused out-of-scope API scan: %s", urlParam)
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Target is out of scope"})
+		return
+	}
+
+	if err := scanner.CheckTargetAddress(r.Context(), urlParam); err != nil {
+		color.Red("[!] Refused API scan of internal address: %s (%v)", urlParam, err)
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	if !chargeScan(w, r) {
+		return
+	}
+
 	// Add an artificial small delay to make the UI look cool while scanning
 	time.Sleep(800 * time.Millisecond)
 
 	result := scanner.API_ScanURL(urlParam)
 
+	if historyStore != nil {
+		if record, err := historyStore.Save(result["url"].(string), result); err != nil {
+			color.Red("[-] Failed to save scan to history: %v", err)
+		} else {
+			w.Header().Set("X-Scan-ID", record.ID)
+		}
+	}
+
+	if notifier != nil {
+		notifier.Notify(notify.ScanEvent(result["url"].(string), result))
+	}
+
 	json.NewEncoder(w).Encode(result)
 }
+
+// setCORSHeaders allows any origin unless CORSOrigins is configured, in which case only
+// an exact match is echoed back
+func setCORSHeaders(w http.ResponseWriter, r *http.Request) {
+	if len(corsOrigins) == 0 {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+	w.Header().Add("Vary", "Origin")
+	if origin := r.Header.Get("Origin"); origin != "" && slices.Contains(corsOrigins, origin) {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+	}
+}
+
+// isPreflight reports whether r is a CORS preflight rather than a plain OPTIONS request
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get("Origin") != "" && r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// handlePreflight answers a CORS preflight without running the endpoint. Allowed
+// origins may send the API key headers, which are not CORS-safelisted.
+func handlePreflight(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w, r)
+	if w.Header().Get("Access-Control-Allow-Origin") != "" {
+		w.Header().Set("Access-Control-Allow-Methods", "GET")
+		w.Header().Set("Access-Control-Allow-Headers", "X-API-Key, Authorization")
+		w.Header().Set("Access-Control-Max-Age", "600")
+	}
+	w.WriteHeader(http.StatusNoContent)
+}